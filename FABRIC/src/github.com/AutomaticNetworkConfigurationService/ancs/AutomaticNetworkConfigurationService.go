@@ -0,0 +1,576 @@
+package ancs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// AutomaticNetworkConfigurationChaincode example simple Chaincode implementation
+type AutomaticNetworkConfigurationChaincode struct {
+}
+
+const chaincodeName = "AutomaticNetworkConfigurationService"
+
+const chaincodeVersion = "1.0"
+
+func toChaincodeArgs(args ...string) [][]byte {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	return bargs
+}
+
+// ============================================================================================================================
+// Asset Definitions - The ledger will store answers with hash id and cid
+// ============================================================================================================================
+type Order struct {
+	OrderID        string        `json:"QuestionHashID"`
+	DataCircuitID  string        `json:"QuestionerID"`
+	OrderBandwidth nsc.Bandwidth `json:"OrderBandwidth"`
+	OperatorID     string        `json:"OperatorID"`
+	OrderSatus     bool          `json:"OrderSatus"`
+	CreatedOn      string        `json:"CreatedOn"`
+	// Status is Completed or Rejected, RejectionReason carries the reason code of a rejected order
+	Status          string `json:"Status"`
+	RejectionReason string `json:"RejectionReason,omitempty"`
+	// RejectionNote says why a rejected order could not be fulfilled
+	RejectionNote string `json:"RejectionNote,omitempty"`
+	// Placement is the strategy BPM chose DataCircuitID with, empty when the operator named the circuit
+	Placement string `json:"Placement,omitempty"`
+	// Legs lists every circuit of an order split across circuits or routed over a path, DataCircuitID is then the circuit
+	// of the first leg
+	Legs []OrderLeg `json:"Legs,omitempty"`
+	// BackupCircuitID is set for a protected order, DataCircuitID is then its primary circuit
+	BackupCircuitID string `json:"BackupCircuitID,omitempty"`
+	// Profile is the bandwidth profile of the order, OrderBandwidth is then its committed rate
+	Profile *BandwidthProfile `json:"Profile,omitempty"`
+	// SLA is set when the order named SLA constraints, see sla.go
+	SLA *ServiceLevel `json:"SLA,omitempty"`
+}
+
+// OrderLeg is the part of an order allocated on one circuit. The legs of a path order are its hops in order from the
+// A-end, each carries the whole OrderBandwidth and names the sites it is crossed between.
+type OrderLeg struct {
+	DataCircuitID string        `json:"DataCircuitID"`
+	Bandwidth     nsc.Bandwidth `json:"Bandwidth"`
+	FromSiteID    string        `json:"FromSiteID,omitempty"`
+	ToSiteID      string        `json:"ToSiteID,omitempty"`
+}
+
+// order statuses
+const (
+	orderCompleted = "Completed"
+	orderRejected  = "Rejected"
+)
+
+// reason codes a rejected order can carry, UPSTREAM_FAILURE for a waitlisted order BPM failed to fulfil
+var rejectionReasons = []string{nsc.CodeInsufficientCapacity, nsc.CodeDiversityViolation, nsc.CodeSLANotMet, nsc.CodeUpstreamFailure}
+
+// Internal data maps
+type DataCircuit struct {
+	CircuitID            string        `json:"CircuitID"`
+	CircuitNetwork       string        `json:"CircuitNetwork"`
+	ProviderID           string        `json:"ProviderID"`
+	IsConfigured         bool          `json:"IsConfigured"`
+	TotalBandwidth       nsc.Bandwidth `json:"TotalBandwidth"`
+	AllocatedBandwidth   nsc.Bandwidth `json:"AllowedBandwidth"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"unallowedBandwidth"`
+	CreatedOn            string        `json:"CreatedOn"`
+	// Attributes such as a site or path are compared when choosing diverse primary and backup circuits
+	Attributes map[string]string `json:"Attributes,omitempty"`
+	// Status is Up or Down, circuits added before it was tracked are Up
+	Status string `json:"Status,omitempty"`
+	// ServiceClass is the class set on the circuit itself, SellableBandwidth is TotalBandwidth times the oversubscription
+	// ratio of its class and left out when it is sold 1:1
+	ServiceClass      string        `json:"ServiceClass,omitempty"`
+	SellableBandwidth nsc.Bandwidth `json:"SellableBandwidth,omitempty"`
+	// ExcessBandwidth is the excess rate allocated on top of the committed rates, ExcessLimit caps it and is left out
+	// when it is the TotalBandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	ExcessLimit     nsc.Bandwidth `json:"ExcessLimit,omitempty"`
+	// Performance is the latency, jitter, loss and availability NIMS recorded for the circuit
+	Performance *CircuitPerformance `json:"Performance,omitempty"`
+	// FaultyEquipment names the faulty or RMA equipment above the ports the circuit is terminated on
+	FaultyEquipment []string `json:"FaultyEquipment,omitempty"`
+}
+
+// CircuitPerformance is the NIMS record of a circuit's performance, latency and jitter are in microseconds, loss and
+// availability in parts per million, a nil value is unknown
+type CircuitPerformance struct {
+	Latency      *int   `json:"Latency,omitempty"`
+	Jitter       *int   `json:"Jitter,omitempty"`
+	Loss         *int   `json:"Loss,omitempty"`
+	Availability *int   `json:"Availability,omitempty"`
+	Source       string `json:"Source"`
+	UpdatedBy    string `json:"UpdatedBy"`
+	UpdatedOn    string `json:"UpdatedOn"`
+}
+
+// ============================================================================================================================
+// Init - initialize the chaincode
+// ============================================================================================================================
+func (t *AutomaticNetworkConfigurationChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	fmt.Println("Answer Store Channel Is Starting Up")
+	funcName, args := stub.GetFunctionAndParameters()
+	var err error
+	txId := stub.GetTxID()
+
+	fmt.Println("  Init() is running")
+	fmt.Println("  Transaction ID: ", txId)
+	fmt.Println("  GetFunctionAndParameters() function: ", funcName)
+	fmt.Println("  GetFunctionAndParameters() args count: ", len(args))
+	fmt.Println("  GetFunctionAndParameters() args found: ", args)
+
+	// expecting 2 args for instantiate or upgrade
+	if len(args) != 2 {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Init(): Incorrect number of arguments. Expecting 2 but got %d", len(args)))
+	}
+
+	err = stub.PutState(args[0], []byte(args[1]))
+	if err != nil {
+		return nsc.ErrorResponse(err) //self-test fail
+	}
+
+	fmt.Println("Ready for action") //self-test pass
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Invoke - Our entry point for Invocations
+// ============================================================================================================================
+func (t *AutomaticNetworkConfigurationChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	fmt.Println(" ")
+	fmt.Println("starting invoke, for - " + function)
+
+	return nsc.InvokeFunction(stub, chaincodeName, chaincodeVersion, chaincodeFunctions, function, args)
+}
+
+// ============================================================================================================================
+// Query - legacy function
+// ============================================================================================================================
+func (t *AutomaticNetworkConfigurationChaincode) Query(stub shim.ChaincodeStubInterface) pb.Response {
+	return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Unknown supported call - Query()"))
+}
+
+// ============================================================================================================================
+// Function Registry - see github.com/NetworkServiceCommon/nsc/registry.go
+// BPM completes, rejects and reconfigures orders under the operator's identity, so those functions are only run for
+// transactions proposed to the chaincodes registered as OMS or BPM, or by an admin.
+// ============================================================================================================================
+
+// the aliases of the chaincodes order transactions are proposed to
+var orderChaincodes = []string{omsDependency, bpmDependency}
+
+var chaincodeFunctions = []nsc.ChaincodeFunction{
+	{
+		Name:        "completeOrder",
+		Description: "Records an order as completed once BPM has allocated its bandwidth and requests its configuration",
+		Arguments:   completeOrderArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     completeOrder,
+	},
+	{
+		Name:        "getOrder",
+		Description: "Returns an order by its ID",
+		Arguments:   getOrderArguments,
+		ReadOnly:    true,
+		Handler:     getOrder,
+	},
+	{
+		Name:        "rejectOrder",
+		Description: "Records an order BPM could not fulfil as Rejected with its reason code",
+		Arguments:   rejectOrderArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     rejectOrder,
+	},
+	{
+		Name:        "reconfigureOrder",
+		Description: "Re-homes a completed order from a failed circuit and requests the configuration of the new circuit",
+		Arguments:   reconfigureOrderArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     reconfigureOrder,
+	},
+	{
+		Name:         "reportConfigurationApplied",
+		Description:  "Records whether the configuration of an order was applied to a device",
+		Arguments:    reportConfigurationAppliedArguments,
+		RequiredRole: agentRole,
+		Handler:      reportConfigurationApplied,
+	},
+	{
+		Name:        "getConfigurationJob",
+		Description: "Returns the configuration job of an order, or of one circuit leg of a split order",
+		Arguments:   getConfigurationJobArguments,
+		ReadOnly:    true,
+		Handler:     getConfigurationJob,
+	},
+	{
+		Name:        "getConfigurationJobs",
+		Description: "Lists the configuration jobs of an order, one per circuit leg",
+		Arguments:   getConfigurationJobsArguments,
+		ReadOnly:    true,
+		Handler:     getConfigurationJobs,
+	},
+	{
+		Name:         "registerChaincodeDependency",
+		Description:  "Registers the chaincode name, version and channel behind a dependency alias",
+		Arguments:    nsc.DependencyArguments(knownDependencies),
+		RequiredRole: nsc.AdminRole,
+		Handler:      nsc.RegisterChaincodeDependency,
+	},
+	{
+		Name:        "getChaincodeDependencies",
+		Description: "Lists the registered chaincode dependencies",
+		Arguments:   nsc.ArgumentSchema{},
+		ReadOnly:    true,
+		Handler:     nsc.GetChaincodeDependencies,
+	},
+}
+
+// ============================================================================================================================
+// Argument Schemas - see github.com/NetworkServiceCommon/nsc/arguments.go
+// ============================================================================================================================
+var getOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var completeOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "DataCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Placement", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	// JSON array of {DataCircuitID, Bandwidth} for an order split across circuits, with FromSiteID and ToSiteID for an
+	// order routed over a path, see parseOrderLegs
+	{Name: "Legs", Type: nsc.ArgString, MaxLength: 4096},
+	{Name: "BackupCircuitID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	// JSON bandwidth profile, see parseBandwidthProfile
+	{Name: "Profile", Type: nsc.ArgString, MaxLength: 1024},
+	// JSON service level, see parseServiceLevel
+	{Name: "SLA", Type: nsc.ArgString, MaxLength: 1024},
+}
+
+var rejectOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "DataCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ReasonCode", Type: nsc.ArgString, Required: true, Enum: rejectionReasons},
+	{Name: "Placement", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	// Reason is kept as the RejectionNote of the order
+	{Name: "Reason", Type: nsc.ArgString, MaxLength: 512},
+}
+
+// ============================================================================================================================
+// Get Answer - get a answer asset from ledger
+// ============================================================================================================================
+func getOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	var order Order
+	orderID := arguments.Str("OrderID")
+
+	fmt.Println("========================= recieved args ==========================")
+	fmt.Println(arguments)
+
+	orderBytes, err := stub.GetState(orderID) //getState retreives a key/value from the ledger
+	if err != nil {                           //this seems to always succeed, even if key didn't exist
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "Failed to find order - %s: %s", orderID, err.Error()))
+	}
+
+	if orderBytes == nil { //test if marble is actually here or just nil
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "Order does not exist - %s", orderID).WithDetail("OrderID", orderID))
+	}
+
+	err = json.Unmarshal([]byte(orderBytes), &order)
+	if err != nil {
+		fmt.Println("Unmarshal failed : ", err)
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to unmarshall order %s", orderID))
+	}
+
+	str := fmt.Sprintf("%s", orderBytes)
+	fmt.Println("string is " + str)
+
+	return shim.Success(orderBytes)
+}
+
+func completeOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting completeOrder")
+
+	OrderID := arguments.Str("OrderID")
+
+	fmt.Println("========================= recieved args ==========================")
+	fmt.Println(arguments)
+
+	// ===================================== save order into ledger ============================================
+	// ANCS is the order store, the order is created here once BPM has allocated its bandwidth
+	err := assertOrderIsOpen(stub, OrderID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	orderObject, err := CreateOrderObject(stub, arguments, orderCompleted)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println(orderObject)
+	err = putOrder(stub, orderObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	configurationJobs, err := createConfigurationJobs(stub, orderObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, OrderID)
+	err = events.Add(nsc.EventOrderCompleted, orderObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	for _, configurationJob := range configurationJobs {
+		err = events.Add(nsc.EventConfigurationRequested, configurationJob)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	fmt.Println("- end completeOrder")
+	return events.Emit(stub)
+}
+
+// rejectOrder stores an order BPM could not fulfil. The OrderRejected event record, with the requested and available
+// amounts, is added by BPM which owns the rejection record, so the batch returned here has no records of its own.
+func rejectOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting rejectOrder")
+
+	orderID := arguments.Str("OrderID")
+
+	err := assertOrderIsOpen(stub, orderID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	orderObject, err := CreateOrderObject(stub, arguments, orderRejected)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	err = putOrder(stub, orderObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end rejectOrder")
+	return nsc.NewEventBatch(stub, chaincodeName, orderID).Emit(stub)
+}
+
+// assertOrderIsOpen fails when the order was already completed or rejected
+func assertOrderIsOpen(stub shim.ChaincodeStubInterface, orderID string) error {
+	orderAsBytes, err := stub.GetState(orderID)
+	if err != nil { //this seems to always succeed, even if key didn't exist
+		return nsc.NewError(nsc.CodeInternal, "error in finding Order for - %s: %s", orderID, err.Error())
+	}
+	if orderAsBytes == nil {
+		return nil
+	}
+
+	existingOrder, err := JSONtoOrder(orderAsBytes)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to unmarshall order %s", orderID)
+	}
+	if existingOrder.OrderSatus || existingOrder.Status == orderRejected {
+		return nsc.NewError(nsc.CodeConflict, "This Order is already closed - %s", orderID).
+			WithDetail("OrderID", orderID).
+			WithDetail("Status", existingOrder.Status)
+	}
+	return nil
+}
+
+func putOrder(stub shim.ChaincodeStubInterface, order Order) error {
+	buff, err := OrderToJSON(order)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert Order to json")
+	}
+	return stub.PutState(order.OrderID, buff) //store marble with id as key
+}
+
+func getQueryResultForQueryString(stub shim.ChaincodeStubInterface, queryString string) ([]byte, error) {
+
+	fmt.Printf("- getQueryResultForQueryString queryString:\n%s\n", queryString)
+
+	resultsIterator, err := stub.GetQueryResult(queryString)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// buffer is a JSON array containing QueryRecords
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	bArrayMemberAlreadyWritten := false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		// Add a comma before array members, suppress it for the first array member
+		if bArrayMemberAlreadyWritten == true {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("{\"Key\":")
+		buffer.WriteString("\"")
+		buffer.WriteString(queryResponse.Key)
+		buffer.WriteString("\"")
+
+		buffer.WriteString(", \"Record\":")
+		// Record is a JSON object, so we write as-is
+		buffer.WriteString(string(queryResponse.Value))
+		buffer.WriteString("}")
+		bArrayMemberAlreadyWritten = true
+	}
+	buffer.WriteString("]")
+
+	fmt.Printf("- getQueryResultForQueryString queryResult:\n%s\n", buffer.String())
+
+	return buffer.Bytes(), nil
+}
+
+// ====================================================== Private Library ====================================================
+
+// CreateAssetObject creates an asset from validated completeOrder or rejectOrder arguments
+func CreateOrderObject(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs, status string) (Order, error) {
+	var myOrder Order
+
+	createdOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return myOrder, err
+	}
+
+	legs, err := parseOrderLegs(arguments)
+	if err != nil {
+		return myOrder, err
+	}
+	if arguments.Has("BackupCircuitID") && (legs != nil || arguments.Str("BackupCircuitID") == arguments.Str("DataCircuitID")) {
+		return myOrder, nsc.NewError(nsc.CodeInvalidArgument, "a protected order needs a single primary circuit and a different backup circuit").
+			WithDetail("BackupCircuitID", arguments.Str("BackupCircuitID"))
+	}
+
+	profile, err := parseBandwidthProfile(arguments)
+	if err != nil {
+		return myOrder, err
+	}
+	sla, err := parseServiceLevel(arguments)
+	if err != nil {
+		return myOrder, err
+	}
+
+	myOrder = Order{arguments.Str("OrderID"), arguments.Str("DataCircuitID"), arguments.Bandwidth("OrderBandwidth"), arguments.Str("OperatorID"), status == orderCompleted, createdOn, status, arguments.Str("ReasonCode"), arguments.Str("Reason"), arguments.Str("Placement"), legs, arguments.Str("BackupCircuitID"), profile, sla}
+	return myOrder, nil
+}
+
+// parseOrderLegs reads the Legs argument, the legs must start on DataCircuitID and use distinct circuits. The legs of a
+// split order add up to OrderBandwidth, the legs of a path order each carry it and join site to site from the A-end.
+func parseOrderLegs(arguments nsc.FunctionArgs) ([]OrderLeg, error) {
+	if !arguments.Has("Legs") {
+		return nil, nil
+	}
+
+	var legs []OrderLeg
+	err := json.Unmarshal([]byte(arguments.Str("Legs")), &legs)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "Legs is not a JSON array of order legs: %s", err.Error())
+	}
+	if len(legs) == 0 || legs[0].DataCircuitID != arguments.Str("DataCircuitID") {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "the first leg must be on DataCircuitID %s", arguments.Str("DataCircuitID"))
+	}
+
+	path := legs[0].FromSiteID != ""
+	total := nsc.Bandwidth(0)
+	circuits := []string{}
+	sites := []string{legs[0].FromSiteID}
+	for _, leg := range legs {
+		if !nsc.MatchesPattern(nsc.IDPattern, leg.DataCircuitID) || stringInSlice(leg.DataCircuitID, circuits) || leg.Bandwidth < 1 {
+			return nil, nsc.NewError(nsc.CodeInvalidArgument, "invalid leg on circuit %q with bandwidth %s", leg.DataCircuitID, leg.Bandwidth)
+		}
+		circuits = append(circuits, leg.DataCircuitID)
+		total = total + leg.Bandwidth
+
+		if !path {
+			if leg.FromSiteID != "" || leg.ToSiteID != "" {
+				return nil, nsc.NewError(nsc.CodeInvalidArgument, "only every leg or no leg of an order can name sites").WithDetail("Legs", legs)
+			}
+			continue
+		}
+		if leg.FromSiteID != sites[len(sites)-1] || !nsc.MatchesPattern(nsc.IDPattern, leg.FromSiteID) || !nsc.MatchesPattern(nsc.IDPattern, leg.ToSiteID) || stringInSlice(leg.ToSiteID, sites) || leg.Bandwidth != arguments.Bandwidth("OrderBandwidth") {
+			return nil, nsc.NewError(nsc.CodeInvalidArgument, "the leg on circuit %s does not continue the path with the OrderBandwidth %s", leg.DataCircuitID, arguments.Bandwidth("OrderBandwidth")).
+				WithDetail("Legs", legs)
+		}
+		sites = append(sites, leg.ToSiteID)
+	}
+	if !path && total != arguments.Bandwidth("OrderBandwidth") {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "the legs add up to %s instead of the OrderBandwidth %s", total, arguments.Bandwidth("OrderBandwidth")).
+			WithDetail("Legs", legs)
+	}
+	return legs, nil
+}
+
+func OrderToJSON(ans Order) ([]byte, error) {
+
+	djson, err := json.Marshal(ans)
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	return djson, nil
+}
+
+func JSONtoOrder(data []byte) (Order, error) {
+
+	eval := Order{}
+	err := json.Unmarshal([]byte(data), &eval)
+	if err != nil {
+		fmt.Println("Unmarshal failed : ", err)
+		return eval, err
+	}
+
+	return eval, nil
+}
+
+func contains(techRepuArray []string, match string) bool {
+	flag := false
+	for _, data := range techRepuArray {
+		if data == match {
+			flag = true
+			break
+		}
+	}
+	return flag
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// getTxTimestamp formats the proposal timestamp so every endorser records the same value
+func getTxTimestamp(stub shim.ChaincodeStubInterface) (string, error) {
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(txTime.Seconds, int64(txTime.Nanos)).UTC().Format("20060102150405"), nil
+}
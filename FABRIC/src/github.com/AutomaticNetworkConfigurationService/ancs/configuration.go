@@ -0,0 +1,264 @@
+package ancs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Configuration Jobs - completeOrder opens a job and emits ConfigurationRequested, an off-chain agent applies it to the
+// device and reports the outcome through reportConfigurationApplied. A failed job may be reported again once retried,
+// an applied job is final. An order split across circuits gets one job per leg, keyed by OrderID and DataCircuitID,
+// single circuit orders keep the job keyed by OrderID alone. A protected order gets a leg job on its primary and one
+// on its backup circuit.
+// ============================================================================================================================
+
+const configurationJobObjectType = "ConfigurationJob"
+
+// agentRole is carried by the identities of the configuration agents
+const agentRole = "agent"
+
+const (
+	configurationRequested = "Requested"
+	configurationApplied   = "Applied"
+	configurationFailed    = "Failed"
+)
+
+// roles of the jobs of a protected order
+const (
+	protectionPrimary = "primary"
+	protectionBackup  = "backup"
+)
+
+var reportConfigurationAppliedArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Status", Type: nsc.ArgString, Required: true, Enum: []string{configurationApplied, configurationFailed}},
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Message", Type: nsc.ArgString, MaxLength: 512},
+	{Name: "DataCircuitID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// DataCircuitID is needed for the jobs of an order split across circuits
+var getConfigurationJobArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "DataCircuitID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var getConfigurationJobsArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type ConfigurationJob struct {
+	OrderID        string        `json:"OrderID"`
+	DataCircuitID  string        `json:"DataCircuitID"`
+	OrderBandwidth nsc.Bandwidth `json:"OrderBandwidth"`
+	OperatorID     string        `json:"OperatorID"`
+	Status         string        `json:"Status"`
+	RequestedOn    string        `json:"RequestedOn"`
+	DeviceID       string        `json:"DeviceID,omitempty"`
+	Message        string        `json:"Message,omitempty"`
+	ReportedBy     string        `json:"ReportedBy,omitempty"`
+	ReportedOn     string        `json:"ReportedOn,omitempty"`
+	// Leg marks a job keyed by its circuit: a leg of a split order, with the bandwidth of the leg, a circuit of a
+	// protected order or the circuit an order was re-homed to
+	Leg bool `json:"Leg,omitempty"`
+	// Role is primary or backup for the jobs of a protected order, both carry the full bandwidth
+	Role string `json:"Role,omitempty"`
+	// ReplacesCircuitID is the failed circuit a reconfiguration job moves the order off
+	ReplacesCircuitID string `json:"ReplacesCircuitID,omitempty"`
+	// Profile is the order's bandwidth profile committed to the job's OrderBandwidth, the agent programs the policer
+	// with it
+	Profile *BandwidthProfile `json:"Profile,omitempty"`
+}
+
+// reportConfigurationApplied records the outcome of applying a job to a device
+func reportConfigurationApplied(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting reportConfigurationApplied")
+
+	orderID := arguments.Str("OrderID")
+
+	job, err := getConfigurationJobState(stub, orderID, arguments.Str("DataCircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if job.Status == configurationApplied {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "The configuration of order %s is already applied on %s", orderID, job.DeviceID).
+			WithDetail("OrderID", orderID).
+			WithDetail("DataCircuitID", job.DataCircuitID).
+			WithDetail("DeviceID", job.DeviceID))
+	}
+
+	job.ReportedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	job.ReportedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	job.Status = arguments.Str("Status")
+	job.DeviceID = arguments.Str("DeviceID")
+	job.Message = arguments.Str("Message")
+
+	err = putConfigurationJob(stub, job)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	eventType := nsc.EventConfigurationApplied
+	if job.Status == configurationFailed {
+		eventType = nsc.EventConfigurationFailed
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	err = events.Add(eventType, job)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end reportConfigurationApplied")
+	return events.Emit(stub)
+}
+
+func getConfigurationJob(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	job, err := getConfigurationJobState(stub, arguments.Str("OrderID"), arguments.Str("DataCircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(job)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
+
+// getConfigurationJobs lists every job of an order, one per leg for a split order
+func getConfigurationJobs(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(configurationJobObjectType, []string{arguments.Str("OrderID")})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	defer resultsIterator.Close()
+
+	jobs := []ConfigurationJob{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+
+		var job ConfigurationJob
+		err = json.Unmarshal(queryResponse.Value, &job)
+		if err != nil {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to unmarshall ConfigurationJob %s", queryResponse.Key))
+		}
+		jobs = append(jobs, job)
+	}
+
+	buff, err := json.Marshal(jobs)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
+
+// createConfigurationJobs opens the jobs of a completed order, one for each leg of a split or path order and one
+// for each circuit of a protected order
+func createConfigurationJobs(stub shim.ChaincodeStubInterface, order Order) ([]ConfigurationJob, error) {
+	requestedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	legs := order.Legs
+	if len(legs) == 0 {
+		legs = []OrderLeg{{DataCircuitID: order.DataCircuitID, Bandwidth: order.OrderBandwidth}}
+	}
+	roles := make([]string, len(legs))
+	if order.BackupCircuitID != "" {
+		legs = append(legs, OrderLeg{DataCircuitID: order.BackupCircuitID, Bandwidth: order.OrderBandwidth})
+		roles = []string{protectionPrimary, protectionBackup}
+	}
+
+	jobs := []ConfigurationJob{}
+	for i, leg := range legs {
+		job := ConfigurationJob{
+			OrderID:        order.OrderID,
+			DataCircuitID:  leg.DataCircuitID,
+			OrderBandwidth: leg.Bandwidth,
+			OperatorID:     order.OperatorID,
+			Status:         configurationRequested,
+			RequestedOn:    requestedOn,
+			Leg:            len(legs) > 1,
+			Role:           roles[i],
+			Profile:        jobProfile(order, leg.Bandwidth),
+		}
+		err = putConfigurationJob(stub, job)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// getConfigurationJobState reads the job of an order, dataCircuitID selects the leg of a split order and may be empty
+// for a single circuit order
+func getConfigurationJobState(stub shim.ChaincodeStubInterface, orderID string, dataCircuitID string) (ConfigurationJob, error) {
+	var job ConfigurationJob
+
+	attributes := [][]string{{orderID}}
+	if dataCircuitID != "" {
+		attributes = [][]string{{orderID, dataCircuitID}, {orderID}}
+	}
+
+	for _, jobAttributes := range attributes {
+		jobKey, err := stub.CreateCompositeKey(configurationJobObjectType, jobAttributes)
+		if err != nil {
+			return job, err
+		}
+
+		jobAsBytes, err := stub.GetState(jobKey)
+		if err != nil {
+			return job, nsc.NewError(nsc.CodeInternal, "error in finding ConfigurationJob for - %s: %s", orderID, err.Error())
+		}
+		if jobAsBytes == nil {
+			continue
+		}
+
+		err = json.Unmarshal(jobAsBytes, &job)
+		if err != nil {
+			return job, nsc.NewError(nsc.CodeInternal, "unable to unmarshall ConfigurationJob %s", orderID)
+		}
+		if dataCircuitID == "" || job.DataCircuitID == dataCircuitID {
+			return job, nil
+		}
+	}
+
+	return job, nsc.NewError(nsc.CodeNotFound, "No configuration was requested for order %s on circuit %q", orderID, dataCircuitID).
+		WithDetail("OrderID", orderID).
+		WithDetail("DataCircuitID", dataCircuitID)
+}
+
+func putConfigurationJob(stub shim.ChaincodeStubInterface, job ConfigurationJob) error {
+	attributes := []string{job.OrderID}
+	if job.Leg {
+		attributes = append(attributes, job.DataCircuitID)
+	}
+
+	jobKey, err := stub.CreateCompositeKey(configurationJobObjectType, attributes)
+	if err != nil {
+		return err
+	}
+
+	buff, err := json.Marshal(job)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert ConfigurationJob to json")
+	}
+	return stub.PutState(jobKey, buff)
+}
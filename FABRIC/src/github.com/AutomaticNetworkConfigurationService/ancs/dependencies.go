@@ -0,0 +1,14 @@
+package ancs
+
+// ============================================================================================================================
+// Chaincode Dependencies - see github.com/NetworkServiceCommon/nsc/dependencies.go
+// ANCS calls no other chaincode, the registry names the chaincodes order transactions are proposed to.
+// ============================================================================================================================
+
+// aliases of the chaincodes that call into ANCS
+const (
+	omsDependency = "OMS"
+	bpmDependency = "BPM"
+)
+
+var knownDependencies = []string{omsDependency, bpmDependency}
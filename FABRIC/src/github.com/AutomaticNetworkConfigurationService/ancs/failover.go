@@ -0,0 +1,139 @@
+package ancs
+
+import (
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Failover - BPM failoverCircuit moves the allocations off a Down circuit and calls reconfigureOrder for every order it
+// re-homed. The order then names the new circuit and a reconfiguration job is opened for it, keyed by OrderID and the
+// new circuit like the job of a leg. The jobs of the failed circuit are kept as they were.
+// ============================================================================================================================
+
+var reconfigureOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "FromCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ToCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+func reconfigureOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting reconfigureOrder")
+
+	orderID := arguments.Str("OrderID")
+	fromCircuitID := arguments.Str("FromCircuitID")
+	toCircuitID := arguments.Str("ToCircuitID")
+	fmt.Println(arguments)
+
+	orderAsBytes, err := stub.GetState(orderID)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "Failed to find order - %s: %s", orderID, err.Error()))
+	}
+	if orderAsBytes == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "Order does not exist - %s", orderID).WithDetail("OrderID", orderID))
+	}
+	order, err := JSONtoOrder(orderAsBytes)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to unmarshall order %s", orderID))
+	}
+	if order.Status != orderCompleted {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Only a completed order can be reconfigured - %s", orderID).
+			WithDetail("OrderID", orderID).
+			WithDetail("Status", order.Status))
+	}
+
+	job, err := rehomeOrder(&order, fromCircuitID, toCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	job.RequestedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	err = putOrder(stub, order)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = putConfigurationJob(stub, job)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	err = events.Add(nsc.EventConfigurationRequested, job)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end reconfigureOrder")
+	return events.Emit(stub)
+}
+
+// rehomeOrder replaces fromCircuitID by toCircuitID in the order and returns the job for toCircuitID. Moving the
+// primary of a protected order onto its backup leaves the order unprotected.
+func rehomeOrder(order *Order, fromCircuitID string, toCircuitID string) (ConfigurationJob, error) {
+	job := ConfigurationJob{
+		OrderID:           order.OrderID,
+		DataCircuitID:     toCircuitID,
+		OrderBandwidth:    order.OrderBandwidth,
+		OperatorID:        order.OperatorID,
+		Status:            configurationRequested,
+		Leg:               true,
+		ReplacesCircuitID: fromCircuitID,
+		Profile:           jobProfile(*order, order.OrderBandwidth),
+	}
+
+	usedCircuits := []string{order.DataCircuitID, order.BackupCircuitID}
+	for _, leg := range order.Legs {
+		usedCircuits = append(usedCircuits, leg.DataCircuitID)
+	}
+	toBackup := order.BackupCircuitID != "" && fromCircuitID == order.DataCircuitID && toCircuitID == order.BackupCircuitID
+	if !toBackup && stringInSlice(toCircuitID, usedCircuits) {
+		return job, nsc.NewError(nsc.CodeConflict, "Order %s already uses circuit %s", order.OrderID, toCircuitID).
+			WithDetail("OrderID", order.OrderID).
+			WithDetail("ToCircuitID", toCircuitID)
+	}
+
+	switch {
+	case len(order.Legs) > 0:
+		moved := false
+		for i := range order.Legs {
+			if order.Legs[i].DataCircuitID == fromCircuitID {
+				order.Legs[i].DataCircuitID = toCircuitID
+				job.OrderBandwidth = order.Legs[i].Bandwidth
+				job.Profile = jobProfile(*order, job.OrderBandwidth)
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+		if order.DataCircuitID == fromCircuitID {
+			order.DataCircuitID = toCircuitID
+		}
+		return job, nil
+	case toBackup:
+		order.DataCircuitID = toCircuitID
+		order.BackupCircuitID = ""
+		job.Role = protectionPrimary
+		return job, nil
+	case order.DataCircuitID == fromCircuitID:
+		order.DataCircuitID = toCircuitID
+		if order.BackupCircuitID != "" {
+			job.Role = protectionPrimary
+		}
+		return job, nil
+	case order.BackupCircuitID != "" && order.BackupCircuitID == fromCircuitID:
+		order.BackupCircuitID = toCircuitID
+		job.Role = protectionBackup
+		return job, nil
+	}
+
+	return job, nsc.NewError(nsc.CodeInvalidArgument, "Order %s does not use circuit %s", order.OrderID, fromCircuitID).
+		WithDetail("OrderID", order.OrderID).
+		WithDetail("FromCircuitID", fromCircuitID)
+}
@@ -0,0 +1,76 @@
+package ancs_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+var alice = simulator.OperatorIdentity("alice")
+
+func expectCode(t *testing.T, result simulator.Result, code string) {
+	t.Helper()
+	if result.OK() {
+		t.Fatalf("expected %s, the call succeeded", code)
+	}
+	if result.Err == nil || result.Err.Code != code {
+		t.Fatalf("expected %s, got %v", code, result.Error())
+	}
+}
+
+func TestOrderOutcomesRequireAnOrderTransaction(t *testing.T) {
+	s := simulator.NewForTest(t)
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+
+	// an operator cannot complete or reject an order by calling ANCS directly
+	expectCode(t, s.Invoke(simulator.ANCS, alice, "completeOrder", "O1", "C1", "50M", "alice"), nsc.CodeForbidden)
+	expectCode(t, s.Invoke(simulator.ANCS, alice, "rejectOrder", "O1", "C1", "50M", "alice", nsc.CodeInsufficientCapacity), nsc.CodeForbidden)
+	if _, err := s.Order("O1"); err == nil {
+		t.Fatal("expected no order O1")
+	}
+
+	// the same identity completes the order through OMS, which BPM calls ANCS for
+	result := s.SubmitOrder(alice, "O1", "C1", 50*simulator.Mbps)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Completed" {
+		t.Errorf("expected O1 to be Completed, it is %q", order.Status)
+	}
+
+	// an admin may still record an outcome by hand
+	result = s.Invoke(simulator.ANCS, simulator.AdminIdentity, "rejectOrder", "O2", "C1", "50M", "alice", nsc.CodeInsufficientCapacity)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+}
+
+func TestOrderChaincodesFollowTheRegistry(t *testing.T) {
+	s := simulator.NewForTest(t)
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+
+	// once another chaincode is registered as OMS, orders proposed to OMS can no longer complete in ANCS
+	register := func(target string) {
+		t.Helper()
+		result := s.Invoke(simulator.ANCS, simulator.AdminIdentity, "registerChaincodeDependency", "OMS", target, "1.0", "mychannel")
+		if !result.OK() {
+			t.Fatal(result.Error())
+		}
+	}
+	register(simulator.NIMS)
+	expectCode(t, s.SubmitOrder(alice, "O1", "C1", 50*simulator.Mbps), nsc.CodeForbidden)
+
+	register(simulator.OMS)
+	if result := s.SubmitOrder(alice, "O1", "C1", 50*simulator.Mbps); !result.OK() {
+		t.Fatal(result.Error())
+	}
+}
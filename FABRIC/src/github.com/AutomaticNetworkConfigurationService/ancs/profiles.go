@@ -0,0 +1,53 @@
+package ancs
+
+import (
+	"encoding/json"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// Bandwidth Profiles - BPM passes the profile of an order to completeOrder as JSON: its committed rate, which is the
+// OrderBandwidth, an excess rate, a burst size in kilobytes and a class of service. The order keeps it and every
+// configuration job carries a copy committed to the job's own bandwidth, which the device agent programs its policer with.
+// ============================================================================================================================
+
+var classesOfService = []string{"best-effort", "assured", "expedited"}
+
+type BandwidthProfile struct {
+	CommittedBandwidth nsc.Bandwidth `json:"CommittedBandwidth"`
+	ExcessBandwidth    nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	BurstSize          int           `json:"BurstSize,omitempty"`
+	ClassOfService     string        `json:"ClassOfService"`
+}
+
+// parseBandwidthProfile reads the Profile argument, its committed rate must be the OrderBandwidth
+func parseBandwidthProfile(arguments nsc.FunctionArgs) (*BandwidthProfile, error) {
+	if !arguments.Has("Profile") {
+		return nil, nil
+	}
+
+	var profile BandwidthProfile
+	err := json.Unmarshal([]byte(arguments.Str("Profile")), &profile)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "Profile is not a JSON bandwidth profile: %s", err.Error())
+	}
+	if profile.CommittedBandwidth != arguments.Bandwidth("OrderBandwidth") {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "the committed rate %s of the profile is not the OrderBandwidth %s", profile.CommittedBandwidth, arguments.Bandwidth("OrderBandwidth")).
+			WithDetail("Profile", profile)
+	}
+	if profile.ExcessBandwidth < 0 || profile.BurstSize < 0 || !stringInSlice(profile.ClassOfService, classesOfService) {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "invalid bandwidth profile").WithDetail("Profile", profile)
+	}
+	return &profile, nil
+}
+
+// jobProfile is the profile of the order for a job carrying bandwidth of it, nil when the order has none
+func jobProfile(order Order, bandwidth nsc.Bandwidth) *BandwidthProfile {
+	if order.Profile == nil {
+		return nil
+	}
+	profile := *order.Profile
+	profile.CommittedBandwidth = bandwidth
+	return &profile
+}
@@ -0,0 +1,38 @@
+package ancs
+
+import (
+	"encoding/json"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// SLA Constraints - BPM passes the SLA of an order to completeOrder as JSON: a maximum latency and jitter in
+// microseconds, a maximum loss and a minimum availability in parts per million, a zero constraint is not set. The order
+// keeps it so a re-homed order can be held to it, an order rejected because no circuit met it has SLA_NOT_MET as
+// RejectionReason and the unmet constraint as RejectionNote.
+// ============================================================================================================================
+
+type ServiceLevel struct {
+	MaxLatency      int `json:"MaxLatency,omitempty"`
+	MaxJitter       int `json:"MaxJitter,omitempty"`
+	MaxLoss         int `json:"MaxLoss,omitempty"`
+	MinAvailability int `json:"MinAvailability,omitempty"`
+}
+
+// parseServiceLevel reads the SLA argument, nil when there is none
+func parseServiceLevel(arguments nsc.FunctionArgs) (*ServiceLevel, error) {
+	if !arguments.Has("SLA") {
+		return nil, nil
+	}
+
+	var sla ServiceLevel
+	err := json.Unmarshal([]byte(arguments.Str("SLA")), &sla)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "SLA is not a JSON service level: %s", err.Error())
+	}
+	if sla.MaxLatency < 0 || sla.MaxJitter < 0 || sla.MaxLoss < 0 || sla.MinAvailability < 0 || sla == (ServiceLevel{}) {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "invalid SLA").WithDetail("SLA", sla)
+	}
+	return &sla, nil
+}
@@ -0,0 +1,566 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// BusinessProcessManagementChaincode example simple Chaincode implementation
+type BusinessProcessManagementChaincode struct {
+}
+
+const chaincodeName = "BusinessProcessManagementService"
+
+const chaincodeVersion = "1.0"
+
+// ============================================================================================================================
+// Asset Definitions - The ledger will store questions with hash id and cid
+// ============================================================================================================================
+
+type Order struct {
+	OrderID        string        `json:"QuestionHashID"`
+	DataCircuitID  string        `json:"QuestionerID"`
+	OrderBandwidth nsc.Bandwidth `json:"OrderBandwidth"`
+	OperatorID     string        `json:"OperatorID"`
+	OrderSatus     bool          `json:"OrderSatus"`
+	CreatedOn      string        `json:"CreatedOn"`
+	// Status is Completed or Rejected, RejectionReason carries the reason code of a rejected order
+	Status          string `json:"Status"`
+	RejectionReason string `json:"RejectionReason,omitempty"`
+	// Placement is the strategy BPM chose DataCircuitID with, empty when the operator named the circuit
+	Placement string `json:"Placement,omitempty"`
+	// Legs lists every circuit of an order split across circuits or routed over a path, DataCircuitID is then the circuit
+	// of the first leg
+	Legs []OrderLeg `json:"Legs,omitempty"`
+	// BackupCircuitID is set for a protected order, DataCircuitID is then its primary circuit
+	BackupCircuitID string `json:"BackupCircuitID,omitempty"`
+	// SLA is set when the order named SLA constraints, its circuits meet them
+	SLA *ServiceLevel `json:"SLA,omitempty"`
+}
+
+// OrderLeg is the part of an order allocated on one circuit. The legs of a path order are its hops in order from the
+// A-end, each carries the whole OrderBandwidth and names the sites it is crossed between.
+type OrderLeg struct {
+	DataCircuitID string        `json:"DataCircuitID"`
+	Bandwidth     nsc.Bandwidth `json:"Bandwidth"`
+	FromSiteID    string        `json:"FromSiteID,omitempty"`
+	ToSiteID      string        `json:"ToSiteID,omitempty"`
+}
+
+// Internal data maps
+type DataCircuit struct {
+	CircuitID            string        `json:"CircuitID"`
+	CircuitNetwork       string        `json:"CircuitNetwork"`
+	ProviderID           string        `json:"ProviderID"`
+	IsConfigured         bool          `json:"IsConfigured"`
+	TotalBandwidth       nsc.Bandwidth `json:"TotalBandwidth"`
+	AllocatedBandwidth   nsc.Bandwidth `json:"AllowedBandwidth"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"unallowedBandwidth"`
+	CreatedOn            string        `json:"CreatedOn"`
+	// Attributes such as a site or path are compared when choosing diverse primary and backup circuits
+	Attributes map[string]string `json:"Attributes,omitempty"`
+	// Status is Up or Down, circuits added before it was tracked are Up
+	Status string `json:"Status,omitempty"`
+	// ServiceClass is the class set on the circuit itself, SellableBandwidth is TotalBandwidth times the oversubscription
+	// ratio of its class and left out when it is sold 1:1
+	ServiceClass      string        `json:"ServiceClass,omitempty"`
+	SellableBandwidth nsc.Bandwidth `json:"SellableBandwidth,omitempty"`
+	// ExcessBandwidth is the excess rate allocated on top of the committed rates, ExcessLimit caps it and is left out
+	// when it is the TotalBandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	ExcessLimit     nsc.Bandwidth `json:"ExcessLimit,omitempty"`
+	// Performance is the latency, jitter, loss and availability NIMS recorded for the circuit
+	Performance *CircuitPerformance `json:"Performance,omitempty"`
+	// FaultyEquipment names the faulty or RMA equipment above the ports the circuit is terminated on
+	FaultyEquipment []string `json:"FaultyEquipment,omitempty"`
+}
+
+// ============================================================================================================================
+// Init - initialize the chaincode
+// ============================================================================================================================
+func (t *BusinessProcessManagementChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	fmt.Println("Question Store Channel Is Starting Up")
+	funcName, args := stub.GetFunctionAndParameters()
+	var err error
+	txId := stub.GetTxID()
+
+	fmt.Println("  Init() is running")
+	fmt.Println("  Transaction ID: ", txId)
+	fmt.Println("  GetFunctionAndParameters() function: ", funcName)
+	fmt.Println("  GetFunctionAndParameters() args count: ", len(args))
+	fmt.Println("  GetFunctionAndParameters() args found: ", args)
+
+	// expecting 2 args for instantiate or upgrade
+	if len(args) != 2 {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Init(): Incorrect number of arguments. Expecting 2 but got %d", len(args)))
+	}
+	// this is a very simple test. let's write to the ledger and error out on any errors
+	// it's handy to read this right away to verify network is healthy if it wrote the correct value
+	err = stub.PutState(args[0], []byte(args[1]))
+	if err != nil {
+		return nsc.ErrorResponse(err) //self-test fail
+	}
+
+	fmt.Println("Ready for action") //self-test pass
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Invoke - Our entry point for Invocations
+// ============================================================================================================================
+func (t *BusinessProcessManagementChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	fmt.Println(" ")
+	fmt.Println("starting invoke, for - " + function)
+
+	return nsc.InvokeFunction(stub, chaincodeName, chaincodeVersion, chaincodeFunctions, function, args)
+}
+
+// ============================================================================================================================
+// Query - legacy function
+// ============================================================================================================================
+func (t *BusinessProcessManagementChaincode) Query(stub shim.ChaincodeStubInterface) pb.Response {
+	return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Unknown supported call - Query()"))
+}
+
+// ============================================================================================================================
+// Function Registry - see github.com/NetworkServiceCommon/nsc/registry.go
+// Orders are taken by OMS, which checks that the invoker orders for itself and hands them to BPM, the order is recorded by
+// ANCS once BPM completes or rejects it. The functions that allocate an order are only run for transactions proposed to
+// the chaincode registered as OMS, or by an admin. Failing over a circuit moves every order on it and needs the admin role.
+// ============================================================================================================================
+
+// the alias of the chaincode order transactions are proposed to
+var orderChaincodes = []string{omsDependency}
+
+var chaincodeFunctions = []nsc.ChaincodeFunction{
+	{
+		Name:        "checkOnNIMSAndRespond",
+		Description: "Allocates an order's bandwidth on its circuit in NIMS and completes the order in ANCS, or waitlists or rejects it when it does not fit",
+		Arguments:   checkOnNIMSAndRespondArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     checkOnNIMSAndRespond,
+	},
+	{
+		Name:        "placeOrder",
+		Description: "Chooses a DataCircuit of a network with the placement strategy and processes the order on it as checkOnNIMSAndRespond does",
+		Arguments:   placeOrderArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     placeOrder,
+	},
+	{
+		Name:        "placePathOrder",
+		Description: "Routes an order between two sites over circuits with room for it and allocates every hop as a leg of the order",
+		Arguments:   placePathOrderArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     placePathOrder,
+	},
+	{
+		Name:        "computePath",
+		Description: "Returns the route placePathOrder would take between two sites by hops, latency or cost",
+		Arguments:   computePathArguments,
+		ReadOnly:    true,
+		Handler:     computePath,
+	},
+	{
+		Name:        "processWaitlist",
+		Description: "Fulfils the waitlisted orders of a circuit that fit its unallocated bandwidth and reports which were fulfilled",
+		Arguments:   waitlistArguments,
+		Handler:     processWaitlist,
+	},
+	{
+		Name:        "getWaitlist",
+		Description: "Lists the waitlisted orders of a circuit in the order they are served",
+		Arguments:   waitlistArguments,
+		ReadOnly:    true,
+		Handler:     getWaitlist,
+	},
+	{
+		Name:        "bookBandwidth",
+		Description: "Books bandwidth on a DataCircuit in NIMS for a time interval, on the circuit's home channel",
+		Arguments:   bookBandwidthArguments,
+		Handler:     bookBandwidth,
+	},
+	{
+		Name:        "cancelBooking",
+		Description: "Cancels a booking of a DataCircuit in NIMS that is not over yet",
+		Arguments:   cancelBookingArguments,
+		Handler:     cancelBooking,
+	},
+	{
+		Name:         "failoverCircuit",
+		Description:  "Moves the orders of a Down circuit onto their backup or a newly placed circuit and reports the orders that could not be re-homed",
+		Arguments:    failoverArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      failoverCircuit,
+	},
+	{
+		Name:        "getFailoverReport",
+		Description: "Returns the report of the last failover of a circuit",
+		Arguments:   failoverArguments,
+		ReadOnly:    true,
+		Handler:     getFailoverReport,
+	},
+	{
+		Name:        "analyzeCircuitImpact",
+		Description: "Lists the allocations, orders, operators and protection of circuits about to go down and where their bandwidth could be re-homed",
+		Arguments:   analyzeCircuitImpactArguments,
+		ReadOnly:    true,
+		Handler:     analyzeCircuitImpact,
+	},
+	{
+		Name:        "getOrderRejection",
+		Description: "Returns the rejection record of an order with its reason code and the requested and available bandwidth",
+		Arguments:   getOrderRejectionArguments,
+		ReadOnly:    true,
+		Handler:     getOrderRejection,
+	},
+	{
+		Name:         "registerChaincodeDependency",
+		Description:  "Registers the chaincode name, version and channel behind a dependency alias",
+		Arguments:    nsc.DependencyArguments(knownDependencies),
+		RequiredRole: nsc.AdminRole,
+		Handler:      nsc.RegisterChaincodeDependency,
+	},
+	{
+		Name:        "getChaincodeDependencies",
+		Description: "Lists the registered chaincode dependencies",
+		Arguments:   nsc.ArgumentSchema{},
+		ReadOnly:    true,
+		Handler:     nsc.GetChaincodeDependencies,
+	},
+	{
+		Name:         "setCircuitHomeChannel",
+		Description:  "Maps a DataCircuit to the channel holding its inventory record",
+		Arguments:    setCircuitHomeChannelArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setCircuitHomeChannel,
+	},
+	{
+		Name:        "getCircuitHomeChannel",
+		Description: "Returns the channel a DataCircuit resolves to",
+		Arguments:   getCircuitHomeChannelArguments,
+		ReadOnly:    true,
+		Handler:     getCircuitHomeChannel,
+	},
+	{
+		Name:        "checkCircuitCapacity",
+		Description: "Checks whether bandwidth fits on a DataCircuit, following it to its home channel",
+		Arguments:   checkCircuitCapacityArguments,
+		ReadOnly:    true,
+		Handler:     checkCircuitCapacity,
+	},
+	{
+		Name:         "setPlacementStrategy",
+		Description:  "Sets the placement strategy placeOrder uses when an order names none",
+		Arguments:    setPlacementStrategyArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setPlacementStrategy,
+	},
+	{
+		Name:        "getPlacementStrategy",
+		Description: "Returns the default placement strategy of the channel",
+		Arguments:   nsc.ArgumentSchema{},
+		ReadOnly:    true,
+		Handler:     getPlacementStrategy,
+	},
+}
+
+// ============================================================================================================================
+// Argument Schemas - see github.com/NetworkServiceCommon/nsc/arguments.go
+// ============================================================================================================================
+var checkOnNIMSAndRespondArguments = nsc.ArgumentSchema{
+	{Name: "DataCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "Waitlist", Type: nsc.ArgBoolean},
+	{Name: "Priority", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(nsc.MaxPriority)},
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "BurstSize", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxBurstSize)},
+	{Name: "ClassOfService", Type: nsc.ArgString, Enum: classesOfService},
+	// the SLA, see sla.go: latency and jitter in microseconds, loss and availability in parts per million
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+// ============================================================================================================================
+// Check On NIMS And Respond - allocate the order on its circuit and hand it over to ANCS
+// ============================================================================================================================
+
+func checkOnNIMSAndRespond(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	var err error
+	fmt.Println("starting checkOnNIMSAndRespond")
+
+	dataCircuitIDAsQueryKey := arguments.Str("DataCircuitID")
+	orderBandwidthToProcess := arguments.Bandwidth("OrderBandwidth")
+	OrderID := arguments.Str("OrderID")
+	operatorIDToProcess := arguments.Str("OperatorID")
+	events := nsc.NewEventBatch(stub, chaincodeName, OrderID)
+
+	err = assertNotWaitlisted(stub, OrderID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	//===================================================================================
+
+	homeChannel, circuitData, err := queryCircuitOnHomeChannel(stub, dataCircuitIDAsQueryKey)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println("captured circuit data ")
+	fmt.Println(circuitData)
+
+	fmt.Println("======================= orderBandwidthToProcess: ")
+	fmt.Println(orderBandwidthToProcess)
+	fmt.Println("======================= circuitData.UnallocatedBandwidth: ")
+	fmt.Println(circuitData.UnallocatedBandwidth)
+	fmt.Println("==========================================================")
+
+	order := WaitlistEntry{
+		CircuitID:  dataCircuitIDAsQueryKey,
+		OrderID:    OrderID,
+		OperatorID: operatorIDToProcess,
+		Bandwidth:  orderBandwidthToProcess,
+		Priority:   arguments.Integer("Priority"),
+		ExpiresOn:  arguments.Str("ExpiresOn"),
+		Profile:    newBandwidthProfile(arguments),
+		SLA:        newServiceLevel(arguments),
+	}
+
+	// an SLA the circuit does not meet cannot be met by waiting for capacity either
+	if shortfall := order.SLA.circuitShortfall(circuitData); shortfall != "" {
+		err = rejectServiceLevel(stub, events, homeChannel, order, circuitData, fmt.Sprintf("DataCircuit %s does not meet the SLA: %s", circuitData.CircuitID, shortfall))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		return events.Emit(stub)
+	}
+
+	err = processOrder(stub, events, homeChannel, circuitData.UnallocatedBandwidth, availableExcess(circuitData), order, arguments.Boolean("Waitlist"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end checkOnNIMSAndRespond")
+	return events.Emit(stub)
+}
+
+// processOrder fulfils the order when its committed and excess rate fit the available bandwidth of its circuit, otherwise
+// waitlists or rejects it. NIMS may still refuse a leg the unallocated bandwidth holds, when bookings or the service
+// class of the circuit leave less room, the order is then waitlisted or rejected the same way.
+func processOrder(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, homeChannel string, availableBandwidth nsc.Bandwidth, availableExcessBandwidth nsc.Bandwidth, order WaitlistEntry, waitlist bool) error {
+	if order.Bandwidth <= availableBandwidth && order.excessBandwidth() <= availableExcessBandwidth {
+		err := fulfilOrder(stub, events, homeChannel, order)
+		if !isInsufficientCapacity(err) {
+			return err
+		}
+		if waitlist {
+			return waitlistOrder(stub, events, order)
+		}
+		return rejectOrder(stub, events, OrderRejection{
+			OrderID:            order.OrderID,
+			DataCircuitID:      order.CircuitID,
+			OperatorID:         order.OperatorID,
+			ReasonCode:         nsc.CodeInsufficientCapacity,
+			Reason:             err.(*nsc.ChaincodeError).Message,
+			RequestedBandwidth: order.Bandwidth,
+			AvailableBandwidth: availableBandwidth,
+			HomeChannel:        homeChannel,
+			Placement:          order.Placement,
+		})
+	}
+	if waitlist {
+		return waitlistOrder(stub, events, order)
+	}
+	if order.Bandwidth <= availableBandwidth {
+		return rejectOrder(stub, events, OrderRejection{
+			OrderID:                  order.OrderID,
+			DataCircuitID:            order.CircuitID,
+			OperatorID:               order.OperatorID,
+			ReasonCode:               nsc.CodeInsufficientCapacity,
+			Reason:                   fmt.Sprintf("Required excess rate is out of allowance range: %s", order.CircuitID),
+			RequestedBandwidth:       order.Bandwidth,
+			AvailableBandwidth:       availableBandwidth,
+			HomeChannel:              homeChannel,
+			Placement:                order.Placement,
+			RequestedExcessBandwidth: order.excessBandwidth(),
+			AvailableExcessBandwidth: availableExcessBandwidth,
+		})
+	}
+	return rejectOrder(stub, events, OrderRejection{
+		OrderID:            order.OrderID,
+		DataCircuitID:      order.CircuitID,
+		OperatorID:         order.OperatorID,
+		ReasonCode:         nsc.CodeInsufficientCapacity,
+		Reason:             fmt.Sprintf("Required bandwidth is out of allowance range: %s", order.CircuitID),
+		RequestedBandwidth: order.Bandwidth,
+		AvailableBandwidth: availableBandwidth,
+		HomeChannel:        homeChannel,
+		Placement:          order.Placement,
+	})
+}
+
+// fulfilOrder allocates the order's bandwidth in NIMS and completes the order in ANCS, merging their records into events.
+// A split order allocates every leg. A protected order allocates its primary and backup circuit together. The legs and
+// the order are checked by admitOrder first, so an order NIMS or ANCS refuses fails before any called chaincode wrote.
+func fulfilOrder(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, homeChannel string, order WaitlistEntry) error {
+	legs := order.Legs
+	if len(legs) == 0 {
+		legs = []OrderLeg{{DataCircuitID: order.CircuitID, Bandwidth: order.Bandwidth}}
+	}
+	if order.BackupCircuitID != "" {
+		legs = nil
+	}
+	err := admitOrder(stub, homeChannel, order, legs)
+	if err != nil {
+		return err
+	}
+
+	if order.BackupCircuitID != "" {
+		// a protected order is allocated on both circuits at once, NIMS checks that they are diverse
+		err = allocateProtectedOrder(stub, events, homeChannel, order)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, leg := range legs {
+		response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "allocateDataCircuitBandwidth", leg.DataCircuitID, leg.Bandwidth.Argument(), order.OrderID, order.ExpiresOn, order.OperatorID, formatExcess(order.excessBandwidth()))
+		if response.Status != shim.OK {
+			return nsc.UpstreamError(nimsDependency, "allocateDataCircuitBandwidth", response).WithDetail("DataCircuitID", leg.DataCircuitID)
+		}
+
+		err = events.Merge(nimsDependency, response.Payload)
+		if err != nil {
+			return err
+		}
+	}
+
+	// then it auto triggers the signal to Automatic Network Configuration Engine
+	// to assign and configure it to a particular network according to client’s demand
+
+	functionName := "completeOrder"
+
+	legsArgument := ""
+	if len(order.Legs) > 0 {
+		legsAsBytes, err := json.Marshal(order.Legs)
+		if err != nil {
+			return nsc.NewError(nsc.CodeInternal, "unable to convert the legs of order %s to json", order.OrderID)
+		}
+		legsArgument = string(legsAsBytes)
+	}
+
+	profile, err := profileArgument(order)
+	if err != nil {
+		return err
+	}
+
+	sla, err := slaArgument(order)
+	if err != nil {
+		return err
+	}
+
+	response := nsc.InvokeDependency(stub, ancsDependency, functionName, order.OrderID, order.CircuitID, order.Bandwidth.Argument(), order.OperatorID, order.Placement, legsArgument, order.BackupCircuitID, profile, sla)
+	if response.Status != shim.OK {
+		return nsc.UpstreamError(ancsDependency, functionName, response)
+	}
+
+	return events.Merge(ancsDependency, response.Payload)
+}
+
+// admitOrder checks the order the way fulfilOrder's callees will: NIMS admits every leg on its circuit and ANCS has not
+// processed the order yet. Each leg is checked against the committed circuit, a caller fulfils one order per circuit.
+func admitOrder(stub shim.ChaincodeStubInterface, homeChannel string, order WaitlistEntry, legs []OrderLeg) error {
+	for _, leg := range legs {
+		// the allocation is a write on NIMS, it can only commit on the circuit's home channel
+		err := assertWritableChannel(stub, leg.DataCircuitID, homeChannel)
+		if err != nil {
+			return err
+		}
+
+		response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "checkDataCircuitBandwidth", leg.DataCircuitID, leg.Bandwidth.Argument(), order.OrderID, order.ExpiresOn, order.OperatorID, formatExcess(order.excessBandwidth()))
+		if response.Status != shim.OK {
+			return nsc.UpstreamError(nimsDependency, "checkDataCircuitBandwidth", response).WithDetail("DataCircuitID", leg.DataCircuitID)
+		}
+	}
+	return assertOrderNotProcessed(stub, order.OrderID)
+}
+
+// assertOrderNotProcessed fails with CONFLICT when the order is already in the ANCS order store, completed or rejected
+func assertOrderNotProcessed(stub shim.ChaincodeStubInterface, orderID string) error {
+	response := nsc.InvokeDependency(stub, ancsDependency, "getOrder", orderID)
+	if response.Status == shim.OK {
+		return nsc.NewError(nsc.CodeConflict, "Order %s was already processed", orderID).WithDetail("OrderID", orderID)
+	}
+	if cause := nsc.ParseErrorMessage(response.Message); cause.Code != nsc.CodeNotFound {
+		return nsc.UpstreamError(ancsDependency, "getOrder", response)
+	}
+	return nil
+}
+
+// =========================================== Private Libraries ========================================================
+
+func CircuitDatatoJSON(dc DataCircuit) ([]byte, error) {
+
+	fmt.Println("dc before being marshelled")
+	fmt.Println(dc)
+
+	djson, err := json.Marshal(dc)
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	return djson, nil
+}
+
+func JSONtoCircuitData(data []byte) (DataCircuit, error) {
+
+	dc := DataCircuit{}
+	err := json.Unmarshal([]byte(data), &dc)
+	if err != nil {
+		fmt.Println("Unmarshal failed : ", err)
+		return dc, err
+	}
+
+	return dc, nil
+}
+
+func JSONtoOrder(data []byte) (Order, error) {
+
+	dc := Order{}
+	err := json.Unmarshal([]byte(data), &dc)
+	if err != nil {
+		fmt.Println("Unmarshal failed : ", err)
+		return dc, err
+	}
+
+	return dc, nil
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// getTxTimestamp formats the proposal timestamp so every endorser records the same value
+func getTxTimestamp(stub shim.ChaincodeStubInterface) (string, error) {
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(txTime.Seconds, int64(txTime.Nanos)).UTC().Format("20060102150405"), nil
+}
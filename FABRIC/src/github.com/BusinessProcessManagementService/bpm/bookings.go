@@ -0,0 +1,84 @@
+package bpm
+
+import (
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Bookings - NIMS only books and cancels bandwidth for transactions proposed to BPM or OMS, operators book through BPM,
+// which sends the booking to NIMS on the circuit's home channel and re-emits the NIMS records.
+// ============================================================================================================================
+
+var bookBandwidthArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "BookingID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "StartsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "EndsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "OrderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var cancelBookingArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "BookingID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// bookBandwidth books bandwidth on a DataCircuit in NIMS for a time interval, for the invoker when it names an operator
+func bookBandwidth(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting bookBandwidth")
+
+	circuitID := arguments.Str("CircuitID")
+	if arguments.Str("OperatorID") != "" {
+		err := nsc.AssertOperator(stub, arguments.Str("OperatorID"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	response := invokeNIMSForCircuit(stub, circuitID, arguments.Str("BookingID"), "bookBandwidth",
+		circuitID, arguments.Str("BookingID"), arguments.Bandwidth("Bandwidth").Argument(), arguments.Str("StartsOn"), arguments.Str("EndsOn"),
+		arguments.Str("OrderID"), arguments.Str("OperatorID"))
+
+	fmt.Println("- end bookBandwidth")
+	return response
+}
+
+// cancelBooking cancels a booking of a DataCircuit in NIMS that is not over yet
+func cancelBooking(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting cancelBooking")
+
+	circuitID := arguments.Str("CircuitID")
+	response := invokeNIMSForCircuit(stub, circuitID, arguments.Str("BookingID"), "cancelBooking", circuitID, arguments.Str("BookingID"))
+
+	fmt.Println("- end cancelBooking")
+	return response
+}
+
+// invokeNIMSForCircuit calls a writing NIMS function on the home channel of a circuit and emits the records of NIMS
+func invokeNIMSForCircuit(stub shim.ChaincodeStubInterface, circuitID string, correlationID string, functionName string, args ...string) pb.Response {
+	homeChannel, err := resolveCircuitHomeChannel(stub, circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertWritableChannel(stub, circuitID, homeChannel)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, functionName, args...)
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(nimsDependency, functionName, response).WithDetail("CircuitID", circuitID))
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, correlationID)
+	err = events.Merge(nimsDependency, response.Payload)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return events.Emit(stub)
+}
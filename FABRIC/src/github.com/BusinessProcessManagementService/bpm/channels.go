@@ -0,0 +1,217 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Circuit Home Channels - providers may keep their inventory on a regional channel. Reads against NIMS are sent to the
+// circuit's home channel, writes are only possible when the home channel is the channel of the current transaction
+// because Fabric discards the writes of a chaincode invoked on another channel.
+// ============================================================================================================================
+
+const circuitChannelObjectType = "CircuitHomeChannel"
+
+var setCircuitHomeChannelArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ChannelID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxNameLength, Pattern: nsc.ChannelPattern},
+}
+
+var getCircuitHomeChannelArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var checkCircuitCapacityArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+type CircuitHomeChannel struct {
+	CircuitID string `json:"CircuitID"`
+	ChannelID string `json:"ChannelID"`
+	UpdatedBy string `json:"UpdatedBy"`
+	UpdatedOn string `json:"UpdatedOn"`
+}
+
+type CircuitCapacity struct {
+	CircuitID            string        `json:"CircuitID"`
+	HomeChannel          string        `json:"HomeChannel"`
+	RequestedBandwidth   nsc.Bandwidth `json:"RequestedBandwidth"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	Fits                 bool          `json:"Fits"`
+}
+
+// setCircuitHomeChannel maps a DataCircuit to the channel holding its inventory record
+func setCircuitHomeChannel(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setCircuitHomeChannel")
+
+	updatedBy, err := nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	updatedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	homeChannel := CircuitHomeChannel{arguments.Str("CircuitID"), arguments.Str("ChannelID"), updatedBy, updatedOn}
+
+	homeChannelKey, err := stub.CreateCompositeKey(circuitChannelObjectType, []string{homeChannel.CircuitID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(homeChannel)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CircuitHomeChannel to json"))
+	}
+
+	err = stub.PutState(homeChannelKey, buff)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setCircuitHomeChannel")
+	return shim.Success(buff)
+}
+
+// getCircuitHomeChannel returns the channel a circuit resolves to
+func getCircuitHomeChannel(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	circuitID := arguments.Str("CircuitID")
+	channelID, err := resolveCircuitHomeChannel(stub, circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(CircuitHomeChannel{CircuitID: circuitID, ChannelID: channelID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
+
+// checkCircuitCapacity is a read only capacity check that works across channels
+func checkCircuitCapacity(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting checkCircuitCapacity")
+
+	circuitID := arguments.Str("CircuitID")
+	requestedBandwidth := arguments.Bandwidth("Bandwidth")
+
+	homeChannel, circuitData, err := queryCircuitOnHomeChannel(stub, circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	capacity := CircuitCapacity{
+		CircuitID:            circuitID,
+		HomeChannel:          homeChannel,
+		RequestedBandwidth:   requestedBandwidth,
+		UnallocatedBandwidth: circuitData.UnallocatedBandwidth,
+		Fits:                 requestedBandwidth <= circuitData.UnallocatedBandwidth,
+	}
+
+	buff, err := json.Marshal(capacity)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end checkCircuitCapacity")
+	return shim.Success(buff)
+}
+
+// resolveCircuitHomeChannel returns the mapped channel of a circuit, defaulting to the channel NIMS is registered on
+func resolveCircuitHomeChannel(stub shim.ChaincodeStubInterface, circuitID string) (string, error) {
+	homeChannelKey, err := stub.CreateCompositeKey(circuitChannelObjectType, []string{circuitID})
+	if err != nil {
+		return "", err
+	}
+
+	homeChannelAsBytes, err := stub.GetState(homeChannelKey)
+	if err != nil {
+		return "", err
+	}
+
+	if homeChannelAsBytes == nil {
+		dependency, err := nsc.GetChaincodeDependency(stub, nimsDependency)
+		if err != nil {
+			return "", err
+		}
+		return dependency.ChannelID, nil
+	}
+
+	var homeChannel CircuitHomeChannel
+	err = json.Unmarshal(homeChannelAsBytes, &homeChannel)
+	if err != nil {
+		return "", err
+	}
+	return homeChannel.ChannelID, nil
+}
+
+// listCircuitHomeChannels reads every mapped home channel in one range query, by CircuitID, with the channel NIMS is
+// registered on that unmapped circuits default to
+func listCircuitHomeChannels(stub shim.ChaincodeStubInterface) (map[string]string, string, error) {
+	dependency, err := nsc.GetChaincodeDependency(stub, nimsDependency)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(circuitChannelObjectType, []string{})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resultsIterator.Close()
+
+	homeChannels := map[string]string{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, "", err
+		}
+		var homeChannel CircuitHomeChannel
+		err = json.Unmarshal(queryResponse.Value, &homeChannel)
+		if err != nil {
+			return nil, "", err
+		}
+		homeChannels[homeChannel.CircuitID] = homeChannel.ChannelID
+	}
+	return homeChannels, dependency.ChannelID, nil
+}
+
+// queryCircuitOnHomeChannel reads a DataCircuit from NIMS on the circuit's home channel
+func queryCircuitOnHomeChannel(stub shim.ChaincodeStubInterface, circuitID string) (string, DataCircuit, error) {
+	var circuitData DataCircuit
+
+	homeChannel, err := resolveCircuitHomeChannel(stub, circuitID)
+	if err != nil {
+		return "", circuitData, err
+	}
+
+	response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "checkBandwithAllowanceOnCircuit", circuitID)
+	if response.Status != shim.OK {
+		return homeChannel, circuitData, nsc.UpstreamError(nimsDependency, "checkBandwithAllowanceOnCircuit", response).WithDetail("HomeChannel", homeChannel)
+	}
+
+	circuitData, err = JSONtoCircuitData(response.Payload)
+	if err != nil {
+		return homeChannel, circuitData, nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling - %s", circuitID)
+	}
+	return homeChannel, circuitData, nil
+}
+
+// assertWritableChannel refuses work that would have to commit on another channel
+func assertWritableChannel(stub shim.ChaincodeStubInterface, circuitID string, homeChannel string) error {
+	if homeChannel == "" || homeChannel == stub.GetChannelID() {
+		return nil
+	}
+	return nsc.NewError(nsc.CodeForbidden, "DataCircuit %s lives on channel %s but this transaction runs on channel %s; "+
+		"bandwidth can only be allocated on the circuit's home channel because cross-channel writes are never committed",
+		circuitID, homeChannel, stub.GetChannelID()).
+		WithDetail("CircuitID", circuitID).
+		WithDetail("HomeChannel", homeChannel)
+}
@@ -0,0 +1,14 @@
+package bpm
+
+// ============================================================================================================================
+// Chaincode Dependencies - see github.com/NetworkServiceCommon/nsc/dependencies.go
+// ============================================================================================================================
+
+// aliases of the chaincodes BPM calls into, and of OMS, which order transactions are proposed to
+const (
+	nimsDependency = "NIMS"
+	ancsDependency = "ANCS"
+	omsDependency  = "OMS"
+)
+
+var knownDependencies = []string{nimsDependency, ancsDependency, omsDependency}
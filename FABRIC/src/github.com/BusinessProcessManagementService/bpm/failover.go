@@ -0,0 +1,324 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Circuit Failover - failoverCircuit re-homes the orders of a circuit NIMS has marked Down. The primary of a protected
+// order moves onto its backup, which already holds the bandwidth. Any other allocation moves onto a circuit of the same
+// network chosen with the channel's placement strategy, skipping circuits the order already uses and, for a protected
+// order, circuits that are not diverse from its other circuit. A hop of a path order only moves onto a circuit between
+// the same two sites, an order with an SLA only onto circuits meeting it. NIMS moves the allocations in one
+// moveAllocations call, ANCS re-homes each order and opens a reconfiguration job. Orders that cannot be moved stay on
+// the Down circuit and are listed as stranded in the FailoverReport kept under "FailoverReport"[CircuitID],
+// failoverCircuit can be run again once capacity was added.
+// ============================================================================================================================
+
+const failoverReportObjectType = "FailoverReport"
+
+// the status NIMS gives a failed circuit
+const circuitDown = "Down"
+
+var failoverArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// Allocation is the NIMS record of the bandwidth an order holds on a circuit
+type Allocation struct {
+	CircuitID  string        `json:"CircuitID"`
+	OrderID    string        `json:"OrderID"`
+	Bandwidth  nsc.Bandwidth `json:"Bandwidth"`
+	ExpiresOn  string        `json:"ExpiresOn,omitempty"`
+	OperatorID string        `json:"OperatorID,omitempty"`
+	// ExcessBandwidth is the excess rate of the order's bandwidth profile, it moves with the allocation
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+}
+
+// AllocationMoveRequest is one move of NIMS moveAllocations
+type AllocationMoveRequest struct {
+	OrderID     string `json:"OrderID"`
+	ToCircuitID string `json:"ToCircuitID"`
+}
+
+type FailoverMove struct {
+	OrderID     string        `json:"OrderID"`
+	ToCircuitID string        `json:"ToCircuitID"`
+	Bandwidth   nsc.Bandwidth `json:"Bandwidth"`
+	ToBackup    bool          `json:"ToBackup,omitempty"`
+}
+
+type StrandedOrder struct {
+	OrderID   string        `json:"OrderID"`
+	Bandwidth nsc.Bandwidth `json:"Bandwidth"`
+	Reason    string        `json:"Reason"`
+}
+
+// FailoverReport is the data of FailoverReported events
+type FailoverReport struct {
+	CircuitID      string          `json:"CircuitID"`
+	CircuitNetwork string          `json:"CircuitNetwork"`
+	Strategy       string          `json:"Strategy"`
+	Moved          []FailoverMove  `json:"Moved"`
+	Stranded       []StrandedOrder `json:"Stranded"`
+	ReportedOn     string          `json:"ReportedOn"`
+}
+
+func failoverCircuit(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting failoverCircuit")
+
+	circuitID := arguments.Str("CircuitID")
+	events := nsc.NewEventBatch(stub, chaincodeName, circuitID)
+
+	homeChannel, failedCircuit, err := queryCircuitOnHomeChannel(stub, circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertWritableChannel(stub, circuitID, homeChannel)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if failedCircuit.Status != circuitDown {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "DataCircuit %s is not Down", circuitID).
+			WithDetail("CircuitID", circuitID).
+			WithDetail("Status", failedCircuit.Status))
+	}
+
+	response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "getCircuitAllocations", circuitID)
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(nimsDependency, "getCircuitAllocations", response))
+	}
+	var allocations []Allocation
+	err = json.Unmarshal(response.Payload, &allocations)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the allocations of %s", circuitID))
+	}
+
+	strategy, err := getPlacementStrategyValue(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	candidates, err := listPlacementCandidates(stub, failedCircuit.CircuitNetwork, "")
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	report := FailoverReport{
+		CircuitID:      circuitID,
+		CircuitNetwork: failedCircuit.CircuitNetwork,
+		Strategy:       strategy,
+		Moved:          []FailoverMove{},
+		Stranded:       []StrandedOrder{},
+	}
+	for _, allocation := range allocations {
+		move, reason, err := planRehome(stub, homeChannel, allocation, candidates, strategy)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if reason != "" {
+			fmt.Println("order " + allocation.OrderID + " is stranded: " + reason)
+			report.Stranded = append(report.Stranded, StrandedOrder{allocation.OrderID, allocation.Bandwidth, reason})
+			continue
+		}
+
+		report.Moved = append(report.Moved, move)
+		if !move.ToBackup {
+			// later orders see the capacity this one took
+			for i := range candidates {
+				if candidates[i].CircuitID == move.ToCircuitID {
+					candidates[i].UnallocatedBandwidth = candidates[i].UnallocatedBandwidth - move.Bandwidth
+					candidates[i].ExcessBandwidth = candidates[i].ExcessBandwidth + allocation.ExcessBandwidth
+				}
+			}
+		}
+	}
+
+	err = rehomeAllocations(stub, events, homeChannel, circuitID, report.Moved)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	report.ReportedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	reportKey, err := stub.CreateCompositeKey(failoverReportObjectType, []string{circuitID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	buff, err := json.Marshal(report)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert FailoverReport to json"))
+	}
+	err = stub.PutState(reportKey, buff)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	err = events.Add(nsc.EventFailoverReported, report)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end failoverCircuit")
+	return events.Emit(stub)
+}
+
+func getFailoverReport(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	circuitID := arguments.Str("CircuitID")
+
+	reportKey, err := stub.CreateCompositeKey(failoverReportObjectType, []string{circuitID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	reportAsBytes, err := stub.GetState(reportKey)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if reportAsBytes == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "No failover was run for %s", circuitID).WithDetail("CircuitID", circuitID))
+	}
+	return shim.Success(reportAsBytes)
+}
+
+// planRehome chooses where one allocation off the failed circuit moves. It returns the reason the order is stranded
+// instead, an error fails the whole failover.
+func planRehome(stub shim.ChaincodeStubInterface, homeChannel string, allocation Allocation, candidates []DataCircuit, strategy string) (FailoverMove, string, error) {
+	move := FailoverMove{OrderID: allocation.OrderID, Bandwidth: allocation.Bandwidth}
+
+	order, diverseOn, reason, err := readAffectedOrder(stub, homeChannel, allocation.OrderID)
+	if err != nil || reason != "" {
+		return move, reason, err
+	}
+
+	candidates, err = hopCandidates(stub, homeChannel, order, allocation, meetingServiceLevel(candidates, order.SLA))
+	if err != nil {
+		return move, "", err
+	}
+	target, toBackup, found := selectRehomeTarget(order, allocation, candidates, diverseOn, strategy, nil)
+	if !found {
+		return move, fmt.Sprintf("no eligible circuit has room for %s", allocation.Bandwidth), nil
+	}
+	move.ToCircuitID = target
+	move.ToBackup = toBackup
+	return move, "", nil
+}
+
+// rehomeAllocations moves the planned allocations off the failed circuit in one NIMS call, a transaction does not read
+// its own writes and moving them one call at a time would keep only the last move on each circuit. ANCS then re-homes
+// every moved order.
+func rehomeAllocations(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, homeChannel string, circuitID string, moves []FailoverMove) error {
+	if len(moves) == 0 {
+		return nil
+	}
+
+	requests := make([]AllocationMoveRequest, len(moves))
+	for i, move := range moves {
+		requests[i] = AllocationMoveRequest{move.OrderID, move.ToCircuitID}
+	}
+	movesAsBytes, err := json.Marshal(requests)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert the moves off %s to json", circuitID)
+	}
+
+	response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "moveAllocations", circuitID, string(movesAsBytes))
+	if response.Status != shim.OK {
+		return nsc.UpstreamError(nimsDependency, "moveAllocations", response).WithDetail("CircuitID", circuitID)
+	}
+	err = events.Merge(nimsDependency, response.Payload)
+	if err != nil {
+		return err
+	}
+
+	for _, move := range moves {
+		response = nsc.InvokeDependency(stub, ancsDependency, "reconfigureOrder", move.OrderID, circuitID, move.ToCircuitID)
+		if response.Status != shim.OK {
+			return nsc.UpstreamError(ancsDependency, "reconfigureOrder", response).WithDetail("OrderID", move.OrderID)
+		}
+		err = events.Merge(ancsDependency, response.Payload)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readAffectedOrder reads the order holding an allocation from ANCS and, for a protected order, the attributes its
+// circuits are diverse on. An order ANCS cannot return is not an error, the reason is returned instead.
+func readAffectedOrder(stub shim.ChaincodeStubInterface, homeChannel string, orderID string) (Order, []string, string, error) {
+	var order Order
+
+	response := nsc.InvokeDependency(stub, ancsDependency, "getOrder", orderID)
+	if response.Status != shim.OK {
+		return order, nil, "the order could not be read from ANCS: " + nsc.ParseErrorMessage(response.Message).Message, nil
+	}
+	order, err := JSONtoOrder(response.Payload)
+	if err != nil {
+		return order, nil, "", nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling order %s", orderID)
+	}
+	if order.BackupCircuitID == "" {
+		return order, nil, "", nil
+	}
+
+	response = nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "getProtectionGroup", orderID)
+	if response.Status != shim.OK {
+		return order, nil, "", nsc.UpstreamError(nimsDependency, "getProtectionGroup", response)
+	}
+	var group struct {
+		DiverseOn []string `json:"DiverseOn"`
+	}
+	err = json.Unmarshal(response.Payload, &group)
+	if err != nil {
+		return order, nil, "", nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the protection group of %s", orderID)
+	}
+	return order, group.DiverseOn, "", nil
+}
+
+// selectRehomeTarget chooses where an allocation moves: the backup of a protected order when its primary is affected
+// and the backup is still a candidate, otherwise the best ranked candidate the order does not use yet and, for a
+// protected order, that is diverse from its surviving circuit. Circuits in exclude are skipped as well.
+func selectRehomeTarget(order Order, allocation Allocation, candidates []DataCircuit, diverseOn []string, strategy string, exclude []string) (string, bool, bool) {
+	var surviving *DataCircuit
+	if order.BackupCircuitID != "" {
+		otherCircuitID := order.BackupCircuitID
+		if otherCircuitID == allocation.CircuitID {
+			otherCircuitID = order.DataCircuitID
+		}
+		for i := range candidates {
+			if candidates[i].CircuitID == otherCircuitID {
+				surviving = &candidates[i]
+			}
+		}
+	}
+	if surviving != nil && allocation.CircuitID == order.DataCircuitID {
+		return surviving.CircuitID, true, true
+	}
+
+	usedCircuits := append([]string{order.DataCircuitID, order.BackupCircuitID}, exclude...)
+	for _, leg := range order.Legs {
+		usedCircuits = append(usedCircuits, leg.DataCircuitID)
+	}
+
+	eligible := []DataCircuit{}
+	for _, candidate := range candidates {
+		if stringInSlice(candidate.CircuitID, usedCircuits) {
+			continue
+		}
+		if order.BackupCircuitID != "" && (surviving == nil || !isDiverse(*surviving, candidate, diverseOn)) {
+			continue
+		}
+		eligible = append(eligible, candidate)
+	}
+
+	ranked := rankCandidates(eligible, allocation.Bandwidth, allocation.ExcessBandwidth, strategy)
+	if len(ranked) == 0 {
+		return "", false, false
+	}
+	return ranked[0].CircuitID, false, true
+}
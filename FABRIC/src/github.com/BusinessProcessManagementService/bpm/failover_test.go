@@ -0,0 +1,122 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestFailoverRequiresAdmin(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 100 * simulator.Mbps})
+	expectOK(t, s.SubmitOrder(alice, "O1", "C1", 50*simulator.Mbps))
+	if err := s.SetCircuitStatus("C1", "Down"); err != nil {
+		t.Fatal(err)
+	}
+
+	// neither the failover nor the reconfiguration it makes can be run by an operator
+	expectCode(t, s.Invoke(simulator.BPM, alice, "failoverCircuit", "C1"), nsc.CodeForbidden)
+	expectCode(t, s.Invoke(simulator.ANCS, alice, "reconfigureOrder", "O1", "C1", "C2"), nsc.CodeForbidden)
+
+	expectOK(t, s.FailoverCircuit("C1"))
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.DataCircuitID != "C2" {
+		t.Errorf("expected O1 to be moved to C2, it is on %s", order.DataCircuitID)
+	}
+}
+
+// failover runs failoverCircuit and returns its FailoverReported record
+func failover(t *testing.T, s *simulator.Simulator, circuitID string) bpm.FailoverReport {
+	t.Helper()
+	result := s.FailoverCircuit(circuitID)
+	expectOK(t, result)
+	records := result.Records(nsc.EventFailoverReported)
+	if len(records) != 1 {
+		t.Fatalf("expected one %s record, got %d", nsc.EventFailoverReported, len(records))
+	}
+	var report bpm.FailoverReport
+	err := json.Unmarshal(records[0].Data, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return report
+}
+
+func TestFailoverMovesOrdersAndReportsStranded(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 70 * simulator.Mbps, "C3": 20 * simulator.Mbps})
+	expectOK(t, s.SubmitOrder(alice, "O1", "C1", 60*simulator.Mbps))
+	expectOK(t, s.SubmitOrder(alice, "O2", "C1", 30*simulator.Mbps))
+
+	// a circuit that is up keeps its orders
+	expectCode(t, s.FailoverCircuit("C1"), nsc.CodeConflict)
+	if err := s.SetCircuitStatus("C1", "Down"); err != nil {
+		t.Fatal(err)
+	}
+
+	// O1 takes C2, after it no circuit has room for O2
+	report := failover(t, s, "C1")
+	if len(report.Moved) != 1 || report.Moved[0].OrderID != "O1" || report.Moved[0].ToCircuitID != "C2" {
+		t.Errorf("expected O1 moved to C2, got %+v", report.Moved)
+	}
+	if len(report.Stranded) != 1 || report.Stranded[0].OrderID != "O2" {
+		t.Errorf("expected O2 stranded, got %+v", report.Stranded)
+	}
+	if err := s.ExpectBandwidth("C2", 60*simulator.Mbps, 10*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	// once capacity was added the failover is run again for what is left
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C4": 50 * simulator.Mbps})
+	report = failover(t, s, "C1")
+	if len(report.Moved) != 1 || report.Moved[0].OrderID != "O2" || report.Moved[0].ToCircuitID != "C4" || len(report.Stranded) != 0 {
+		t.Errorf("expected O2 moved to C4, got %+v", report)
+	}
+	order, err := s.Order("O2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.DataCircuitID != "C4" {
+		t.Errorf("expected O2 to be re-homed on C4, it is on %s", order.DataCircuitID)
+	}
+	if err = s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailoverMovesEveryOrderOntoOneCircuit(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 100 * simulator.Mbps})
+	expectOK(t, s.SubmitOrder(alice, "O1", "C1", 30*simulator.Mbps))
+	expectOK(t, s.SubmitOrder(alice, "O2", "C1", 20*simulator.Mbps))
+	if err := s.SetCircuitStatus("C1", "Down"); err != nil {
+		t.Fatal(err)
+	}
+
+	// both moves land on C2 and both release C1 in the same transaction, neither may overwrite the other
+	report := failover(t, s, "C1")
+	if len(report.Moved) != 2 || len(report.Stranded) != 0 {
+		t.Fatalf("expected O1 and O2 moved, got %+v", report)
+	}
+	if err := s.ExpectBandwidth("C2", 50*simulator.Mbps, 50*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+	for _, orderID := range []string{"O1", "O2"} {
+		order, err := s.Order(orderID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if order.DataCircuitID != "C2" {
+			t.Errorf("expected %s to be re-homed on C2, it is on %s", orderID, order.DataCircuitID)
+		}
+	}
+}
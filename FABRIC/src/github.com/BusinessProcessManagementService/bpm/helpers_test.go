@@ -0,0 +1,39 @@
+package bpm_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+var (
+	alice    = simulator.OperatorIdentity("alice")
+	provider = simulator.ProviderAdminIdentity("Org1MSP")
+)
+
+func expectOK(t *testing.T, result simulator.Result) {
+	t.Helper()
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+}
+
+func expectCode(t *testing.T, result simulator.Result, code string) {
+	t.Helper()
+	if result.OK() {
+		t.Fatalf("expected %s, the call succeeded", code)
+	}
+	if result.Err == nil || result.Err.Code != code {
+		t.Fatalf("expected %s, got %v", code, result.Error())
+	}
+}
+
+// fillCircuit seeds a circuit and allocates all of it to one order
+func fillCircuit(t *testing.T, s *simulator.Simulator, circuitID string, orderID string, bandwidth nsc.Bandwidth) {
+	t.Helper()
+	if err := s.SeedCircuit(circuitID, "NET1", "Org1MSP", bandwidth); err != nil {
+		t.Fatal(err)
+	}
+	expectOK(t, s.SubmitOrder(alice, orderID, circuitID, bandwidth))
+}
@@ -0,0 +1,217 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Impact Analysis - analyzeCircuitImpact answers what taking circuits down would do before a provider does it. It reads
+// every allocation of the circuits from NIMS and its order from ANCS, and runs the failoverCircuit choice of a new circuit
+// without writing anything: the analyzed circuits are no candidates, and each allocation that could be re-homed takes
+// its bandwidth off the candidate it would move to, so the estimate holds for the circuits going down together.
+// ============================================================================================================================
+
+// protection status of an affected allocation
+const (
+	protectionNone       = "Unprotected"
+	protectionFailover   = "FailsOverToBackup"
+	protectionLoseBackup = "LosesBackup"
+	protectionLost       = "BothCircuitsAffected"
+)
+
+// at most this many circuits are analyzed at once
+const maxImpactCircuits = 32
+
+var analyzeCircuitImpactArguments = nsc.ArgumentSchema{
+	// comma separated CircuitIDs
+	{Name: "CircuitIDs", Type: nsc.ArgString, Required: true, MaxLength: maxImpactCircuits * (nsc.MaxIDLength + 1), Pattern: `^[A-Za-z0-9][A-Za-z0-9._:-]*(,[A-Za-z0-9][A-Za-z0-9._:-]*)*$`},
+}
+
+type ImpactedAllocation struct {
+	CircuitID  string        `json:"CircuitID"`
+	OrderID    string        `json:"OrderID"`
+	OperatorID string        `json:"OperatorID"`
+	Bandwidth  nsc.Bandwidth `json:"Bandwidth"`
+	ExpiresOn  string        `json:"ExpiresOn,omitempty"`
+	Protection string        `json:"Protection"`
+	// RehomeCircuitID is where failoverCircuit would move the allocation, empty when it would be stranded
+	RehomeCircuitID string `json:"RehomeCircuitID,omitempty"`
+	ToBackup        bool   `json:"ToBackup,omitempty"`
+	Reason          string `json:"Reason,omitempty"`
+}
+
+type ImpactedCircuit struct {
+	CircuitID          string               `json:"CircuitID"`
+	CircuitNetwork     string               `json:"CircuitNetwork"`
+	ProviderID         string               `json:"ProviderID"`
+	Status             string               `json:"Status,omitempty"`
+	AllocatedBandwidth nsc.Bandwidth        `json:"AllocatedBandwidth"`
+	Allocations        []ImpactedAllocation `json:"Allocations"`
+}
+
+type CircuitImpact struct {
+	Circuits           []ImpactedCircuit `json:"Circuits"`
+	Orders             []string          `json:"Orders"`
+	Operators          []string          `json:"Operators"`
+	AffectedBandwidth  nsc.Bandwidth     `json:"AffectedBandwidth"`
+	RehomableBandwidth nsc.Bandwidth     `json:"RehomableBandwidth"`
+	StrandedBandwidth  nsc.Bandwidth     `json:"StrandedBandwidth"`
+	Strategy           string            `json:"Strategy"`
+}
+
+func analyzeCircuitImpact(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting analyzeCircuitImpact")
+
+	circuitIDs := []string{}
+	for _, circuitID := range strings.Split(arguments.Str("CircuitIDs"), ",") {
+		if !stringInSlice(circuitID, circuitIDs) {
+			circuitIDs = append(circuitIDs, circuitID)
+		}
+	}
+	if len(circuitIDs) > maxImpactCircuits {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "at most %d circuits can be analyzed at once", maxImpactCircuits))
+	}
+
+	strategy, err := getPlacementStrategyValue(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	impact := CircuitImpact{Circuits: []ImpactedCircuit{}, Orders: []string{}, Operators: []string{}, Strategy: strategy}
+	// candidates per network, their unallocated bandwidth shrinks with every allocation that would move onto them
+	candidatesByNetwork := map[string][]DataCircuit{}
+	// circuits an order would newly use, so two of its legs are not re-homed onto the same circuit
+	rehomedTo := map[string][]string{}
+
+	for _, circuitID := range circuitIDs {
+		homeChannel, circuitData, err := queryCircuitOnHomeChannel(stub, circuitID)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+
+		response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "getCircuitAllocations", circuitID)
+		if response.Status != shim.OK {
+			return nsc.ErrorResponse(nsc.UpstreamError(nimsDependency, "getCircuitAllocations", response))
+		}
+		var allocations []Allocation
+		err = json.Unmarshal(response.Payload, &allocations)
+		if err != nil {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the allocations of %s", circuitID))
+		}
+
+		candidates, found := candidatesByNetwork[circuitData.CircuitNetwork]
+		if !found {
+			listed, err := listPlacementCandidates(stub, circuitData.CircuitNetwork, "")
+			if err != nil {
+				return nsc.ErrorResponse(err)
+			}
+			candidates = []DataCircuit{}
+			for _, candidate := range listed {
+				if !stringInSlice(candidate.CircuitID, circuitIDs) {
+					candidates = append(candidates, candidate)
+				}
+			}
+			candidatesByNetwork[circuitData.CircuitNetwork] = candidates
+		}
+
+		impacted := ImpactedCircuit{
+			CircuitID:          circuitID,
+			CircuitNetwork:     circuitData.CircuitNetwork,
+			ProviderID:         circuitData.ProviderID,
+			Status:             circuitData.Status,
+			AllocatedBandwidth: circuitData.AllocatedBandwidth,
+			Allocations:        []ImpactedAllocation{},
+		}
+		for _, allocation := range allocations {
+			affected := ImpactedAllocation{
+				CircuitID:  circuitID,
+				OrderID:    allocation.OrderID,
+				Bandwidth:  allocation.Bandwidth,
+				ExpiresOn:  allocation.ExpiresOn,
+				Protection: protectionNone,
+			}
+
+			order, diverseOn, reason, err := readAffectedOrder(stub, homeChannel, allocation.OrderID)
+			if err != nil {
+				return nsc.ErrorResponse(err)
+			}
+			if reason == "" {
+				affected.OperatorID = order.OperatorID
+				affected.Protection = protectionStatus(order, circuitID, circuitIDs)
+
+				eligible, err := hopCandidates(stub, homeChannel, order, allocation, meetingServiceLevel(candidates, order.SLA))
+				if err != nil {
+					return nsc.ErrorResponse(err)
+				}
+				target, toBackup, fits := selectRehomeTarget(order, allocation, eligible, diverseOn, strategy, rehomedTo[order.OrderID])
+				if fits {
+					affected.RehomeCircuitID = target
+					affected.ToBackup = toBackup
+					if !toBackup {
+						rehomedTo[order.OrderID] = append(rehomedTo[order.OrderID], target)
+						for i := range candidates {
+							if candidates[i].CircuitID == target {
+								candidates[i].UnallocatedBandwidth = candidates[i].UnallocatedBandwidth - allocation.Bandwidth
+								candidates[i].ExcessBandwidth = candidates[i].ExcessBandwidth + allocation.ExcessBandwidth
+							}
+						}
+					}
+				} else {
+					reason = fmt.Sprintf("no eligible circuit has room for %s", allocation.Bandwidth)
+				}
+			}
+			affected.Reason = reason
+
+			impact.AffectedBandwidth = impact.AffectedBandwidth + allocation.Bandwidth
+			if affected.RehomeCircuitID != "" {
+				impact.RehomableBandwidth = impact.RehomableBandwidth + allocation.Bandwidth
+			} else {
+				impact.StrandedBandwidth = impact.StrandedBandwidth + allocation.Bandwidth
+			}
+			if !stringInSlice(affected.OrderID, impact.Orders) {
+				impact.Orders = append(impact.Orders, affected.OrderID)
+			}
+			if affected.OperatorID != "" && !stringInSlice(affected.OperatorID, impact.Operators) {
+				impact.Operators = append(impact.Operators, affected.OperatorID)
+			}
+			impacted.Allocations = append(impacted.Allocations, affected)
+		}
+		impact.Circuits = append(impact.Circuits, impacted)
+	}
+	sort.Strings(impact.Orders)
+	sort.Strings(impact.Operators)
+
+	impactAsBytes, err := json.Marshal(impact)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CircuitImpact to json"))
+	}
+
+	fmt.Println("- end analyzeCircuitImpact")
+	return shim.Success(impactAsBytes)
+}
+
+// protectionStatus tells what happens to the protection of an order when the analyzed circuits go down
+func protectionStatus(order Order, circuitID string, circuitIDs []string) string {
+	if order.BackupCircuitID == "" {
+		return protectionNone
+	}
+	otherCircuitID := order.BackupCircuitID
+	if circuitID == order.BackupCircuitID {
+		otherCircuitID = order.DataCircuitID
+	}
+	switch {
+	case stringInSlice(otherCircuitID, circuitIDs):
+		return protectionLost
+	case circuitID == order.DataCircuitID:
+		return protectionFailover
+	default:
+		return protectionLoseBackup
+	}
+}
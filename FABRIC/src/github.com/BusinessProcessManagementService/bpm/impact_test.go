@@ -0,0 +1,58 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestImpactOfCircuitsGoingDownTogether(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 100 * simulator.Mbps, "C3": 50 * simulator.Mbps})
+	expectOK(t, s.SubmitOrder(alice, "O1", "C1", 60*simulator.Mbps))
+	expectOK(t, s.SubmitOrder(alice, "O2", "C1", 30*simulator.Mbps))
+	expectOK(t, s.SubmitOrder(simulator.OperatorIdentity("bob"), "O3", "C2", 40*simulator.Mbps))
+
+	// only C3 is left, O2 takes 30M of it and leaves no room for O1 or O3
+	result := s.Invoke(simulator.BPM, alice, "analyzeCircuitImpact", "C1,C2,C1")
+	expectOK(t, result)
+	var impact bpm.CircuitImpact
+	if err := json.Unmarshal(result.Response.Payload, &impact); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(impact.Circuits) != 2 || strings.Join(impact.Orders, ",") != "O1,O2,O3" || strings.Join(impact.Operators, ",") != "alice,bob" {
+		t.Fatalf("expected O1, O2 and O3 of alice and bob on C1 and C2, got %+v", impact)
+	}
+	if impact.AffectedBandwidth != 130*simulator.Mbps || impact.RehomableBandwidth != 30*simulator.Mbps || impact.StrandedBandwidth != 100*simulator.Mbps {
+		t.Errorf("expected 130M affected of which 30M rehomable, got %+v", impact)
+	}
+	rehomed := map[string]string{}
+	for _, circuit := range impact.Circuits {
+		for _, allocation := range circuit.Allocations {
+			rehomed[allocation.OrderID] = allocation.RehomeCircuitID
+			if allocation.Protection != "Unprotected" {
+				t.Errorf("expected %s to be unprotected, got %s", allocation.OrderID, allocation.Protection)
+			}
+		}
+	}
+	if rehomed["O1"] != "" || rehomed["O2"] != "C3" || rehomed["O3"] != "" {
+		t.Errorf("expected only O2 to be re-homed, on C3, got %v", rehomed)
+	}
+
+	// the analysis moves nothing
+	if err := s.ExpectBandwidth("C3", 0, 50*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+	order, err := s.Order("O2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.DataCircuitID != "C1" {
+		t.Errorf("expected O2 to stay on C1, it is on %s", order.DataCircuitID)
+	}
+}
@@ -0,0 +1,494 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Path Orders - placePathOrder takes an A-end and a Z-end site instead of a circuit and routes the order over the circuits
+// NIMS links between the sites of a network, computePath only returns the route. A route uses placement candidates that
+// can hold the whole order, every hop carries all of its bandwidth. It minimises the metric: the number of hops, the sum
+// of the latency NIMS recorded for its circuits or the sum of their Cost attribute, circuits without a valid value are
+// left out. Ties go to fewer hops and then to the lowest CircuitID hop by hop, so every endorser computes the same route.
+// With an SLA only circuits meeting it are used and the route must meet it end to end, which the latency metric
+// guarantees for MaxLatency whenever any route can. The order gets one leg per hop, which fulfilOrder allocates in one
+// transaction, and is rejected when no route has room or meets the SLA.
+// ============================================================================================================================
+
+// path metrics
+const (
+	metricHops    = "hops"
+	metricLatency = "latency"
+	metricCost    = "cost"
+)
+
+var pathMetrics = []string{metricHops, metricLatency, metricCost}
+
+// the DataCircuit attribute the cost metric is read from
+const costAttribute = "Cost"
+
+// a Cost attribute above this is not a valid value
+const maxCircuitCost = 1000000000
+
+var computePathArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ASiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ZSiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "Metric", Type: nsc.ArgString, Enum: pathMetrics},
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	// the SLA, see sla.go: latency and jitter in microseconds, loss and availability in parts per million
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+var placePathOrderArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ASiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ZSiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Metric", Type: nsc.ArgString, Enum: pathMetrics},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "BurstSize", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxBurstSize)},
+	{Name: "ClassOfService", Type: nsc.ArgString, Enum: classesOfService},
+	// the SLA, see sla.go: latency and jitter in microseconds, loss and availability in parts per million
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+// CircuitLink is the NIMS record of a circuit linked between two sites
+type CircuitLink struct {
+	CircuitID string `json:"CircuitID"`
+	ASiteID   string `json:"ASiteID"`
+	ZSiteID   string `json:"ZSiteID"`
+}
+
+// PathHop is one circuit of a route, crossed from FromSiteID to ToSiteID
+type PathHop struct {
+	CircuitID            string        `json:"CircuitID"`
+	FromSiteID           string        `json:"FromSiteID"`
+	ToSiteID             string        `json:"ToSiteID"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	// AvailableExcessBandwidth is the excess rate left on the circuit, see availableExcess
+	AvailableExcessBandwidth nsc.Bandwidth       `json:"AvailableExcessBandwidth"`
+	Metric                   int64               `json:"Metric"`
+	Performance              *CircuitPerformance `json:"Performance,omitempty"`
+}
+
+// ComputedPath is returned by computePath. Without a route that fits, Fits is false and Hops is the route the metric
+// would take regardless of room, Bottleneck is then its first hop that cannot hold the order. SLAShortfall is set when
+// the route does not meet the SLA, Hops is empty when no route of circuits meeting it joins the sites.
+type ComputedPath struct {
+	CircuitNetwork string        `json:"CircuitNetwork"`
+	ASiteID        string        `json:"ASiteID"`
+	ZSiteID        string        `json:"ZSiteID"`
+	Bandwidth      nsc.Bandwidth `json:"Bandwidth"`
+	Metric         string        `json:"Metric"`
+	// Candidates is the number of linked placement candidates the route was searched over
+	Candidates   int       `json:"Candidates"`
+	Fits         bool      `json:"Fits"`
+	Hops         []PathHop `json:"Hops"`
+	TotalMetric  int64     `json:"TotalMetric"`
+	Bottleneck   string    `json:"Bottleneck,omitempty"`
+	SLAShortfall string    `json:"SLAShortfall,omitempty"`
+	// slaCircuit is the first circuit short of the SLA on the route of a path order no route meets it for
+	slaCircuit DataCircuit
+}
+
+// pathEdge is a circuit leaving a site in the graph findPath searches
+type pathEdge struct {
+	dataCircuit DataCircuit
+	farSiteID   string
+	metric      int64
+}
+
+func computePath(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	path, err := computePathForArguments(stub, arguments)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(path)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert ComputedPath to json"))
+	}
+	return shim.Success(buff)
+}
+
+// placePathOrder routes the order between its sites and fulfils it with one leg per hop, or rejects it against the
+// bottleneck of the route it would take when no route has room. Path orders are never waitlisted.
+func placePathOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting placePathOrder")
+
+	orderID := arguments.Str("OrderID")
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+
+	err := assertNotWaitlisted(stub, orderID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	path, err := computePathForArguments(stub, arguments)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	placement := "path-" + path.Metric
+
+	order := WaitlistEntry{
+		OrderID:    orderID,
+		OperatorID: arguments.Str("OperatorID"),
+		Bandwidth:  path.Bandwidth,
+		ExpiresOn:  arguments.Str("ExpiresOn"),
+		Placement:  placement,
+		Profile:    newBandwidthProfile(arguments),
+		SLA:        newServiceLevel(arguments),
+	}
+	if len(path.Hops) == 0 {
+		err = rejectServiceLevel(stub, events, stub.GetChannelID(), order, path.slaCircuit,
+			fmt.Sprintf("No path from %s to %s in %s meets the SLA, %s: %s", path.ASiteID, path.ZSiteID, path.CircuitNetwork, path.slaCircuit.CircuitID, path.SLAShortfall))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		fmt.Println("- end placePathOrder")
+		return events.Emit(stub)
+	}
+
+	legs := make([]OrderLeg, len(path.Hops))
+	for i, hop := range path.Hops {
+		legs[i] = OrderLeg{DataCircuitID: hop.CircuitID, Bandwidth: path.Bandwidth, FromSiteID: hop.FromSiteID, ToSiteID: hop.ToSiteID}
+	}
+	fmt.Printf("placing order %s from %s to %s by %s, fits: %t, hops: %d\n", orderID, path.ASiteID, path.ZSiteID, path.Metric, path.Fits, len(legs))
+
+	selection := CircuitSelection{
+		OrderID:              orderID,
+		CircuitNetwork:       path.CircuitNetwork,
+		Strategy:             placement,
+		DataCircuitID:        legs[0].DataCircuitID,
+		Candidates:           path.Candidates,
+		Fits:                 path.Fits,
+		UnallocatedBandwidth: bottleneckBandwidth(path.Hops),
+		Legs:                 legs,
+	}
+	err = events.Add(nsc.EventCircuitSelected, selection)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	order.CircuitID = legs[0].DataCircuitID
+	order.Legs = legs
+	if path.SLAShortfall != "" {
+		err = rejectServiceLevel(stub, events, stub.GetChannelID(), order, DataCircuit{CircuitID: order.CircuitID, UnallocatedBandwidth: path.Hops[0].UnallocatedBandwidth},
+			fmt.Sprintf("The path from %s to %s in %s does not meet the SLA: %s", path.ASiteID, path.ZSiteID, path.CircuitNetwork, path.SLAShortfall))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		fmt.Println("- end placePathOrder")
+		return events.Emit(stub)
+	}
+
+	if path.Fits {
+		err = fulfilOrder(stub, events, stub.GetChannelID(), order)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		fmt.Println("- end placePathOrder")
+		return events.Emit(stub)
+	}
+
+	var bottleneck PathHop
+	for _, hop := range path.Hops {
+		if hop.CircuitID == path.Bottleneck {
+			bottleneck = hop
+		}
+	}
+	rejection := OrderRejection{
+		OrderID:            orderID,
+		DataCircuitID:      bottleneck.CircuitID,
+		OperatorID:         arguments.Str("OperatorID"),
+		ReasonCode:         nsc.CodeInsufficientCapacity,
+		Reason:             fmt.Sprintf("No path from %s to %s in %s has room for %s", path.ASiteID, path.ZSiteID, path.CircuitNetwork, path.Bandwidth),
+		RequestedBandwidth: path.Bandwidth,
+		AvailableBandwidth: bottleneck.UnallocatedBandwidth,
+		HomeChannel:        stub.GetChannelID(),
+		Placement:          placement,
+	}
+	if bottleneck.UnallocatedBandwidth >= path.Bandwidth {
+		rejection.Reason = fmt.Sprintf("No path from %s to %s in %s has room for the excess rate %s", path.ASiteID, path.ZSiteID, path.CircuitNetwork, arguments.Bandwidth("ExcessBandwidth"))
+		rejection.RequestedExcessBandwidth = arguments.Bandwidth("ExcessBandwidth")
+		rejection.AvailableExcessBandwidth = bottleneck.AvailableExcessBandwidth
+	}
+	err = rejectOrder(stub, events, rejection)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end placePathOrder")
+	return events.Emit(stub)
+}
+
+// computePathForArguments routes the OrderBandwidth and ExcessBandwidth arguments from ASiteID to ZSiteID. It fails
+// with NOT_FOUND when no route joins the sites at all, a route without room is returned with Fits false.
+func computePathForArguments(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) (ComputedPath, error) {
+	path := ComputedPath{
+		CircuitNetwork: arguments.Str("CircuitNetwork"),
+		ASiteID:        arguments.Str("ASiteID"),
+		ZSiteID:        arguments.Str("ZSiteID"),
+		Bandwidth:      arguments.Bandwidth("OrderBandwidth"),
+		Metric:         arguments.Str("Metric"),
+	}
+	if path.Metric == "" {
+		path.Metric = metricHops
+	}
+	if path.ASiteID == path.ZSiteID {
+		return path, nsc.NewError(nsc.CodeInvalidArgument, "the A-end and Z-end of a path must be different sites").
+			WithDetail("SiteID", path.ASiteID)
+	}
+
+	candidates, err := listPlacementCandidates(stub, path.CircuitNetwork, "")
+	if err != nil {
+		return path, err
+	}
+	links, err := listCircuitLinks(stub, path.CircuitNetwork)
+	if err != nil {
+		return path, err
+	}
+
+	for _, link := range links {
+		for _, candidate := range candidates {
+			if candidate.CircuitID == link.CircuitID {
+				path.Candidates++
+			}
+		}
+	}
+
+	anyCircuit := func(DataCircuit) bool {
+		return true
+	}
+	sla := newServiceLevel(arguments)
+	qualifying := meetingServiceLevel(candidates, sla)
+	excess := arguments.Bandwidth("ExcessBandwidth")
+	path.Hops, path.Fits = findPath(qualifying, links, path.Metric, path.ASiteID, path.ZSiteID, func(dataCircuit DataCircuit) bool {
+		return fitsCircuit(dataCircuit, path.Bandwidth, excess)
+	})
+	if path.Fits {
+		path.SLAShortfall = sla.routeShortfall(path.Hops)
+	} else {
+		var connected bool
+		path.Hops, connected = findPath(qualifying, links, path.Metric, path.ASiteID, path.ZSiteID, anyCircuit)
+		if !connected && sla != nil {
+			// the sites are joined, only not by circuits meeting the SLA: name the first short one on the route
+			route, _ := findPath(candidates, links, path.Metric, path.ASiteID, path.ZSiteID, anyCircuit)
+			for _, hop := range route {
+				hopCircuit := DataCircuit{CircuitID: hop.CircuitID, UnallocatedBandwidth: hop.UnallocatedBandwidth, Performance: hop.Performance}
+				if shortfall := sla.circuitShortfall(hopCircuit); shortfall != "" {
+					path.SLAShortfall = shortfall
+					path.slaCircuit = hopCircuit
+					return path, nil
+				}
+			}
+		}
+		if !connected {
+			return path, nsc.NewError(nsc.CodeNotFound, "No circuits of %s that can be allocated on channel %s join %s to %s by %s", path.CircuitNetwork, stub.GetChannelID(), path.ASiteID, path.ZSiteID, path.Metric).
+				WithDetail("CircuitNetwork", path.CircuitNetwork).
+				WithDetail("ASiteID", path.ASiteID).
+				WithDetail("ZSiteID", path.ZSiteID)
+		}
+		for _, hop := range path.Hops {
+			if hop.UnallocatedBandwidth < path.Bandwidth || hop.AvailableExcessBandwidth < excess {
+				path.Bottleneck = hop.CircuitID
+				break
+			}
+		}
+	}
+
+	for _, hop := range path.Hops {
+		path.TotalMetric = path.TotalMetric + hop.Metric
+	}
+	return path, nil
+}
+
+// findPath searches the route with the lowest metric from aSiteID to zSiteID over the linked candidates usable accepts.
+// It is Dijkstra's search on labels that order routes by metric, then hops, then CircuitIDs hop by hop. Extending two
+// routes by the same hop keeps their order, so the first label settled at zSiteID is the best route.
+func findPath(candidates []DataCircuit, links []CircuitLink, metric string, aSiteID string, zSiteID string, usable func(DataCircuit) bool) ([]PathHop, bool) {
+	byCircuitID := map[string]DataCircuit{}
+	for _, candidate := range candidates {
+		byCircuitID[candidate.CircuitID] = candidate
+	}
+
+	edges := map[string][]pathEdge{}
+	for _, link := range links {
+		dataCircuit, found := byCircuitID[link.CircuitID]
+		if !found || !usable(dataCircuit) {
+			continue
+		}
+		value, valid := circuitMetric(dataCircuit, metric)
+		if !valid {
+			continue
+		}
+		edges[link.ASiteID] = append(edges[link.ASiteID], pathEdge{dataCircuit, link.ZSiteID, value})
+		edges[link.ZSiteID] = append(edges[link.ZSiteID], pathEdge{dataCircuit, link.ASiteID, value})
+	}
+
+	labels := map[string][]PathHop{aSiteID: {}}
+	settled := map[string]bool{}
+	for {
+		siteID, found := "", false
+		for labelled, hops := range labels {
+			if settled[labelled] {
+				continue
+			}
+			if !found || routePrefers(hops, labels[siteID]) || (!routePrefers(labels[siteID], hops) && labelled < siteID) {
+				siteID, found = labelled, true
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		if siteID == zSiteID {
+			return labels[siteID], true
+		}
+		settled[siteID] = true
+
+		for _, edge := range edges[siteID] {
+			if settled[edge.farSiteID] {
+				continue
+			}
+			extended := make([]PathHop, len(labels[siteID]), len(labels[siteID])+1)
+			copy(extended, labels[siteID])
+			extended = append(extended, PathHop{
+				CircuitID:                edge.dataCircuit.CircuitID,
+				FromSiteID:               siteID,
+				ToSiteID:                 edge.farSiteID,
+				UnallocatedBandwidth:     edge.dataCircuit.UnallocatedBandwidth,
+				AvailableExcessBandwidth: availableExcess(edge.dataCircuit),
+				Metric:                   edge.metric,
+				Performance:              edge.dataCircuit.Performance,
+			})
+			current, labelled := labels[edge.farSiteID]
+			if !labelled || routePrefers(extended, current) {
+				labels[edge.farSiteID] = extended
+			}
+		}
+	}
+}
+
+// routePrefers reports whether route a is strictly better than route b: a lower metric, then fewer hops, then the
+// lower CircuitID at the first hop they differ
+func routePrefers(a []PathHop, b []PathHop) bool {
+	aMetric, bMetric := int64(0), int64(0)
+	for _, hop := range a {
+		aMetric = aMetric + hop.Metric
+	}
+	for _, hop := range b {
+		bMetric = bMetric + hop.Metric
+	}
+	if aMetric != bMetric {
+		return aMetric < bMetric
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	for i := range a {
+		if a[i].CircuitID != b[i].CircuitID {
+			return a[i].CircuitID < b[i].CircuitID
+		}
+	}
+	return false
+}
+
+// circuitMetric is the cost of crossing a circuit, false when the circuit has no valid value for the metric
+func circuitMetric(dataCircuit DataCircuit, metric string) (int64, bool) {
+	switch metric {
+	case metricHops:
+		return 1, true
+	case metricLatency:
+		if dataCircuit.Performance == nil || dataCircuit.Performance.Latency == nil {
+			return 0, false
+		}
+		return int64(*dataCircuit.Performance.Latency), true
+	}
+	value, err := strconv.ParseInt(dataCircuit.Attributes[costAttribute], 10, 64)
+	if err != nil || value < 0 || value > maxCircuitCost {
+		return 0, false
+	}
+	return value, true
+}
+
+// bottleneckBandwidth is the least unallocated bandwidth of the hops of a route
+func bottleneckBandwidth(hops []PathHop) nsc.Bandwidth {
+	if len(hops) == 0 {
+		return 0
+	}
+	bottleneck := hops[0].UnallocatedBandwidth
+	for _, hop := range hops[1:] {
+		if hop.UnallocatedBandwidth < bottleneck {
+			bottleneck = hop.UnallocatedBandwidth
+		}
+	}
+	return bottleneck
+}
+
+// listCircuitLinks reads the circuits of the network NIMS links between two sites
+func listCircuitLinks(stub shim.ChaincodeStubInterface, network string) ([]CircuitLink, error) {
+	response := nsc.InvokeDependency(stub, nimsDependency, "listCircuitLinks", network)
+	if response.Status != shim.OK {
+		return nil, nsc.UpstreamError(nimsDependency, "listCircuitLinks", response)
+	}
+
+	var links []CircuitLink
+	err := json.Unmarshal(response.Payload, &links)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the circuit links of %s", network)
+	}
+	return links, nil
+}
+
+// hopCandidates narrows the candidates for re-homing an allocation of a path order to the circuits linking the same two
+// sites as its hop, a path cannot move onto a circuit elsewhere. Other allocations keep every candidate.
+func hopCandidates(stub shim.ChaincodeStubInterface, homeChannel string, order Order, allocation Allocation, candidates []DataCircuit) ([]DataCircuit, error) {
+	var hop *OrderLeg
+	for i := range order.Legs {
+		if order.Legs[i].DataCircuitID == allocation.CircuitID && order.Legs[i].FromSiteID != "" {
+			hop = &order.Legs[i]
+		}
+	}
+	if hop == nil {
+		return candidates, nil
+	}
+
+	response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "getCircuitsBetweenSites", hop.FromSiteID, hop.ToSiteID)
+	if response.Status != shim.OK {
+		return nil, nsc.UpstreamError(nimsDependency, "getCircuitsBetweenSites", response)
+	}
+	var parallel []DataCircuit
+	err := json.Unmarshal(response.Payload, &parallel)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the circuits between %s and %s", hop.FromSiteID, hop.ToSiteID)
+	}
+
+	parallelIDs := []string{}
+	for _, dataCircuit := range parallel {
+		parallelIDs = append(parallelIDs, dataCircuit.CircuitID)
+	}
+	narrowed := []DataCircuit{}
+	for _, candidate := range candidates {
+		if stringInSlice(candidate.CircuitID, parallelIDs) {
+			narrowed = append(narrowed, candidate)
+		}
+	}
+	return narrowed, nil
+}
@@ -0,0 +1,114 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// seedPaths links sites A and D directly over the expensive C1 and over B by the cheap C2 and C3
+func seedPaths(t *testing.T, s *simulator.Simulator) {
+	t.Helper()
+	for _, siteID := range []string{"A", "B", "D"} {
+		if err := s.AddSite(siteID, "site "+siteID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	circuits := []struct {
+		id        string
+		bandwidth nsc.Bandwidth
+		a, z      string
+		cost      string
+	}{
+		{"C1", 50 * simulator.Mbps, "A", "D", "100"},
+		{"C2", 100 * simulator.Mbps, "A", "B", "10"},
+		{"C3", 100 * simulator.Mbps, "B", "D", "10"},
+	}
+	for _, circuit := range circuits {
+		if err := s.SeedCircuit(circuit.id, "NET1", "Org1MSP", circuit.bandwidth); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.LinkCircuit(circuit.id, circuit.a, circuit.z); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetCircuitAttribute(circuit.id, "Cost", circuit.cost); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func computePath(t *testing.T, s *simulator.Simulator, bandwidth nsc.Bandwidth, metric string) bpm.ComputedPath {
+	t.Helper()
+	result := s.ComputePath("NET1", "A", "D", bandwidth, metric)
+	expectOK(t, result)
+	var path bpm.ComputedPath
+	if err := json.Unmarshal(result.Response.Payload, &path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func pathCircuits(path bpm.ComputedPath) []string {
+	circuits := []string{}
+	for _, hop := range path.Hops {
+		circuits = append(circuits, hop.CircuitID)
+	}
+	return circuits
+}
+
+func TestComputePathByMetricAndCapacity(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedPaths(t, s)
+
+	tests := []struct {
+		bandwidth nsc.Bandwidth
+		metric    string
+		circuits  string
+		total     int64
+	}{
+		{40 * simulator.Mbps, "hops", "[C1]", 1},
+		{40 * simulator.Mbps, "cost", "[C2 C3]", 20},
+		// every hop carries all of the order, C1 cannot
+		{80 * simulator.Mbps, "hops", "[C2 C3]", 2},
+	}
+	for _, test := range tests {
+		path := computePath(t, s, test.bandwidth, test.metric)
+		if !path.Fits || fmt.Sprint(pathCircuits(path)) != test.circuits || path.TotalMetric != test.total {
+			t.Errorf("%v by %s: expected %s with metric %d, got %+v", test.bandwidth, test.metric, test.circuits, test.total, path)
+		}
+	}
+}
+
+func TestPathOrderAllocatesEveryHop(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedPaths(t, s)
+
+	expectOK(t, s.PlacePathOrder(alice, "O1", "NET1", "A", "D", 80*simulator.Mbps, ""))
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Completed" || len(order.Legs) != 2 || order.Legs[0].DataCircuitID != "C2" || order.Legs[1].FromSiteID != "B" {
+		t.Fatalf("expected O1 Completed over C2 and C3 from B, got %+v", order)
+	}
+	for _, circuitID := range []string{"C2", "C3"} {
+		if err = s.ExpectBandwidth(circuitID, 80*simulator.Mbps, 20*simulator.Mbps); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// no route has room for 60M any more, the hop route is reported with C1 as its bottleneck
+	path := computePath(t, s, 60*simulator.Mbps, "hops")
+	if path.Fits || path.Bottleneck != "C1" {
+		t.Errorf("expected no route with C1 as the bottleneck, got %+v", path)
+	}
+	expectOK(t, s.PlacePathOrder(alice, "O2", "NET1", "A", "D", 60*simulator.Mbps, ""))
+	expectOrderStatus(t, s, "O2", "Rejected")
+	if err = s.ExpectBandwidth("C1", 0, 50*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,431 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Circuit Placement - placeOrder takes a network, optionally a provider, and a bandwidth instead of a DataCircuitID and
+// chooses the circuit from the NIMS inventory index. Candidates are the circuits that are not Down, not on faulty
+// equipment, not in an Outage maintenance window and whose home channel is this channel, they are compared by the
+// placement strategy and ties always go to the lowest CircuitID, so every endorser picks the same one.
+// The channel default is set by an admin with setPlacementStrategy, an order can override it.
+// ============================================================================================================================
+
+const placementStrategyObjectType = "PlacementStrategy"
+
+// placement strategies
+const (
+	placementBestFit       = "best-fit"
+	placementFirstFit      = "first-fit"
+	placementWorstFit      = "worst-fit"
+	placementLeastUtilized = "least-utilized"
+)
+
+var placementStrategies = []string{placementBestFit, placementFirstFit, placementWorstFit, placementLeastUtilized}
+
+const defaultPlacementStrategy = placementBestFit
+
+var placeOrderArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ProviderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Strategy", Type: nsc.ArgString, Enum: placementStrategies},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "Waitlist", Type: nsc.ArgBoolean},
+	{Name: "Priority", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(nsc.MaxPriority)},
+	{Name: "AllowSplit", Type: nsc.ArgBoolean},
+	{Name: "Protected", Type: nsc.ArgBoolean},
+	{Name: "DiverseOn", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength, Pattern: diverseOnPattern},
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "BurstSize", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxBurstSize)},
+	{Name: "ClassOfService", Type: nsc.ArgString, Enum: classesOfService},
+	// the SLA, see sla.go: latency and jitter in microseconds, loss and availability in parts per million
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+var setPlacementStrategyArguments = nsc.ArgumentSchema{
+	{Name: "Strategy", Type: nsc.ArgString, Required: true, Enum: placementStrategies},
+}
+
+type PlacementStrategy struct {
+	Strategy  string `json:"Strategy"`
+	UpdatedBy string `json:"UpdatedBy,omitempty"`
+	UpdatedOn string `json:"UpdatedOn,omitempty"`
+}
+
+// CircuitSelection is the data of CircuitSelected events
+type CircuitSelection struct {
+	OrderID              string        `json:"OrderID"`
+	CircuitNetwork       string        `json:"CircuitNetwork"`
+	ProviderID           string        `json:"ProviderID,omitempty"`
+	Strategy             string        `json:"Strategy"`
+	DataCircuitID        string        `json:"DataCircuitID"`
+	Candidates           int           `json:"Candidates"`
+	Fits                 bool          `json:"Fits"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	// Legs is set when the order was split across circuits or routed over a path
+	Legs []OrderLeg `json:"Legs,omitempty"`
+	// BackupCircuitID is set for a protected order, DataCircuitID is then its primary
+	BackupCircuitID string `json:"BackupCircuitID,omitempty"`
+}
+
+// placeOrder chooses a circuit for the order and processes it on that circuit as checkOnNIMSAndRespond does. When no
+// candidate fits and AllowSplit is set, the order is split across candidates. Otherwise it is waitlisted on or rejected
+// against the candidate with the most unallocated bandwidth. Candidates that do not meet the SLA of the order are left
+// out.
+func placeOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting placeOrder")
+
+	orderID := arguments.Str("OrderID")
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+
+	err := assertNotWaitlisted(stub, orderID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	strategy := arguments.Str("Strategy")
+	if strategy == "" {
+		strategy, err = getPlacementStrategyValue(stub)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	candidates, err := listPlacementCandidates(stub, arguments.Str("CircuitNetwork"), arguments.Str("ProviderID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if len(candidates) == 0 {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "No DataCircuit of network %s can be written on channel %s", arguments.Str("CircuitNetwork"), stub.GetChannelID()).
+			WithDetail("CircuitNetwork", arguments.Str("CircuitNetwork")).
+			WithDetail("ProviderID", arguments.Str("ProviderID")))
+	}
+
+	// only circuits meeting the SLA are placed on, an order none of them meets is rejected against the closest one
+	sla := newServiceLevel(arguments)
+	qualifying := meetingServiceLevel(candidates, sla)
+	if len(qualifying) == 0 {
+		closest, _ := selectDataCircuit(candidates, arguments.Bandwidth("OrderBandwidth"), arguments.Bandwidth("ExcessBandwidth"), strategy)
+		err = rejectServiceLevel(stub, events, stub.GetChannelID(), WaitlistEntry{
+			OrderID:    orderID,
+			OperatorID: arguments.Str("OperatorID"),
+			Bandwidth:  arguments.Bandwidth("OrderBandwidth"),
+			Placement:  strategy,
+		}, closest, fmt.Sprintf("No DataCircuit of %s meets the SLA, %s: %s", arguments.Str("CircuitNetwork"), closest.CircuitID, sla.circuitShortfall(closest)))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		fmt.Println("- end placeOrder")
+		return events.Emit(stub)
+	}
+	candidates = qualifying
+
+	if arguments.Boolean("Protected") {
+		err = placeProtectedOrder(stub, events, arguments, candidates, strategy)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		fmt.Println("- end placeOrder")
+		return events.Emit(stub)
+	}
+
+	if arguments.Has("ExcessBandwidth") && arguments.Boolean("AllowSplit") {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "an order with an excess rate cannot be split").
+			WithDetail("OrderID", orderID))
+	}
+
+	orderBandwidth := arguments.Bandwidth("OrderBandwidth")
+	chosen, fits := selectDataCircuit(candidates, orderBandwidth, arguments.Bandwidth("ExcessBandwidth"), strategy)
+	availableBandwidth := chosen.UnallocatedBandwidth
+
+	var legs []OrderLeg
+	if !fits && arguments.Boolean("AllowSplit") {
+		legs, fits = splitAcrossCircuits(candidates, orderBandwidth)
+		if fits {
+			chosen = DataCircuit{CircuitID: legs[0].DataCircuitID}
+			availableBandwidth = orderBandwidth
+		}
+	}
+	fmt.Printf("placing order %s on %s with %s, fits: %t, legs: %d\n", orderID, chosen.CircuitID, strategy, fits, len(legs))
+
+	err = events.Add(nsc.EventCircuitSelected, CircuitSelection{
+		OrderID:              orderID,
+		CircuitNetwork:       arguments.Str("CircuitNetwork"),
+		ProviderID:           arguments.Str("ProviderID"),
+		Strategy:             strategy,
+		DataCircuitID:        chosen.CircuitID,
+		Candidates:           len(candidates),
+		Fits:                 fits,
+		UnallocatedBandwidth: availableBandwidth,
+		Legs:                 legs,
+	})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	order := WaitlistEntry{
+		CircuitID:  chosen.CircuitID,
+		OrderID:    orderID,
+		OperatorID: arguments.Str("OperatorID"),
+		Bandwidth:  orderBandwidth,
+		Priority:   arguments.Integer("Priority"),
+		ExpiresOn:  arguments.Str("ExpiresOn"),
+		Placement:  strategy,
+		Legs:       legs,
+		Profile:    newBandwidthProfile(arguments),
+		SLA:        sla,
+	}
+	if arguments.Boolean("AllowSplit") {
+		// a split order that goes to the waitlist is split again over the network once capacity is freed
+		order.AllowSplit = true
+		order.CircuitNetwork = arguments.Str("CircuitNetwork")
+		order.ProviderID = arguments.Str("ProviderID")
+	}
+	err = processOrder(stub, events, stub.GetChannelID(), availableBandwidth, availableExcess(chosen), order, arguments.Boolean("Waitlist"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end placeOrder")
+	return events.Emit(stub)
+}
+
+func setPlacementStrategy(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setPlacementStrategy")
+
+	updatedBy, err := nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	updatedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	strategyKey, err := stub.CreateCompositeKey(placementStrategyObjectType, []string{})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(PlacementStrategy{arguments.Str("Strategy"), updatedBy, updatedOn})
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert PlacementStrategy to json"))
+	}
+
+	err = stub.PutState(strategyKey, buff)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setPlacementStrategy")
+	return shim.Success(buff)
+}
+
+func getPlacementStrategy(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	strategy, err := getPlacementStrategyValue(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(PlacementStrategy{Strategy: strategy})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
+
+// getPlacementStrategyValue returns the channel default, best-fit until an admin sets one
+func getPlacementStrategyValue(stub shim.ChaincodeStubInterface) (string, error) {
+	strategyKey, err := stub.CreateCompositeKey(placementStrategyObjectType, []string{})
+	if err != nil {
+		return "", err
+	}
+
+	strategyAsBytes, err := stub.GetState(strategyKey)
+	if err != nil {
+		return "", err
+	}
+	if strategyAsBytes == nil {
+		return defaultPlacementStrategy, nil
+	}
+
+	var strategy PlacementStrategy
+	err = json.Unmarshal(strategyAsBytes, &strategy)
+	if err != nil {
+		return "", nsc.NewError(nsc.CodeInternal, "unable to read PlacementStrategy: %s", err.Error())
+	}
+	return strategy.Strategy, nil
+}
+
+// MaintenanceWindow is the part of a NIMS maintenance window placement reads
+type MaintenanceWindow struct {
+	CircuitID string `json:"CircuitID"`
+	WindowID  string `json:"WindowID"`
+	StartsOn  string `json:"StartsOn"`
+	EndsOn    string `json:"EndsOn"`
+	Type      string `json:"Type"`
+}
+
+// listPlacementCandidates lists the circuits of the network that can be allocated in this transaction, by CircuitID.
+// Circuits that are Down, on faulty equipment or in an Outage maintenance window are left out.
+func listPlacementCandidates(stub shim.ChaincodeStubInterface, network string, providerID string) ([]DataCircuit, error) {
+	response := nsc.InvokeDependency(stub, nimsDependency, "listDataCircuits", network, providerID)
+	if response.Status != shim.OK {
+		return nil, nsc.UpstreamError(nimsDependency, "listDataCircuits", response)
+	}
+
+	var dataCircuits []DataCircuit
+	err := json.Unmarshal(response.Payload, &dataCircuits)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the DataCircuits of %s", network)
+	}
+
+	outages, err := listOpenOutages(stub, network, providerID)
+	if err != nil {
+		return nil, err
+	}
+	homeChannels, defaultChannel, err := listCircuitHomeChannels(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []DataCircuit{}
+	for _, dataCircuit := range dataCircuits {
+		if dataCircuit.Status == circuitDown || len(dataCircuit.FaultyEquipment) > 0 || outages[dataCircuit.CircuitID] {
+			continue
+		}
+		homeChannel, mapped := homeChannels[dataCircuit.CircuitID]
+		if !mapped {
+			homeChannel = defaultChannel
+		}
+		if homeChannel != "" && homeChannel != stub.GetChannelID() {
+			continue
+		}
+		candidates = append(candidates, dataCircuit)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].CircuitID < candidates[j].CircuitID
+	})
+	return candidates, nil
+}
+
+// listOpenOutages returns the circuits of the network in an open Outage window, NIMS refuses allocations inside one
+func listOpenOutages(stub shim.ChaincodeStubInterface, network string, providerID string) (map[string]bool, error) {
+	response := nsc.InvokeDependency(stub, nimsDependency, "listOpenOutages", network, providerID)
+	if response.Status != shim.OK {
+		return nil, nsc.UpstreamError(nimsDependency, "listOpenOutages", response)
+	}
+
+	var windows []MaintenanceWindow
+	err := json.Unmarshal(response.Payload, &windows)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeUpstreamFailure, "Error in unmarshelling the open outages of %s", network)
+	}
+
+	outages := map[string]bool{}
+	for _, window := range windows {
+		outages[window.CircuitID] = true
+	}
+	return outages, nil
+}
+
+// selectDataCircuit applies the strategy to the candidates, which must be sorted by CircuitID. Without a fitting
+// candidate it returns the one with the most unallocated bandwidth and false.
+func selectDataCircuit(candidates []DataCircuit, bandwidth nsc.Bandwidth, excess nsc.Bandwidth, strategy string) (DataCircuit, bool) {
+	ranked := rankCandidates(candidates, bandwidth, excess, strategy)
+	if len(ranked) > 0 {
+		return ranked[0], true
+	}
+
+	closest := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if candidate.UnallocatedBandwidth > closest.UnallocatedBandwidth {
+			closest = candidate
+		}
+	}
+	return closest, false
+}
+
+// rankCandidates returns the candidates the bandwidth and excess rate fit on, best first by the strategy. The sort is
+// stable on the CircuitID order of the candidates, first-fit keeps that order.
+func rankCandidates(candidates []DataCircuit, bandwidth nsc.Bandwidth, excess nsc.Bandwidth, strategy string) []DataCircuit {
+	ranked := []DataCircuit{}
+	for _, candidate := range candidates {
+		if fitsCircuit(candidate, bandwidth, excess) {
+			ranked = append(ranked, candidate)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return placementPrefers(strategy, ranked[i], ranked[j])
+	})
+	return ranked
+}
+
+// placementPrefers reports whether a is strictly better than b, equal candidates keep the earlier CircuitID
+func placementPrefers(strategy string, a DataCircuit, b DataCircuit) bool {
+	switch strategy {
+	case placementBestFit:
+		return a.UnallocatedBandwidth < b.UnallocatedBandwidth
+	case placementWorstFit:
+		return a.UnallocatedBandwidth > b.UnallocatedBandwidth
+	case placementLeastUtilized:
+		// compares the sold utilization AllocatedBandwidth/sellable bandwidth without floating point, the products of two
+		// bandwidths in bits per second can overflow int64
+		aUsage := new(big.Int).Mul(big.NewInt(int64(a.AllocatedBandwidth)), big.NewInt(int64(sellableBandwidth(b))))
+		bUsage := new(big.Int).Mul(big.NewInt(int64(b.AllocatedBandwidth)), big.NewInt(int64(sellableBandwidth(a))))
+		return aUsage.Cmp(bUsage) < 0
+	}
+	return false
+}
+
+// sellableBandwidth is the bandwidth NIMS sells on a circuit, its TotalBandwidth when it is not oversubscribed
+func sellableBandwidth(dataCircuit DataCircuit) nsc.Bandwidth {
+	if dataCircuit.SellableBandwidth == 0 {
+		return dataCircuit.TotalBandwidth
+	}
+	return dataCircuit.SellableBandwidth
+}
+
+// splitAcrossCircuits spreads the bandwidth over the candidates with the most unallocated bandwidth first, ties by
+// CircuitID, which keeps the number of legs as low as possible. It returns false when the candidates cannot hold it.
+func splitAcrossCircuits(candidates []DataCircuit, bandwidth nsc.Bandwidth) ([]OrderLeg, bool) {
+	ordered := make([]DataCircuit, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].UnallocatedBandwidth > ordered[j].UnallocatedBandwidth
+	})
+
+	var legs []OrderLeg
+	remaining := bandwidth
+	for _, candidate := range ordered {
+		if remaining == 0 || candidate.UnallocatedBandwidth == 0 {
+			break
+		}
+		legBandwidth := candidate.UnallocatedBandwidth
+		if legBandwidth > remaining {
+			legBandwidth = remaining
+		}
+		legs = append(legs, OrderLeg{DataCircuitID: candidate.CircuitID, Bandwidth: legBandwidth})
+		remaining = remaining - legBandwidth
+	}
+	if remaining > 0 {
+		return nil, false
+	}
+	return legs, true
+}
@@ -0,0 +1,132 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// seedNetwork seeds circuits of NET1 provided by Org1MSP
+func seedNetwork(t *testing.T, s *simulator.Simulator, circuits map[string]nsc.Bandwidth) {
+	t.Helper()
+	for circuitID, bandwidth := range circuits {
+		if err := s.SeedCircuit(circuitID, "NET1", "Org1MSP", bandwidth); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// selectedCircuit returns the circuit of the CircuitSelected record of a placement
+func selectedCircuit(t *testing.T, result simulator.Result) bpm.CircuitSelection {
+	t.Helper()
+	expectOK(t, result)
+	records := result.Records(nsc.EventCircuitSelected)
+	if len(records) != 1 {
+		t.Fatalf("expected one %s record, got %d", nsc.EventCircuitSelected, len(records))
+	}
+	var selection bpm.CircuitSelection
+	err := json.Unmarshal(records[0].Data, &selection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return selection
+}
+
+func TestPlaceOrderSkipsCircuitsInOutage(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 150 * simulator.Mbps, "C2": 100 * simulator.Mbps})
+
+	// the simulator's transactions are timestamped 20231114221320, the Outage on C1 is open then, the one on C2 is not
+	expectOK(t, s.ScheduleMaintenance("C1", "W1", "20231101000000", "20231201000000", "Outage"))
+	expectOK(t, s.ScheduleMaintenance("C2", "W2", "20231201000000", "20231202000000", "Outage"))
+
+	selection := selectedCircuit(t, s.PlaceOrder(alice, "O1", "NET1", "", 50*simulator.Mbps, "worst-fit"))
+	if selection.DataCircuitID != "C2" || selection.Candidates != 1 {
+		t.Errorf("expected O1 on C2 out of 1 candidate, got %s out of %d", selection.DataCircuitID, selection.Candidates)
+	}
+
+	// an AtRisk window leaves the circuit a candidate
+	expectOK(t, s.ScheduleMaintenance("C2", "W3", "20231101000000", "20231115000000", "AtRisk"))
+	selection = selectedCircuit(t, s.PlaceOrder(alice, "O2", "NET1", "", 10*simulator.Mbps, "worst-fit"))
+	if selection.DataCircuitID != "C2" {
+		t.Errorf("expected O2 on C2, got %s", selection.DataCircuitID)
+	}
+
+	expectOK(t, s.Invoke(simulator.NIMS, provider, "cancelMaintenanceWindow", "C2", "W3"))
+	expectOK(t, s.ScheduleMaintenance("C2", "W4", "20231114000000", "20231115000000", "Outage"))
+	// with every circuit in an Outage there is nothing to place on
+	expectCode(t, s.PlaceOrder(alice, "O3", "NET1", "", 10*simulator.Mbps, ""), nsc.CodeNotFound)
+}
+
+func TestPlaceOrderRequiresAnOMSTransaction(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps})
+
+	expectCode(t, s.Invoke(simulator.BPM, alice, "placeOrder", "NET1", "", "50M", "O1", "alice"), nsc.CodeForbidden)
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	selection := selectedCircuit(t, s.PlaceOrder(alice, "O1", "NET1", "", 50*simulator.Mbps, ""))
+	if selection.DataCircuitID != "C1" {
+		t.Errorf("expected O1 on C1, got %s", selection.DataCircuitID)
+	}
+}
+
+// seedUsedNetwork seeds NET1 so that every strategy chooses a different circuit for a 70M order:
+// C1 is the first that fits, C2 has the most left, C3 the least left that fits and C4 the lowest utilization
+func seedUsedNetwork(t *testing.T, s *simulator.Simulator) {
+	t.Helper()
+	seedNetwork(t, s, map[string]nsc.Bandwidth{
+		"C1": 100 * simulator.Mbps,
+		"C2": 1 * simulator.Gbps,
+		"C3": 80 * simulator.Mbps,
+		"C4": 200 * simulator.Mbps,
+	})
+	expectOK(t, s.SubmitOrder(alice, "U1", "C1", 10*simulator.Mbps))
+	expectOK(t, s.SubmitOrder(alice, "U2", "C2", 500*simulator.Mbps))
+	expectOK(t, s.SubmitOrder(alice, "U3", "C3", 4*simulator.Mbps))
+}
+
+func TestPlaceOrderStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		circuit  string
+	}{
+		{"first-fit", "C1"},
+		{"worst-fit", "C2"},
+		{"best-fit", "C3"},
+		{"least-utilized", "C4"},
+		// the channel default until an admin sets one
+		{"", "C3"},
+	}
+	for _, test := range tests {
+		s := simulator.NewForTest(t)
+		seedUsedNetwork(t, s)
+		selection := selectedCircuit(t, s.PlaceOrder(alice, "O1", "NET1", "", 70*simulator.Mbps, test.strategy))
+		if selection.DataCircuitID != test.circuit || selection.Candidates != 4 || !selection.Fits {
+			t.Errorf("%q: expected O1 to fit on %s out of 4 candidates, got %+v", test.strategy, test.circuit, selection)
+		}
+	}
+}
+
+func TestPlaceOrderUsesChannelStrategy(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedUsedNetwork(t, s)
+
+	expectCode(t, s.Invoke(simulator.BPM, alice, "setPlacementStrategy", "worst-fit"), nsc.CodeForbidden)
+	expectOK(t, s.Invoke(simulator.BPM, simulator.AdminIdentity, "setPlacementStrategy", "worst-fit"))
+
+	selection := selectedCircuit(t, s.PlaceOrder(alice, "O1", "NET1", "", 70*simulator.Mbps, ""))
+	if selection.DataCircuitID != "C2" || selection.Strategy != "worst-fit" {
+		t.Errorf("expected O1 on C2 by worst-fit, got %+v", selection)
+	}
+	// an order still overrides the channel default
+	selection = selectedCircuit(t, s.PlaceOrder(alice, "O2", "NET1", "", 70*simulator.Mbps, "first-fit"))
+	if selection.DataCircuitID != "C1" {
+		t.Errorf("expected O2 on C1 by first-fit, got %s", selection.DataCircuitID)
+	}
+}
@@ -0,0 +1,92 @@
+package bpm
+
+import (
+	"encoding/json"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// Bandwidth Profiles - an order's OrderBandwidth is its committed rate (CIR). It can add an excess rate (EIR) it may burst
+// to, a burst size and a class of service. Orders are admitted on the committed rate, which fills the circuit, and on
+// the excess rate, which must fit under the circuit's ExcessLimit on its own. NIMS holds both on the allocation and ANCS
+// passes the whole profile to the device agent in the configuration job, which programs the policer with it.
+// ============================================================================================================================
+
+// classes of service, in increasing priority on the device
+const (
+	classBestEffort = "best-effort"
+	classAssured    = "assured"
+	classExpedited  = "expedited"
+)
+
+var classesOfService = []string{classBestEffort, classAssured, classExpedited}
+
+// burst sizes are in kilobytes
+const maxBurstSize = 1000000
+
+type BandwidthProfile struct {
+	CommittedBandwidth nsc.Bandwidth `json:"CommittedBandwidth"`
+	ExcessBandwidth    nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	BurstSize          int           `json:"BurstSize,omitempty"`
+	ClassOfService     string        `json:"ClassOfService"`
+}
+
+// newBandwidthProfile reads the profile arguments of an order, nil when it names none of them
+func newBandwidthProfile(arguments nsc.FunctionArgs) *BandwidthProfile {
+	if !arguments.Has("ExcessBandwidth") && !arguments.Has("BurstSize") && !arguments.Has("ClassOfService") {
+		return nil
+	}
+	profile := BandwidthProfile{
+		CommittedBandwidth: arguments.Bandwidth("OrderBandwidth"),
+		ExcessBandwidth:    arguments.Bandwidth("ExcessBandwidth"),
+		BurstSize:          arguments.Integer("BurstSize"),
+		ClassOfService:     arguments.Str("ClassOfService"),
+	}
+	if profile.ClassOfService == "" {
+		profile.ClassOfService = classBestEffort
+	}
+	return &profile
+}
+
+// excessBandwidth is the excess rate of an order, 0 without a profile
+func (entry WaitlistEntry) excessBandwidth() nsc.Bandwidth {
+	if entry.Profile == nil {
+		return 0
+	}
+	return entry.Profile.ExcessBandwidth
+}
+
+// availableExcess is the excess rate left on a circuit, mirroring excessLimit in NIMS
+func availableExcess(dataCircuit DataCircuit) nsc.Bandwidth {
+	limit := dataCircuit.ExcessLimit
+	if limit == 0 {
+		limit = dataCircuit.TotalBandwidth
+	}
+	return limit - dataCircuit.ExcessBandwidth
+}
+
+// fitsCircuit reports whether a committed and an excess rate both fit on a circuit
+func fitsCircuit(dataCircuit DataCircuit, bandwidth nsc.Bandwidth, excess nsc.Bandwidth) bool {
+	return dataCircuit.UnallocatedBandwidth >= bandwidth && availableExcess(dataCircuit) >= excess
+}
+
+// formatExcess returns an excess rate as a chaincode argument, empty for none
+func formatExcess(excess nsc.Bandwidth) string {
+	if excess == 0 {
+		return ""
+	}
+	return excess.Argument()
+}
+
+// profileArgument returns the profile of an order as the JSON argument of ANCS completeOrder, empty without one
+func profileArgument(entry WaitlistEntry) (string, error) {
+	if entry.Profile == nil {
+		return "", nil
+	}
+	profileAsBytes, err := json.Marshal(entry.Profile)
+	if err != nil {
+		return "", nsc.NewError(nsc.CodeInternal, "unable to convert the bandwidth profile of order %s to json", entry.OrderID)
+	}
+	return string(profileAsBytes), nil
+}
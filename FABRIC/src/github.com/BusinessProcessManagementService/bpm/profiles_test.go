@@ -0,0 +1,61 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestExcessRateIsAdmittedUnderTheExcessLimit(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps})
+
+	expectOK(t, s.SubmitProfiledOrder(alice, "O1", "C1", 60*simulator.Mbps, 80*simulator.Mbps, 64, "expedited"))
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Profile == nil || order.Profile.ExcessBandwidth != 80*simulator.Mbps || order.Profile.BurstSize != 64 || order.Profile.ClassOfService != "expedited" {
+		t.Fatalf("expected O1 with its profile, got %+v", order)
+	}
+	job, err := s.ConfigurationJob("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Profile == nil || job.Profile.CommittedBandwidth != 60*simulator.Mbps || job.Profile.ExcessBandwidth != 80*simulator.Mbps {
+		t.Errorf("expected the configuration job to carry the profile of O1, got %+v", job.Profile)
+	}
+
+	// 40M of committed rate is left, but only 20M of excess rate
+	expectOK(t, s.SubmitProfiledOrder(alice, "O2", "C1", 30*simulator.Mbps, 30*simulator.Mbps, 0, ""))
+	expectOrderStatus(t, s, "O2", "Rejected")
+	result := s.Query(simulator.BPM, alice, "getOrderRejection", "O2")
+	expectOK(t, result)
+	var rejection bpm.OrderRejection
+	if err = json.Unmarshal(result.Response.Payload, &rejection); err != nil {
+		t.Fatal(err)
+	}
+	if rejection.RequestedExcessBandwidth != 30*simulator.Mbps || rejection.AvailableExcessBandwidth != 20*simulator.Mbps {
+		t.Errorf("expected 30M of excess rate requested against 20M, got %+v", rejection)
+	}
+
+	// a profile without a class of service is best-effort
+	expectOK(t, s.SubmitProfiledOrder(alice, "O3", "C1", 30*simulator.Mbps, 20*simulator.Mbps, 0, ""))
+	order, err = s.Order("O3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Completed" || order.Profile == nil || order.Profile.ClassOfService != "best-effort" {
+		t.Errorf("expected O3 Completed as best-effort, got %+v", order)
+	}
+	circuit, err := s.Circuit("C1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if circuit.AllocatedBandwidth != 90*simulator.Mbps || circuit.ExcessBandwidth != 100*simulator.Mbps {
+		t.Errorf("expected 90M committed and 100M excess on C1, got %+v", circuit)
+	}
+}
@@ -0,0 +1,132 @@
+package bpm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// ============================================================================================================================
+// Protected Orders - placeOrder with Protected set needs a primary and a backup circuit of different providers, and
+// diverse on every attribute named in DiverseOn. Primaries are tried in the order of the placement strategy, each with
+// the best ranked diverse backup, so the pair is the same on every endorser. NIMS allocates both and keeps the pair as a
+// protection group, ANCS records BackupCircuitID on the order and opens a configuration job for each circuit.
+// ============================================================================================================================
+
+// comma separated DataCircuit attribute names
+const diverseOnPattern = `^[A-Za-z0-9_]+(,[A-Za-z0-9_]+)*$`
+
+// placeProtectedOrder chooses a diverse pair for the order and fulfils it, or rejects the order when there is none
+func placeProtectedOrder(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, arguments nsc.FunctionArgs, candidates []DataCircuit, strategy string) error {
+	orderID := arguments.Str("OrderID")
+	if arguments.Has("ProviderID") || arguments.Boolean("AllowSplit") || arguments.Boolean("Waitlist") {
+		return nsc.NewError(nsc.CodeInvalidArgument, "a protected order cannot name a ProviderID, be split or be waitlisted").
+			WithDetail("OrderID", orderID)
+	}
+
+	var diverseOn []string
+	if arguments.Has("DiverseOn") {
+		diverseOn = strings.Split(arguments.Str("DiverseOn"), ",")
+	}
+
+	orderBandwidth := arguments.Bandwidth("OrderBandwidth")
+	ranked := rankCandidates(candidates, orderBandwidth, arguments.Bandwidth("ExcessBandwidth"), strategy)
+	primary, backup, found := selectProtectedPair(ranked, diverseOn)
+
+	selection := CircuitSelection{
+		OrderID:        orderID,
+		CircuitNetwork: arguments.Str("CircuitNetwork"),
+		Strategy:       strategy,
+		Candidates:     len(candidates),
+		Fits:           found,
+	}
+	if found {
+		selection.DataCircuitID = primary.CircuitID
+		selection.BackupCircuitID = backup.CircuitID
+		selection.UnallocatedBandwidth = primary.UnallocatedBandwidth
+	}
+	fmt.Printf("placing protected order %s on %s and %s with %s\n", orderID, primary.CircuitID, backup.CircuitID, strategy)
+
+	err := events.Add(nsc.EventCircuitSelected, selection)
+	if err != nil {
+		return err
+	}
+
+	if found {
+		return fulfilOrder(stub, events, stub.GetChannelID(), WaitlistEntry{
+			CircuitID:       primary.CircuitID,
+			OrderID:         orderID,
+			OperatorID:      arguments.Str("OperatorID"),
+			Bandwidth:       orderBandwidth,
+			ExpiresOn:       arguments.Str("ExpiresOn"),
+			Placement:       strategy,
+			BackupCircuitID: backup.CircuitID,
+			DiverseOn:       arguments.Str("DiverseOn"),
+			Profile:         newBandwidthProfile(arguments),
+			SLA:             newServiceLevel(arguments),
+		})
+	}
+
+	// circuits with room but no diverse pair among them are a diversity problem, otherwise capacity is missing
+	rejection := OrderRejection{
+		OrderID:            orderID,
+		OperatorID:         arguments.Str("OperatorID"),
+		ReasonCode:         nsc.CodeInsufficientCapacity,
+		Reason:             fmt.Sprintf("No two circuits of %s can hold the protected order", arguments.Str("CircuitNetwork")),
+		RequestedBandwidth: orderBandwidth,
+		HomeChannel:        stub.GetChannelID(),
+		Placement:          strategy,
+	}
+	closest, _ := selectDataCircuit(candidates, orderBandwidth, arguments.Bandwidth("ExcessBandwidth"), strategy)
+	rejection.DataCircuitID = closest.CircuitID
+	rejection.AvailableBandwidth = closest.UnallocatedBandwidth
+	if len(ranked) > 1 {
+		rejection.ReasonCode = nsc.CodeDiversityViolation
+		rejection.Reason = fmt.Sprintf("No two circuits of %s with room for the protected order are diverse", arguments.Str("CircuitNetwork"))
+	}
+	return rejectOrder(stub, events, rejection)
+}
+
+// selectProtectedPair returns the first primary in ranked order that has a diverse backup, with its best ranked backup
+func selectProtectedPair(ranked []DataCircuit, diverseOn []string) (DataCircuit, DataCircuit, bool) {
+	for _, primary := range ranked {
+		for _, backup := range ranked {
+			if isDiverse(primary, backup, diverseOn) {
+				return primary, backup, true
+			}
+		}
+	}
+	return DataCircuit{}, DataCircuit{}, false
+}
+
+// isDiverse mirrors the NIMS check, which has the final say when the pair is allocated
+func isDiverse(primary DataCircuit, backup DataCircuit, diverseOn []string) bool {
+	if primary.CircuitID == backup.CircuitID || primary.ProviderID == backup.ProviderID {
+		return false
+	}
+	for _, attribute := range diverseOn {
+		primaryValue, backupValue := primary.Attributes[attribute], backup.Attributes[attribute]
+		if primaryValue == "" || backupValue == "" || primaryValue == backupValue {
+			return false
+		}
+	}
+	return true
+}
+
+// allocateProtectedOrder holds the order's bandwidth on its primary and backup circuit as one NIMS protection group
+func allocateProtectedOrder(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, homeChannel string, order WaitlistEntry) error {
+	for _, circuitID := range []string{order.CircuitID, order.BackupCircuitID} {
+		err := assertWritableChannel(stub, circuitID, homeChannel)
+		if err != nil {
+			return err
+		}
+	}
+
+	response := nsc.InvokeDependencyOnChannel(stub, nimsDependency, homeChannel, "allocateProtectedBandwidth", order.OrderID, order.CircuitID, order.BackupCircuitID, order.Bandwidth.Argument(), order.DiverseOn, order.ExpiresOn, order.OperatorID, formatExcess(order.excessBandwidth()))
+	if response.Status != shim.OK {
+		return nsc.UpstreamError(nimsDependency, "allocateProtectedBandwidth", response).WithDetail("BackupCircuitID", order.BackupCircuitID)
+	}
+	return events.Merge(nimsDependency, response.Payload)
+}
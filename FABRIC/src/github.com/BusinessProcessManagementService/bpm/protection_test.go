@@ -0,0 +1,81 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// seedConduits seeds 100M circuits of NET1 with their provider and Conduit attribute
+func seedConduits(t *testing.T, s *simulator.Simulator, circuits map[string][2]string) {
+	t.Helper()
+	for circuitID, circuit := range circuits {
+		if err := s.SeedCircuit(circuitID, "NET1", circuit[0], 100*simulator.Mbps); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetCircuitAttribute(circuitID, "Conduit", circuit[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProtectedOrderUsesDiversePair(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedConduits(t, s, map[string][2]string{
+		"C1": {"Org1MSP", "A"},
+		"C2": {"Org1MSP", "B"},
+		"C3": {"Org2MSP", "A"},
+		"C4": {"Org2MSP", "B"},
+	})
+
+	// C2 shares the provider of C1 and C3 its conduit, C4 is the only backup diverse on both
+	selection := selectedCircuit(t, s.PlaceProtectedOrder(alice, "O1", "NET1", 50*simulator.Mbps, "Conduit"))
+	if selection.DataCircuitID != "C1" || selection.BackupCircuitID != "C4" {
+		t.Fatalf("expected O1 on C1 backed up by C4, got %+v", selection)
+	}
+
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Completed" || order.BackupCircuitID != "C4" {
+		t.Errorf("expected O1 Completed with backup C4, got %+v", order)
+	}
+	for _, circuitID := range []string{"C1", "C4"} {
+		if err = s.ExpectBandwidth(circuitID, 50*simulator.Mbps, 50*simulator.Mbps); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestProtectedOrderWithoutDiversePairIsRejected(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedConduits(t, s, map[string][2]string{
+		"C1": {"Org1MSP", "A"},
+		"C2": {"Org1MSP", "B"},
+	})
+
+	// a protected order is never queued
+	expectCode(t, s.Invoke(simulator.OMS, alice, "placeOrder", "O1", "alice", "NET1", "", "50M", "", "", "true", "", "", "true"),
+		nsc.CodeInvalidArgument)
+
+	// both circuits have room, but they share a provider
+	expectOK(t, s.PlaceProtectedOrder(alice, "O1", "NET1", 50*simulator.Mbps, ""))
+	expectOrderStatus(t, s, "O1", "Rejected")
+
+	result := s.Query(simulator.BPM, alice, "getOrderRejection", "O1")
+	expectOK(t, result)
+	var rejection bpm.OrderRejection
+	if err := json.Unmarshal(result.Response.Payload, &rejection); err != nil {
+		t.Fatal(err)
+	}
+	if rejection.ReasonCode != nsc.CodeDiversityViolation {
+		t.Errorf("expected %s, got %+v", nsc.CodeDiversityViolation, rejection)
+	}
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
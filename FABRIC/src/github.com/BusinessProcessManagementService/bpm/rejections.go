@@ -0,0 +1,101 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Order Rejections - an order that cannot be fulfilled commits a rejection record with its reason code and the amounts
+// that were compared, and moves to Rejected in the ANCS order store. Rejecting is a successful transaction, the reason
+// is in the OrderRejected event record and in getOrderRejection.
+// ============================================================================================================================
+
+const orderRejectionObjectType = "OrderRejection"
+
+var getOrderRejectionArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type OrderRejection struct {
+	OrderID            string        `json:"OrderID"`
+	DataCircuitID      string        `json:"DataCircuitID"`
+	OperatorID         string        `json:"OperatorID"`
+	ReasonCode         string        `json:"ReasonCode"`
+	Reason             string        `json:"Reason"`
+	RequestedBandwidth nsc.Bandwidth `json:"RequestedBandwidth"`
+	AvailableBandwidth nsc.Bandwidth `json:"AvailableBandwidth"`
+	HomeChannel        string        `json:"HomeChannel"`
+	RejectedOn         string        `json:"RejectedOn"`
+	Placement          string        `json:"Placement,omitempty"`
+	// set when the order was rejected on its excess rate
+	RequestedExcessBandwidth nsc.Bandwidth `json:"RequestedExcessBandwidth,omitempty"`
+	AvailableExcessBandwidth nsc.Bandwidth `json:"AvailableExcessBandwidth,omitempty"`
+}
+
+// rejectOrder records the rejection, moves the order to Rejected in ANCS and adds the OrderRejected record to events
+func rejectOrder(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, rejection OrderRejection) error {
+	fmt.Println("rejecting order " + rejection.OrderID + ": " + rejection.Reason)
+
+	rejectionKey, err := stub.CreateCompositeKey(orderRejectionObjectType, []string{rejection.OrderID})
+	if err != nil {
+		return err
+	}
+
+	existing, err := stub.GetState(rejectionKey)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nsc.NewError(nsc.CodeConflict, "Order %s was already rejected", rejection.OrderID).WithDetail("OrderID", rejection.OrderID)
+	}
+
+	rejection.RejectedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return err
+	}
+
+	buff, err := json.Marshal(rejection)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert OrderRejection to json")
+	}
+
+	err = stub.PutState(rejectionKey, buff)
+	if err != nil {
+		return err
+	}
+
+	response := nsc.InvokeDependency(stub, ancsDependency, "rejectOrder", rejection.OrderID, rejection.DataCircuitID,
+		rejection.RequestedBandwidth.Argument(), rejection.OperatorID, rejection.ReasonCode, rejection.Placement, rejection.Reason)
+	if response.Status != shim.OK {
+		return nsc.UpstreamError(ancsDependency, "rejectOrder", response)
+	}
+
+	err = events.Merge(ancsDependency, response.Payload)
+	if err != nil {
+		return err
+	}
+	return events.Add(nsc.EventOrderRejected, rejection)
+}
+
+func getOrderRejection(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	orderID := arguments.Str("OrderID")
+
+	rejectionKey, err := stub.CreateCompositeKey(orderRejectionObjectType, []string{orderID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	rejectionAsBytes, err := stub.GetState(rejectionKey)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if rejectionAsBytes == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "Order %s was not rejected", orderID).WithDetail("OrderID", orderID))
+	}
+	return shim.Success(rejectionAsBytes)
+}
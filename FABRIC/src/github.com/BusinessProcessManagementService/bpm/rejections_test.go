@@ -0,0 +1,52 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestRejectedOrderRecordsItsReason(t *testing.T) {
+	s := simulator.NewForTest(t)
+	fillCircuit(t, s, "C1", "O1", 80*simulator.Mbps)
+
+	// rejecting is a successful transaction, the reason is in the event and the rejection record
+	result := s.SubmitOrder(alice, "O2", "C1", 50*simulator.Mbps)
+	expectOK(t, result)
+	if len(result.Records(nsc.EventOrderRejected)) != 1 {
+		t.Errorf("expected one %s record, got %+v", nsc.EventOrderRejected, result.Event)
+	}
+
+	result = s.Query(simulator.BPM, alice, "getOrderRejection", "O2")
+	expectOK(t, result)
+	var rejection bpm.OrderRejection
+	if err := json.Unmarshal(result.Response.Payload, &rejection); err != nil {
+		t.Fatal(err)
+	}
+	if rejection.ReasonCode != nsc.CodeInsufficientCapacity || rejection.DataCircuitID != "C1" || rejection.OperatorID != "alice" {
+		t.Errorf("expected an %s rejection of alice's order on C1, got %+v", nsc.CodeInsufficientCapacity, rejection)
+	}
+	if rejection.RequestedBandwidth != 50*simulator.Mbps || rejection.AvailableBandwidth != 0 || rejection.RejectedOn == "" {
+		t.Errorf("expected 50M requested against nothing available, got %+v", rejection)
+	}
+
+	order, err := s.Order("O2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Rejected" || order.RejectionReason != nsc.CodeInsufficientCapacity {
+		t.Errorf("expected O2 Rejected with %s, got %+v", nsc.CodeInsufficientCapacity, order)
+	}
+	if err = s.ExpectBandwidth("C1", 80*simulator.Mbps, 0); err != nil {
+		t.Error(err)
+	}
+
+	// a fulfilled order has no rejection, and an order is rejected only once
+	expectCode(t, s.Query(simulator.BPM, alice, "getOrderRejection", "O1"), nsc.CodeNotFound)
+	if s.SubmitOrder(alice, "O2", "C1", 50*simulator.Mbps).OK() {
+		t.Error("expected resubmitting the rejected O2 to fail")
+	}
+}
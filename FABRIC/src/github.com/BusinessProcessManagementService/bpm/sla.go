@@ -0,0 +1,161 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// ============================================================================================================================
+// SLA Constraints - an order can name a maximum latency, jitter and loss and a minimum availability. Only circuits whose
+// performance recorded in NIMS is known and meets every constraint are placed on, the route of a path order must meet
+// them end to end as well: latency, jitter and loss add up along the hops and availability multiplies. An order no
+// circuit qualifies for is rejected with SLA_NOT_MET and the unmet constraint as reason, it is never waitlisted. ANCS
+// keeps the SLA on the order so failoverCircuit only re-homes it onto qualifying circuits.
+// ============================================================================================================================
+
+// latency and jitter are in microseconds, loss and availability in parts per million
+const (
+	maxDelay        = 1000000000
+	partsPerMillion = 1000000
+)
+
+// CircuitPerformance is the NIMS record of a circuit's performance, a nil value is unknown
+type CircuitPerformance struct {
+	Latency      *int   `json:"Latency,omitempty"`
+	Jitter       *int   `json:"Jitter,omitempty"`
+	Loss         *int   `json:"Loss,omitempty"`
+	Availability *int   `json:"Availability,omitempty"`
+	Source       string `json:"Source"`
+	UpdatedBy    string `json:"UpdatedBy"`
+	UpdatedOn    string `json:"UpdatedOn"`
+}
+
+// ServiceLevel is the SLA of an order, a zero constraint is not set
+type ServiceLevel struct {
+	MaxLatency      int `json:"MaxLatency,omitempty"`
+	MaxJitter       int `json:"MaxJitter,omitempty"`
+	MaxLoss         int `json:"MaxLoss,omitempty"`
+	MinAvailability int `json:"MinAvailability,omitempty"`
+}
+
+// newServiceLevel reads the SLA arguments of an order, nil when it names none of them
+func newServiceLevel(arguments nsc.FunctionArgs) *ServiceLevel {
+	if !arguments.Has("MaxLatency") && !arguments.Has("MaxJitter") && !arguments.Has("MaxLoss") && !arguments.Has("MinAvailability") {
+		return nil
+	}
+	return &ServiceLevel{
+		MaxLatency:      arguments.Integer("MaxLatency"),
+		MaxJitter:       arguments.Integer("MaxJitter"),
+		MaxLoss:         arguments.Integer("MaxLoss"),
+		MinAvailability: arguments.Integer("MinAvailability"),
+	}
+}
+
+// shortfall describes the first constraint the performance does not meet, empty when it meets them all. A value the
+// SLA constrains must be known.
+func (sla *ServiceLevel) shortfall(performance CircuitPerformance) string {
+	if sla == nil {
+		return ""
+	}
+	switch {
+	case sla.MaxLatency > 0 && performance.Latency == nil:
+		return "the latency is unknown"
+	case sla.MaxLatency > 0 && *performance.Latency > sla.MaxLatency:
+		return fmt.Sprintf("the latency of %dus is above %dus", *performance.Latency, sla.MaxLatency)
+	case sla.MaxJitter > 0 && performance.Jitter == nil:
+		return "the jitter is unknown"
+	case sla.MaxJitter > 0 && *performance.Jitter > sla.MaxJitter:
+		return fmt.Sprintf("the jitter of %dus is above %dus", *performance.Jitter, sla.MaxJitter)
+	case sla.MaxLoss > 0 && performance.Loss == nil:
+		return "the loss is unknown"
+	case sla.MaxLoss > 0 && *performance.Loss > sla.MaxLoss:
+		return fmt.Sprintf("the loss of %dppm is above %dppm", *performance.Loss, sla.MaxLoss)
+	case sla.MinAvailability > 0 && performance.Availability == nil:
+		return "the availability is unknown"
+	case sla.MinAvailability > 0 && *performance.Availability < sla.MinAvailability:
+		return fmt.Sprintf("the availability of %dppm is below %dppm", *performance.Availability, sla.MinAvailability)
+	}
+	return ""
+}
+
+// circuitShortfall is the shortfall of a circuit, a circuit without recorded performance knows no value
+func (sla *ServiceLevel) circuitShortfall(dataCircuit DataCircuit) string {
+	if dataCircuit.Performance == nil {
+		return sla.shortfall(CircuitPerformance{})
+	}
+	return sla.shortfall(*dataCircuit.Performance)
+}
+
+// routeShortfall is the shortfall of a route as a whole, its hops have each met the SLA already
+func (sla *ServiceLevel) routeShortfall(hops []PathHop) string {
+	if sla == nil {
+		return ""
+	}
+	latency, jitter, loss, availability := 0, 0, 0, partsPerMillion
+	for _, hop := range hops {
+		if hop.Performance == nil {
+			return sla.shortfall(CircuitPerformance{})
+		}
+		latency = latency + valueOrZero(hop.Performance.Latency)
+		jitter = jitter + valueOrZero(hop.Performance.Jitter)
+		loss = loss + valueOrZero(hop.Performance.Loss)
+		if hop.Performance.Availability != nil {
+			availability = availability * *hop.Performance.Availability / partsPerMillion
+		}
+	}
+	if shortfall := sla.shortfall(CircuitPerformance{Latency: &latency, Jitter: &jitter, Loss: &loss, Availability: &availability}); shortfall != "" {
+		return "end to end " + shortfall
+	}
+	return ""
+}
+
+// meetingServiceLevel returns the candidates that meet the SLA, every candidate without one
+func meetingServiceLevel(candidates []DataCircuit, sla *ServiceLevel) []DataCircuit {
+	if sla == nil {
+		return candidates
+	}
+	qualifying := []DataCircuit{}
+	for _, candidate := range candidates {
+		if sla.circuitShortfall(candidate) == "" {
+			qualifying = append(qualifying, candidate)
+		}
+	}
+	return qualifying
+}
+
+// rejectServiceLevel rejects an order with SLA_NOT_MET against a circuit it was compared with
+func rejectServiceLevel(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, homeChannel string, order WaitlistEntry, dataCircuit DataCircuit, reason string) error {
+	return rejectOrder(stub, events, OrderRejection{
+		OrderID:            order.OrderID,
+		DataCircuitID:      dataCircuit.CircuitID,
+		OperatorID:         order.OperatorID,
+		ReasonCode:         nsc.CodeSLANotMet,
+		Reason:             reason,
+		RequestedBandwidth: order.Bandwidth,
+		AvailableBandwidth: dataCircuit.UnallocatedBandwidth,
+		HomeChannel:        homeChannel,
+		Placement:          order.Placement,
+	})
+}
+
+// slaArgument returns the SLA of an order as the JSON argument of ANCS completeOrder, empty without one
+func slaArgument(entry WaitlistEntry) (string, error) {
+	if entry.SLA == nil {
+		return "", nil
+	}
+	slaAsBytes, err := json.Marshal(entry.SLA)
+	if err != nil {
+		return "", nsc.NewError(nsc.CodeInternal, "unable to convert the SLA of order %s to json", entry.OrderID)
+	}
+	return string(slaAsBytes), nil
+}
+
+func valueOrZero(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,76 @@
+package bpm_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func expectRejection(t *testing.T, s *simulator.Simulator, orderID string, reasonCode string) {
+	t.Helper()
+	order, err := s.Order(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Rejected" || order.RejectionReason != reasonCode {
+		t.Errorf("expected order %s Rejected with %s, got %+v", orderID, reasonCode, order)
+	}
+}
+
+func TestSLAOrderOnlyUsesQualifyingCircuits(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 500 * simulator.Mbps, "C3": simulator.Gbps})
+	// C1 is fast, C2 slow and the performance of C3 is not known
+	if err := s.SetCircuitPerformance("C1", "measured", 5000, 500, 100, 999000); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetCircuitPerformance("C2", "measured", 20000, 500, 100, 999000); err != nil {
+		t.Fatal(err)
+	}
+
+	selection := selectedCircuit(t, s.PlaceSLAOrder(alice, "O1", "NET1", 50*simulator.Mbps, simulator.ServiceLevel{MaxLatency: 10000}))
+	if selection.DataCircuitID != "C1" {
+		t.Errorf("expected O1 on the only circuit meeting 10ms, got %s", selection.DataCircuitID)
+	}
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.SLA == nil || order.SLA.MaxLatency != 10000 {
+		t.Errorf("expected ANCS to keep the SLA of O1, got %+v", order.SLA)
+	}
+
+	expectOK(t, s.PlaceSLAOrder(alice, "O2", "NET1", 10*simulator.Mbps, simulator.ServiceLevel{MinAvailability: 999500}))
+	expectRejection(t, s, "O2", nsc.CodeSLANotMet)
+}
+
+func TestSLAPathMustMeetTheSLAEndToEnd(t *testing.T) {
+	s := simulator.NewForTest(t)
+	for _, siteID := range []string{"A", "B", "D"} {
+		if err := s.AddSite(siteID, "site "+siteID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for circuitID, ends := range map[string][2]string{"C1": {"A", "B"}, "C2": {"B", "D"}} {
+		if err := s.SeedCircuit(circuitID, "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.LinkCircuit(circuitID, ends[0], ends[1]); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SetCircuitPerformance(circuitID, "measured", 6000, -1, -1, -1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// each hop meets 10ms, the route of both does not
+	expectOK(t, s.PlaceSLAPathOrder(alice, "O1", "NET1", "A", "D", 10*simulator.Mbps, "latency", simulator.ServiceLevel{MaxLatency: 10000}))
+	expectRejection(t, s, "O1", nsc.CodeSLANotMet)
+
+	expectOK(t, s.PlaceSLAPathOrder(alice, "O2", "NET1", "A", "D", 10*simulator.Mbps, "latency", simulator.ServiceLevel{MaxLatency: 12000}))
+	expectOrderStatus(t, s, "O2", "Completed")
+	if err := s.ExpectBandwidth("C2", 10*simulator.Mbps, 90*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,89 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestSplitOrderFillsLargestCircuitsFirst(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 60 * simulator.Mbps, "C3": 30 * simulator.Mbps})
+
+	selection := selectedCircuit(t, s.PlaceSplitOrder(alice, "O1", "NET1", 150*simulator.Mbps))
+	expected := []bpm.OrderLeg{{DataCircuitID: "C1", Bandwidth: 100 * simulator.Mbps}, {DataCircuitID: "C2", Bandwidth: 50 * simulator.Mbps}}
+	if !selection.Fits || len(selection.Legs) != len(expected) || selection.Legs[0] != expected[0] || selection.Legs[1] != expected[1] {
+		t.Fatalf("expected O1 split as %+v, got %+v", expected, selection)
+	}
+
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Completed" || order.DataCircuitID != "C1" || len(order.Legs) != 2 {
+		t.Errorf("expected O1 Completed on the legs C1 and C2, got %+v", order)
+	}
+	for circuitID, allocated := range map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 50 * simulator.Mbps, "C3": 0} {
+		circuit, err := s.Circuit(circuitID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if circuit.AllocatedBandwidth != allocated {
+			t.Errorf("expected %v allocated on %s, got %v", allocated, circuitID, circuit.AllocatedBandwidth)
+		}
+	}
+}
+
+func TestSplitOrderThatDoesNotFitAllocatesNoLeg(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 60 * simulator.Mbps})
+
+	selection := selectedCircuit(t, s.PlaceSplitOrder(alice, "O1", "NET1", 200*simulator.Mbps))
+	if selection.Fits || len(selection.Legs) != 0 {
+		t.Errorf("expected O1 not to fit, got %+v", selection)
+	}
+	expectOrderStatus(t, s, "O1", "Rejected")
+	for _, circuitID := range []string{"C1", "C2"} {
+		circuit, err := s.Circuit(circuitID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if circuit.AllocatedBandwidth != 0 {
+			t.Errorf("expected nothing allocated on %s, got %v", circuitID, circuit.AllocatedBandwidth)
+		}
+	}
+
+	// an excess rate is policed on one circuit, such an order is never split
+	result := s.Invoke(simulator.OMS, alice, "placeOrder", "O2", "alice", "NET1", "", "150M", "", "", "", "", "true", "", "", "10M")
+	expectCode(t, result, nsc.CodeInvalidArgument)
+}
+
+func TestSplitOrderThatNIMSRefusesIsRejected(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedNetwork(t, s, map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 60 * simulator.Mbps})
+	// a booking is not taken from the unallocated bandwidth, only NIMS sees that it leaves 50M of C1 for now
+	expectOK(t, s.BookBandwidth(alice, "C1", "B1", 50*simulator.Mbps, "20231114000000", "20231201000000"))
+
+	selection := selectedCircuit(t, s.PlaceSplitOrder(alice, "O1", "NET1", 150*simulator.Mbps))
+	if !selection.Fits || len(selection.Legs) != 2 {
+		t.Fatalf("expected O1 split over C1 and C2 by their unallocated bandwidth, got %+v", selection)
+	}
+	expectOrderStatus(t, s, "O1", "Rejected")
+	result := s.Query(simulator.BPM, alice, "getOrderRejection", "O1")
+	expectOK(t, result)
+	var rejection bpm.OrderRejection
+	if err := json.Unmarshal(result.Response.Payload, &rejection); err != nil {
+		t.Fatal(err)
+	}
+	if rejection.ReasonCode != nsc.CodeInsufficientCapacity || rejection.RequestedBandwidth != 150*simulator.Mbps {
+		t.Errorf("expected an %s rejection of the 150M of O1, got %+v", nsc.CodeInsufficientCapacity, rejection)
+	}
+	for circuitID, total := range map[string]nsc.Bandwidth{"C1": 100 * simulator.Mbps, "C2": 60 * simulator.Mbps} {
+		if err := s.ExpectBandwidth(circuitID, 0, total); err != nil {
+			t.Error(err)
+		}
+	}
+}
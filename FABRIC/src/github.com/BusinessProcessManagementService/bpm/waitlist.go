@@ -0,0 +1,389 @@
+package bpm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Order Waitlist - an order that does not fit on its circuit can be queued instead of rejected. Entries are kept per
+// circuit under "WaitlistEntry"[CircuitID, rank, sequence]: the rank puts higher priorities first and the sequence keeps
+// orders of the same priority in FIFO order. NIMS does not call back into BPM, so processWaitlist is run after capacity
+// was freed by releaseDataCircuitBandwidth, resizeDataCircuit or expireAllocations, e.g. by a listener on their
+// BandwidthReleased and CircuitResized records. A transaction does not read its own writes, a second allocation on a
+// circuit would overwrite the first, so one run fulfils at most one entry per circuit: the entries that wait for an
+// allocated circuit are reported as Deferred and the listener runs processWaitlist again while there are any. An entry
+// whose ExpiresOn has passed is rejected instead of fulfilled, an entry NIMS or ANCS refuses for another reason than
+// capacity is rejected as UPSTREAM_FAILURE, neither blocks the entries behind it.
+// ============================================================================================================================
+
+const (
+	waitlistEntryObjectType    = "WaitlistEntry"
+	waitlistOrderObjectType    = "WaitlistOrder"
+	waitlistSequenceObjectType = "WaitlistSequence"
+)
+
+var waitlistArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type WaitlistEntry struct {
+	CircuitID  string        `json:"CircuitID"`
+	OrderID    string        `json:"OrderID"`
+	OperatorID string        `json:"OperatorID"`
+	Bandwidth  nsc.Bandwidth `json:"Bandwidth"`
+	Priority   int           `json:"Priority"`
+	Sequence   int           `json:"Sequence"`
+	ExpiresOn  string        `json:"ExpiresOn,omitempty"`
+	QueuedOn   string        `json:"QueuedOn"`
+	Placement  string        `json:"Placement,omitempty"`
+	// Legs is set for an order split across circuits or routed over a path, CircuitID is then the circuit of the first leg
+	Legs []OrderLeg `json:"Legs,omitempty"`
+	// BackupCircuitID and DiverseOn are set for a protected order, it is never queued
+	BackupCircuitID string `json:"BackupCircuitID,omitempty"`
+	DiverseOn       string `json:"DiverseOn,omitempty"`
+	// Profile is set when the order names an excess rate, burst size or class of service, see profiles.go
+	Profile *BandwidthProfile `json:"Profile,omitempty"`
+	// SLA is set when the order names SLA constraints, see sla.go
+	SLA *ServiceLevel `json:"SLA,omitempty"`
+	// AllowSplit is set for an order placed by network that may be split, processWaitlist then sizes it against the
+	// circuits of CircuitNetwork, of ProviderID when set, rather than against CircuitID alone
+	AllowSplit     bool   `json:"AllowSplit,omitempty"`
+	CircuitNetwork string `json:"CircuitNetwork,omitempty"`
+	ProviderID     string `json:"ProviderID,omitempty"`
+}
+
+// WaitlistResult is the data of WaitlistProcessed events
+type WaitlistResult struct {
+	CircuitID            string        `json:"CircuitID"`
+	Fulfilled            []string      `json:"Fulfilled"`
+	StillQueued          []string      `json:"StillQueued"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	// Deferred lists the entries left for the next run because a circuit they could use was allocated in this one
+	Deferred []string `json:"Deferred"`
+	// Expired lists the entries dropped because their ExpiresOn had passed, Rejected those that failed to be fulfilled
+	Expired  []string `json:"Expired"`
+	Rejected []string `json:"Rejected"`
+}
+
+// processWaitlist fulfils the queued orders of a circuit that fit its unallocated bandwidth, in priority and FIFO order.
+// A queued order that does not fit does not block smaller orders behind it. A circuit written to is left alone for the
+// rest of the run, so every fit is checked against circuits as they were committed.
+func processWaitlist(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting processWaitlist")
+
+	circuitID := arguments.Str("CircuitID")
+	events := nsc.NewEventBatch(stub, chaincodeName, circuitID)
+
+	homeChannel, circuitData, err := queryCircuitOnHomeChannel(stub, circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertWritableChannel(stub, circuitID, homeChannel)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	entries, err := getWaitlistEntries(stub, circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	networks, err := listSplitCandidates(stub, entries)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	result := WaitlistResult{CircuitID: circuitID, Fulfilled: []string{}, StillQueued: []string{}, Deferred: []string{}, Expired: []string{}, Rejected: []string{}}
+	// touched holds the circuits a called chaincode wrote to in this run, fulfilled the bandwidth fulfilled on them
+	touched := map[string]bool{}
+	fulfilled := map[string]nsc.Bandwidth{}
+	for _, entry := range entries {
+		available := circuitData.UnallocatedBandwidth - fulfilled[circuitID]
+
+		if entry.ExpiresOn != "" && entry.ExpiresOn <= now {
+			fmt.Println("dropping expired waitlisted order " + entry.OrderID)
+			reason := fmt.Sprintf("Order %s expired on %s while waitlisted on %s", entry.OrderID, entry.ExpiresOn, circuitID)
+			err = rejectWaitlistEntry(stub, events, homeChannel, entry, available, nsc.CodeInsufficientCapacity, reason)
+			if err != nil {
+				return nsc.ErrorResponse(err)
+			}
+			result.Expired = append(result.Expired, entry.OrderID)
+			continue
+		}
+
+		candidates := networks[splitNetworkKey(entry)]
+		order := entry
+		if touched[circuitID] || entry.Bandwidth > available || entry.excessBandwidth() > availableExcess(circuitData) {
+			legs, fits := []OrderLeg(nil), false
+			if entry.AllowSplit {
+				legs, fits = splitAcrossCircuits(untouchedCandidates(candidates, touched), entry.Bandwidth)
+			}
+			if !fits && (touched[circuitID] || len(untouchedCandidates(candidates, touched)) < len(candidates)) {
+				result.Deferred = append(result.Deferred, entry.OrderID)
+				continue
+			}
+			if !fits {
+				result.StillQueued = append(result.StillQueued, entry.OrderID)
+				continue
+			}
+			order.CircuitID = legs[0].DataCircuitID
+			order.Legs = legs
+		}
+		orderLegs := order.Legs
+		if len(orderLegs) == 0 {
+			orderLegs = []OrderLeg{{DataCircuitID: order.CircuitID, Bandwidth: order.Bandwidth}}
+		}
+
+		fmt.Println("fulfilling waitlisted order " + entry.OrderID)
+		recorded := len(events.Records)
+		err = fulfilOrder(stub, events, homeChannel, order)
+		if err != nil && len(events.Records) == recorded && isInsufficientCapacity(err) {
+			// NIMS admits less than the circuits show, e.g. a booking peak, the order may fit later
+			result.StillQueued = append(result.StillQueued, entry.OrderID)
+			continue
+		}
+		if err != nil {
+			// what a called chaincode wrote before it failed stays, its circuits are left alone for the rest of the run
+			if len(events.Records) > recorded {
+				for _, leg := range orderLegs {
+					touched[leg.DataCircuitID] = true
+				}
+			}
+			err = rejectWaitlistEntry(stub, events, homeChannel, entry, available, nsc.CodeUpstreamFailure, err.Error())
+			if err != nil {
+				return nsc.ErrorResponse(err)
+			}
+			result.Rejected = append(result.Rejected, entry.OrderID)
+			continue
+		}
+
+		err = deleteWaitlistEntry(stub, entry)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		for _, leg := range orderLegs {
+			touched[leg.DataCircuitID] = true
+			fulfilled[leg.DataCircuitID] = fulfilled[leg.DataCircuitID] + leg.Bandwidth
+		}
+		result.Fulfilled = append(result.Fulfilled, entry.OrderID)
+	}
+	result.UnallocatedBandwidth = circuitData.UnallocatedBandwidth - fulfilled[circuitID]
+
+	err = events.Add(nsc.EventWaitlistProcessed, result)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end processWaitlist")
+	return events.Emit(stub)
+}
+
+// isInsufficientCapacity tells whether err, raised here or by a called chaincode, is INSUFFICIENT_CAPACITY
+func isInsufficientCapacity(err error) bool {
+	ccErr, ok := err.(*nsc.ChaincodeError)
+	return ok && ccErr.Code == nsc.CodeInsufficientCapacity
+}
+
+// rejectWaitlistEntry dequeues an entry and rejects its order
+func rejectWaitlistEntry(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, homeChannel string, entry WaitlistEntry, available nsc.Bandwidth, reasonCode string, reason string) error {
+	err := deleteWaitlistEntry(stub, entry)
+	if err != nil {
+		return err
+	}
+	return rejectOrder(stub, events, OrderRejection{
+		OrderID:            entry.OrderID,
+		DataCircuitID:      entry.CircuitID,
+		OperatorID:         entry.OperatorID,
+		ReasonCode:         reasonCode,
+		Reason:             reason,
+		RequestedBandwidth: entry.Bandwidth,
+		AvailableBandwidth: available,
+		HomeChannel:        homeChannel,
+		Placement:          entry.Placement,
+	})
+}
+
+// listSplitCandidates reads the placement candidates of every network a split entry may be spread over, keyed by
+// splitNetworkKey, before any entry is fulfilled
+func listSplitCandidates(stub shim.ChaincodeStubInterface, entries []WaitlistEntry) (map[string][]DataCircuit, error) {
+	networks := map[string][]DataCircuit{}
+	for _, entry := range entries {
+		key := splitNetworkKey(entry)
+		if !entry.AllowSplit || networks[key] != nil {
+			continue
+		}
+		candidates, err := listPlacementCandidates(stub, entry.CircuitNetwork, entry.ProviderID)
+		if err != nil {
+			return nil, err
+		}
+		networks[key] = meetingServiceLevel(candidates, entry.SLA)
+	}
+	return networks, nil
+}
+
+func splitNetworkKey(entry WaitlistEntry) string {
+	return entry.CircuitNetwork + "/" + entry.ProviderID
+}
+
+// untouchedCandidates returns the candidates no called chaincode wrote to in this run
+func untouchedCandidates(candidates []DataCircuit, touched map[string]bool) []DataCircuit {
+	remaining := []DataCircuit{}
+	for _, candidate := range candidates {
+		if !touched[candidate.CircuitID] {
+			remaining = append(remaining, candidate)
+		}
+	}
+	return remaining
+}
+
+func getWaitlist(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	entries, err := getWaitlistEntries(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	entriesAsBytes, err := json.Marshal(entries)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert waitlist to json"))
+	}
+	return shim.Success(entriesAsBytes)
+}
+
+// waitlistOrder queues an order on its circuit and adds the OrderWaitlisted record to events
+func waitlistOrder(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, entry WaitlistEntry) error {
+	fmt.Println("waitlisting order " + entry.OrderID + " on " + entry.CircuitID)
+
+	// a completed or rejected order is already in the ANCS order store and cannot be queued again
+	err := assertOrderNotProcessed(stub, entry.OrderID)
+	if err != nil {
+		return err
+	}
+
+	entry.Sequence, err = nextWaitlistSequence(stub, entry.CircuitID)
+	if err != nil {
+		return err
+	}
+	entry.QueuedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return err
+	}
+
+	entryKey, err := waitlistEntryKey(stub, entry)
+	if err != nil {
+		return err
+	}
+	buff, err := json.Marshal(entry)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert WaitlistEntry to json")
+	}
+	err = stub.PutState(entryKey, buff)
+	if err != nil {
+		return err
+	}
+	orderKey, err := stub.CreateCompositeKey(waitlistOrderObjectType, []string{entry.OrderID})
+	if err != nil {
+		return err
+	}
+	err = stub.PutState(orderKey, []byte(entryKey))
+	if err != nil {
+		return err
+	}
+
+	return events.Add(nsc.EventOrderWaitlisted, entry)
+}
+
+// assertNotWaitlisted refuses to process an order again while it waits in the queue, processWaitlist owns it
+func assertNotWaitlisted(stub shim.ChaincodeStubInterface, orderID string) error {
+	orderKey, err := stub.CreateCompositeKey(waitlistOrderObjectType, []string{orderID})
+	if err != nil {
+		return err
+	}
+	existing, err := stub.GetState(orderKey)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nsc.NewError(nsc.CodeConflict, "Order %s is waitlisted", orderID).WithDetail("OrderID", orderID)
+	}
+	return nil
+}
+
+// waitlistEntryKey orders entries by descending priority, then by arrival
+func waitlistEntryKey(stub shim.ChaincodeStubInterface, entry WaitlistEntry) (string, error) {
+	rank := fmt.Sprintf("%03d", nsc.MaxPriority-entry.Priority)
+	sequence := fmt.Sprintf("%010d", entry.Sequence)
+	return stub.CreateCompositeKey(waitlistEntryObjectType, []string{entry.CircuitID, rank, sequence})
+}
+
+// nextWaitlistSequence hands out increasing sequence numbers per circuit
+func nextWaitlistSequence(stub shim.ChaincodeStubInterface, circuitID string) (int, error) {
+	sequenceKey, err := stub.CreateCompositeKey(waitlistSequenceObjectType, []string{circuitID})
+	if err != nil {
+		return 0, err
+	}
+	sequenceAsBytes, err := stub.GetState(sequenceKey)
+	if err != nil {
+		return 0, err
+	}
+
+	sequence := 0
+	if sequenceAsBytes != nil {
+		sequence, err = strconv.Atoi(string(sequenceAsBytes))
+		if err != nil {
+			return 0, nsc.NewError(nsc.CodeInternal, "unable to read waitlist sequence of %s: %s", circuitID, err.Error())
+		}
+	}
+	sequence++
+	return sequence, stub.PutState(sequenceKey, []byte(strconv.Itoa(sequence)))
+}
+
+func deleteWaitlistEntry(stub shim.ChaincodeStubInterface, entry WaitlistEntry) error {
+	entryKey, err := waitlistEntryKey(stub, entry)
+	if err != nil {
+		return err
+	}
+	err = stub.DelState(entryKey)
+	if err != nil {
+		return err
+	}
+
+	orderKey, err := stub.CreateCompositeKey(waitlistOrderObjectType, []string{entry.OrderID})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(orderKey)
+}
+
+// getWaitlistEntries lists the queued orders of a circuit in the order they are served
+func getWaitlistEntries(stub shim.ChaincodeStubInterface, circuitID string) ([]WaitlistEntry, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(waitlistEntryObjectType, []string{circuitID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	entries := []WaitlistEntry{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry WaitlistEntry
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, nsc.NewError(nsc.CodeInternal, "unable to read waitlist entry %s: %s", queryResponse.Key, err.Error())
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
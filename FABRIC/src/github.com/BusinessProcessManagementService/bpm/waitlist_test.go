@@ -0,0 +1,216 @@
+package bpm_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// processWaitlist runs the waitlist of a circuit and returns its WaitlistProcessed record
+func processWaitlist(t *testing.T, s *simulator.Simulator, circuitID string) bpm.WaitlistResult {
+	t.Helper()
+	result := s.ProcessWaitlist(circuitID)
+	expectOK(t, result)
+	records := result.Records(nsc.EventWaitlistProcessed)
+	if len(records) != 1 {
+		t.Fatalf("expected one %s record, got %d", nsc.EventWaitlistProcessed, len(records))
+	}
+	var processed bpm.WaitlistResult
+	err := json.Unmarshal(records[0].Data, &processed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return processed
+}
+
+func expectOrderStatus(t *testing.T, s *simulator.Simulator, orderID string, status string) {
+	t.Helper()
+	order, err := s.Order(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != status {
+		t.Errorf("expected order %s to be %s, it is %q", orderID, status, order.Status)
+	}
+}
+
+func TestProcessWaitlistDropsExpiredEntries(t *testing.T) {
+	s := simulator.NewForTest(t)
+	fillCircuit(t, s, "C1", "O1", 100*simulator.Mbps)
+
+	// the simulator's transactions are timestamped 20231114221320
+	expectOK(t, s.Invoke(simulator.OMS, alice, "prepareOrder", "O2", "alice", "C1", "50M", "20230101000000", "true", "1"))
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O3", "C1", 50*simulator.Mbps, 0))
+	expectOK(t, s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "", "O1"))
+
+	processed := processWaitlist(t, s, "C1")
+	if len(processed.Expired) != 1 || processed.Expired[0] != "O2" {
+		t.Errorf("expected O2 to expire, got %v", processed.Expired)
+	}
+	if len(processed.Fulfilled) != 1 || processed.Fulfilled[0] != "O3" {
+		t.Errorf("expected O3 to be fulfilled, got %v", processed.Fulfilled)
+	}
+	expectOrderStatus(t, s, "O2", "Rejected")
+	if err := s.ExpectBandwidth("C1", 50*simulator.Mbps, 50*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	// the expired entry is gone, a second run has nothing left to do
+	processed = processWaitlist(t, s, "C1")
+	if len(processed.Expired)+len(processed.Fulfilled)+len(processed.StillQueued) != 0 {
+		t.Errorf("expected an empty waitlist, got %+v", processed)
+	}
+}
+
+func TestProcessWaitlistRejectsFailingEntryAndContinues(t *testing.T) {
+	s := simulator.NewForTest(t)
+	fillCircuit(t, s, "C1", "O1", 100*simulator.Mbps)
+
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O2", "C1", 50*simulator.Mbps, 1))
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O3", "C1", 50*simulator.Mbps, 0))
+	expectOK(t, s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "", "O1"))
+
+	// an ExpiresOn NIMS refuses makes the allocation of O2 fail
+	stub := s.Stub(simulator.BPM)
+	for key, value := range stub.State {
+		if !strings.Contains(key, "WaitlistEntry") || !strings.Contains(string(value), `"O2"`) {
+			continue
+		}
+		var entry bpm.WaitlistEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entry.ExpiresOn = "never"
+		stub.State[key], _ = json.Marshal(entry)
+	}
+
+	processed := processWaitlist(t, s, "C1")
+	if len(processed.Rejected) != 1 || processed.Rejected[0] != "O2" {
+		t.Errorf("expected O2 to be rejected, got %v", processed.Rejected)
+	}
+	if len(processed.Fulfilled) != 1 || processed.Fulfilled[0] != "O3" {
+		t.Errorf("expected O3 to be fulfilled, got %v", processed.Fulfilled)
+	}
+	expectOrderStatus(t, s, "O2", "Rejected")
+	expectOrderStatus(t, s, "O3", "Completed")
+	order, err := s.Order("O2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.RejectionReason != nsc.CodeUpstreamFailure {
+		t.Errorf("expected O2 to be rejected as %s, got %s", nsc.CodeUpstreamFailure, order.RejectionReason)
+	}
+
+	processed = processWaitlist(t, s, "C1")
+	if len(processed.Rejected)+len(processed.StillQueued) != 0 {
+		t.Errorf("expected O2 to be dequeued, got %+v", processed)
+	}
+}
+
+func TestProcessWaitlistSplitsAcrossNetwork(t *testing.T) {
+	s := simulator.NewForTest(t)
+	fillCircuit(t, s, "C1", "O1", 100*simulator.Mbps)
+	fillCircuit(t, s, "C2", "O2", 100*simulator.Mbps)
+
+	// nothing is free, the split order is queued on one circuit of NET1
+	result := s.Invoke(simulator.OMS, alice, "placeOrder", "O3", "alice", "NET1", "", "80M", "", "", "true", "", "true")
+	expectOK(t, result)
+	waitlisted := result.Records(nsc.EventOrderWaitlisted)
+	if len(waitlisted) != 1 {
+		t.Fatalf("expected O3 to be waitlisted, got %d %s records", len(waitlisted), nsc.EventOrderWaitlisted)
+	}
+	var entry bpm.WaitlistEntry
+	if err := json.Unmarshal(waitlisted[0].Data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	queuedOn, other := entry.CircuitID, "C1"
+	if queuedOn == "C1" {
+		other = "C2"
+	}
+
+	// 50M frees up on each circuit: neither holds O3 alone, together they do
+	for _, circuitID := range []string{"C1", "C2"} {
+		expectOK(t, s.Invoke(simulator.NIMS, provider, "resizeDataCircuit", circuitID, "150M"))
+	}
+
+	processed := processWaitlist(t, s, queuedOn)
+	if len(processed.Fulfilled) != 1 || processed.Fulfilled[0] != "O3" {
+		t.Fatalf("expected O3 to be fulfilled across NET1, got %+v", processed)
+	}
+	order, err := s.Order("O3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order.Legs) != 2 {
+		t.Fatalf("expected O3 to be split in two legs, got %+v", order.Legs)
+	}
+	var total nsc.Bandwidth
+	for _, leg := range order.Legs {
+		total = total + leg.Bandwidth
+	}
+	if total != 80*simulator.Mbps {
+		t.Errorf("expected the legs to add up to 80M, got %s", total)
+	}
+	circuit, err := s.Circuit(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if circuit.AllocatedBandwidth <= 100*simulator.Mbps {
+		t.Errorf("expected a leg on %s, it holds %s", other, circuit.AllocatedBandwidth)
+	}
+}
+
+func TestProcessWaitlistServesPriorityThenFIFO(t *testing.T) {
+	s := simulator.NewForTest(t)
+	fillCircuit(t, s, "C1", "O1", 100*simulator.Mbps)
+
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O2", "C1", 60*simulator.Mbps, 0))
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O3", "C1", 30*simulator.Mbps, 5))
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O4", "C1", 30*simulator.Mbps, 5))
+	expectOK(t, s.SubmitWaitlistedOrder(alice, "O5", "C1", 20*simulator.Mbps, 0))
+
+	result := s.Query(simulator.BPM, alice, "getWaitlist", "C1")
+	expectOK(t, result)
+	var entries []bpm.WaitlistEntry
+	if err := json.Unmarshal(result.Response.Payload, &entries); err != nil {
+		t.Fatal(err)
+	}
+	var queued []string
+	for _, entry := range entries {
+		queued = append(queued, entry.OrderID)
+	}
+	if strings.Join(queued, ",") != "O3,O4,O2,O5" {
+		t.Fatalf("expected the waitlist O3,O4,O2,O5, got %v", queued)
+	}
+
+	// one entry is fulfilled per run, the others wait for the next one
+	expectOK(t, s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "", "O1"))
+	processed := processWaitlist(t, s, "C1")
+	if strings.Join(processed.Fulfilled, ",") != "O3" || strings.Join(processed.Deferred, ",") != "O4,O2,O5" {
+		t.Fatalf("expected O3 fulfilled and O4,O2,O5 deferred, got %+v", processed)
+	}
+
+	// O2 does not fit after O3 and O4, the smaller O5 behind it still does
+	var fulfilled []string
+	for runs := 0; len(processed.Deferred) > 0; runs++ {
+		if runs == 3 {
+			t.Fatalf("expected the deferred entries to be served, got %+v", processed)
+		}
+		processed = processWaitlist(t, s, "C1")
+		fulfilled = append(fulfilled, processed.Fulfilled...)
+	}
+	if strings.Join(fulfilled, ",") != "O4,O5" || strings.Join(processed.StillQueued, ",") != "O2" {
+		t.Errorf("expected O4,O5 fulfilled and O2 still queued, got %v and %+v", fulfilled, processed)
+	}
+	if processed.UnallocatedBandwidth != 20*simulator.Mbps {
+		t.Errorf("expected 20M left unallocated, got %v", processed.UnallocatedBandwidth)
+	}
+	expectOrderStatus(t, s, "O5", "Completed")
+	if err := s.ExpectBandwidth("C1", 80*simulator.Mbps, 20*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
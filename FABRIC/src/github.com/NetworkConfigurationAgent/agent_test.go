@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+const mbps nsc.Bandwidth = 1000000
+
+type recordingReporter struct {
+	statuses []string
+}
+
+func (r *recordingReporter) Report(job ConfigurationJob, status string, deviceID string, message string) error {
+	r.statuses = append(r.statuses, job.OrderID+"/"+job.DataCircuitID+" "+status)
+	return nil
+}
+
+func TestFailoverJobRemovesReplacedCircuit(t *testing.T) {
+	driver, err := newSimulatedRouter(map[string]string{"capacity": "100M"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := driver.(*SimulatedRouter)
+	reporter := &recordingReporter{}
+	a := &agent{"ANCS", driver, reporter, map[string]bool{}}
+
+	profile := &BandwidthProfile{CommittedBandwidth: 60 * mbps, ExcessBandwidth: 20 * mbps, BurstSize: 64, ClassOfService: "gold"}
+	jobs := []ConfigurationJob{
+		{OrderID: "O1", DataCircuitID: "C1", OrderBandwidth: profile.CommittedBandwidth, Profile: profile},
+		{OrderID: "O1", DataCircuitID: "C2", OrderBandwidth: profile.CommittedBandwidth, Profile: profile, ReplacesCircuitID: "C1"},
+		// a replay of the failover finds C1 already removed
+		{OrderID: "O1", DataCircuitID: "C2", OrderBandwidth: profile.CommittedBandwidth, Profile: profile, ReplacesCircuitID: "C1"},
+	}
+	for _, job := range jobs {
+		err = a.applyJob(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := router.Interfaces["O1/C1"]; ok {
+		t.Error("expected the sub-interface and policer of O1 on C1 to be removed")
+	}
+	moved, ok := router.Interfaces["O1/C2"]
+	if !ok || moved.ClassOfService != "gold" || moved.ExcessBandwidth != profile.ExcessBandwidth {
+		t.Errorf("expected O1 on C2 with its policer, got %+v", moved)
+	}
+	for _, status := range reporter.statuses {
+		if !strings.HasSuffix(status, configurationApplied) {
+			t.Errorf("expected every job to be applied, got %v", reporter.statuses)
+		}
+	}
+}
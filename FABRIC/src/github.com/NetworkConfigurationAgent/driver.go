@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// Device Drivers - a driver applies the configuration of one order to a device and removes it from a circuit the order
+// was moved off. Apply and Remove must be idempotent because events are replayed after a restart or a dropped stream.
+// ============================================================================================================================
+
+// DeviceConfig is what the network has to be configured with for one order
+type DeviceConfig struct {
+	OrderID    string
+	CircuitID  string
+	Bandwidth  nsc.Bandwidth
+	OperatorID string
+	// the policer of the order: Bandwidth is its committed rate, ExcessBandwidth the rate it may burst to on top of it,
+	// BurstSize in kilobytes, all zero and an empty class when the order has no bandwidth profile
+	ExcessBandwidth nsc.Bandwidth
+	BurstSize       int
+	ClassOfService  string
+}
+
+type DeviceDriver interface {
+	DeviceID() string
+	Apply(config DeviceConfig) error
+	// Remove takes the configuration of an order off a circuit, with its policer, and succeeds when there is none
+	Remove(orderID string, circuitID string) error
+}
+
+// driverFactories build a driver from its "key=value,key=value" options
+var driverFactories = map[string]func(options map[string]string) (DeviceDriver, error){
+	"simulated": newSimulatedRouter,
+}
+
+func newDeviceDriver(name string, options string) (DeviceDriver, error) {
+	factory, ok := driverFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown device driver %s, expecting one of %s", name, strings.Join(driverNames(), ", "))
+	}
+
+	parsed, err := parseDriverOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return factory(parsed)
+}
+
+func driverNames() []string {
+	var names []string
+	for name := range driverFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseDriverOptions(options string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, option := range strings.Split(options, ",") {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			continue
+		}
+		pair := strings.SplitN(option, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid driver option %q, expecting key=value", option)
+		}
+		parsed[pair[0]] = pair[1]
+	}
+	return parsed, nil
+}
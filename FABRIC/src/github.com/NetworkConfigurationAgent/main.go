@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// Network Configuration Agent - closes the provisioning loop off-chain. It follows the ConfigurationRequested records
+// that ANCS adds to the transaction events, applies each job to a device driver and reports the outcome back to ANCS
+// through reportConfigurationApplied.
+//
+// Live, against the REST API (the user must be enrolled with role "agent"):
+//   NetworkConfigurationAgent -source stream -api http://localhost:3000 -user Agent1 -org org1
+// Replaying a recording with the simulated router and no network:
+//   NetworkConfigurationAgent -source blocks -report stdout -driver-options state=/tmp/router.json mychannel_12.block
+// ============================================================================================================================
+
+const (
+	eventName                   = "NetworkServiceEvents"
+	eventPayloadVersion         = "1.0"
+	eventConfigurationRequested = "ConfigurationRequested"
+	configurationApplied        = "Applied"
+	configurationFailed         = "Failed"
+)
+
+type EventRecord struct {
+	Type          string          `json:"type"`
+	Chaincode     string          `json:"chaincode"`
+	CorrelationID string          `json:"correlationId"`
+	Data          json.RawMessage `json:"data"`
+}
+
+type EventBatch struct {
+	Version       string        `json:"version"`
+	TxID          string        `json:"txId"`
+	CorrelationID string        `json:"correlationId"`
+	Records       []EventRecord `json:"records"`
+}
+
+// ConfigurationJob is the data of a ConfigurationRequested record
+type ConfigurationJob struct {
+	OrderID        string        `json:"OrderID"`
+	DataCircuitID  string        `json:"DataCircuitID"`
+	OrderBandwidth nsc.Bandwidth `json:"OrderBandwidth"`
+	OperatorID     string        `json:"OperatorID"`
+	Status         string        `json:"Status"`
+	RequestedOn    string        `json:"RequestedOn"`
+	// Leg is set for the job of one circuit leg of a split order, OrderBandwidth is then the leg's bandwidth
+	Leg bool `json:"Leg,omitempty"`
+	// Role is primary or backup for the jobs of a protected order, the backup is configured like the primary
+	Role string `json:"Role,omitempty"`
+	// Profile is set when the order has a bandwidth profile, its committed rate is OrderBandwidth
+	Profile *BandwidthProfile `json:"Profile,omitempty"`
+	// ReplacesCircuitID is the failed circuit a failover moves the order off, its configuration is removed first
+	ReplacesCircuitID string `json:"ReplacesCircuitID,omitempty"`
+}
+
+type BandwidthProfile struct {
+	CommittedBandwidth nsc.Bandwidth `json:"CommittedBandwidth"`
+	ExcessBandwidth    nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	BurstSize          int           `json:"BurstSize,omitempty"`
+	ClassOfService     string        `json:"ClassOfService"`
+}
+
+type agent struct {
+	ancsName string
+	driver   DeviceDriver
+	reporter Reporter
+	handled  map[string]bool
+}
+
+func main() {
+	sourceKind := flag.String("source", "stream", "where events come from: stream, events (recorded stream) or blocks (block files given as arguments)")
+	eventsPath := flag.String("events", "", "newline delimited JSON recorded from the event stream, for -source events")
+	apiURL := flag.String("api", "http://localhost:3000", "base URL of the REST API")
+	userName := flag.String("user", "", "enrolled user the REST API acts as, it needs the agent role to report")
+	orgName := flag.String("org", "org1", "organization of the user")
+	channelID := flag.String("channel", "mychannel", "channel of the chaincodes")
+	chaincodes := flag.String("chaincodes", "OrderManagementService,BusinessProcessManagementService,AutomaticNetworkConfigurationService",
+		"chaincodes whose transaction events are followed, any of them can be the top of a call chain ending in ANCS")
+	ancsName := flag.String("ancs", "AutomaticNetworkConfigurationService", "name ANCS is deployed under")
+	peer := flag.String("peer", "", "peer to read events from, default the first peer of the organization")
+	peers := flag.String("peers", "peer0.org1.example.com", "comma separated endorsing peers for the reports")
+	startBlock := flag.Int64("start-block", -1, "block to replay the stream from, -1 for new blocks only")
+	driverName := flag.String("driver", "simulated", "device driver, one of "+strings.Join(driverNames(), ", "))
+	driverOptions := flag.String("driver-options", "", "comma separated key=value options of the device driver")
+	reportKind := flag.String("report", "rest", "where outcomes are reported: rest or stdout")
+	flag.Parse()
+
+	driver, err := newDeviceDriver(*driverName, *driverOptions)
+	if err != nil {
+		exit(err)
+	}
+
+	var reporter Reporter
+	switch *reportKind {
+	case "rest":
+		if *userName == "" {
+			exit(fmt.Errorf("-user is required to report over the REST API"))
+		}
+		reporter = newRestReporter(*apiURL, *channelID, *ancsName, *userName, *orgName, splitList(*peers))
+	case "stdout":
+		reporter = stdoutReporter{}
+	default:
+		exit(fmt.Errorf("unknown reporter %s", *reportKind))
+	}
+
+	var source EventSource
+	switch *sourceKind {
+	case "stream":
+		if *userName == "" {
+			exit(fmt.Errorf("-user is required to follow the event stream"))
+		}
+		source, err = newStreamSource(streamConfig{
+			APIURL:     *apiURL,
+			UserName:   *userName,
+			OrgName:    *orgName,
+			ChannelID:  *channelID,
+			Chaincodes: splitList(*chaincodes),
+			EventName:  eventName,
+			Peer:       *peer,
+			StartBlock: *startBlock,
+			RetryDelay: 5 * time.Second,
+		})
+	case "events":
+		source, err = newEventLogSource(*eventsPath)
+	case "blocks":
+		source, err = newBlockFileSource(flag.Args())
+	default:
+		err = fmt.Errorf("unknown event source %s", *sourceKind)
+	}
+	if err != nil {
+		exit(err)
+	}
+	defer source.Close()
+
+	a := &agent{*ancsName, driver, reporter, map[string]bool{}}
+	fmt.Printf("agent for device %s is running\n", driver.DeviceID())
+	for {
+		event, err := source.Next()
+		if err == io.EOF {
+			fmt.Println("no more events")
+			return
+		}
+		if err != nil {
+			exit(err)
+		}
+		a.handleEvent(event)
+	}
+}
+
+// handleEvent applies every configuration job in the event batch of one transaction
+func (a *agent) handleEvent(event ChaincodeEvent) {
+	if event.EventName != eventName {
+		return
+	}
+
+	var batch EventBatch
+	err := json.Unmarshal([]byte(event.Payload), &batch)
+	if err != nil {
+		fmt.Printf("skipping event of transaction %s: %s\n", event.TxID, err.Error())
+		return
+	}
+	if batch.Version != eventPayloadVersion {
+		fmt.Printf("skipping event of transaction %s: unsupported version %s\n", event.TxID, batch.Version)
+		return
+	}
+
+	for _, record := range batch.Records {
+		if record.Type != eventConfigurationRequested || record.Chaincode != a.ancsName {
+			continue
+		}
+
+		var job ConfigurationJob
+		err = json.Unmarshal(record.Data, &job)
+		if err != nil {
+			fmt.Printf("skipping configuration record of transaction %s: %s\n", event.TxID, err.Error())
+			continue
+		}
+		// a split order has one job per circuit leg, a failover requests a circuit's job again
+		jobKey := job.OrderID + "/" + job.DataCircuitID + "/" + job.RequestedOn
+		if a.handled[jobKey] {
+			continue
+		}
+
+		err = a.applyJob(job)
+		if err != nil {
+			// left unhandled, a replay of the stream retries it
+			fmt.Printf("order %s: %s\n", job.OrderID, err.Error())
+			continue
+		}
+		a.handled[jobKey] = true
+	}
+}
+
+func (a *agent) applyJob(job ConfigurationJob) error {
+	fmt.Printf("applying order %s: %s on circuit %s\n", job.OrderID, job.OrderBandwidth, job.DataCircuitID)
+
+	status := configurationApplied
+	message := ""
+	config := DeviceConfig{OrderID: job.OrderID, CircuitID: job.DataCircuitID, Bandwidth: job.OrderBandwidth, OperatorID: job.OperatorID}
+	if job.Profile != nil {
+		config.ExcessBandwidth = job.Profile.ExcessBandwidth
+		config.BurstSize = job.Profile.BurstSize
+		config.ClassOfService = job.Profile.ClassOfService
+	}
+	err := a.removeReplaced(job)
+	if err == nil {
+		err = a.driver.Apply(config)
+	}
+	if err != nil {
+		status = configurationFailed
+		message = err.Error()
+	}
+
+	return a.reporter.Report(job, status, a.driver.DeviceID(), message)
+}
+
+// removeReplaced removes the configuration of the order from the circuit a failover moved it off
+func (a *agent) removeReplaced(job ConfigurationJob) error {
+	if job.ReplacesCircuitID == "" || job.ReplacesCircuitID == job.DataCircuitID {
+		return nil
+	}
+	fmt.Printf("removing order %s from circuit %s\n", job.OrderID, job.ReplacesCircuitID)
+	return a.driver.Remove(job.OrderID, job.ReplacesCircuitID)
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ============================================================================================================================
+// Reporters - the outcome of every job is sent back to ANCS through reportConfigurationApplied, invoked over the REST API
+// as a user enrolled with the "agent" role. The stdout reporter only prints, for local runs against a recording.
+// ============================================================================================================================
+
+type Reporter interface {
+	Report(job ConfigurationJob, status string, deviceID string, message string) error
+}
+
+type stdoutReporter struct{}
+
+func (stdoutReporter) Report(job ConfigurationJob, status string, deviceID string, message string) error {
+	fmt.Printf("reportConfigurationApplied %s %s %s %q %s\n", job.OrderID, status, deviceID, message, job.DataCircuitID)
+	return nil
+}
+
+type restReporter struct {
+	APIURL    string
+	ChannelID string
+	Chaincode string
+	UserName  string
+	OrgName   string
+	Peers     []string
+	client    *http.Client
+}
+
+type invokeRequest struct {
+	Peers    []string `json:"peers"`
+	Fcn      string   `json:"fcn"`
+	Args     []string `json:"args"`
+	UserName string   `json:"userName"`
+	OrgName  string   `json:"orgName"`
+}
+
+// invokeResult is the body returned by the REST API for an invoke
+type invokeResult struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+func newRestReporter(apiURL, channelID, chaincode, userName, orgName string, peers []string) Reporter {
+	return &restReporter{apiURL, channelID, chaincode, userName, orgName, peers, &http.Client{Timeout: 60 * time.Second}}
+}
+
+func (r *restReporter) Report(job ConfigurationJob, status string, deviceID string, message string) error {
+	orderID := job.OrderID
+	body, err := json.Marshal(invokeRequest{
+		Peers:    r.Peers,
+		Fcn:      "reportConfigurationApplied",
+		Args:     []string{orderID, status, deviceID, message, job.DataCircuitID},
+		UserName: r.UserName,
+		OrgName:  r.OrgName,
+	})
+	if err != nil {
+		return err
+	}
+
+	invokeURL := fmt.Sprintf("%s/chaincodesAPI/channels/%s/chaincodes/%s", r.APIURL, url.PathEscape(r.ChannelID), url.PathEscape(r.Chaincode))
+	response, err := r.client.Post(invokeURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	var result invokeResult
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil {
+		return fmt.Errorf("%s: unreadable invoke response: %s", response.Status, err.Error())
+	}
+	if !result.Success {
+		// a replayed event for a job that was already reported
+		if strings.Contains(result.Message, "CONFLICT") {
+			fmt.Printf("order %s was already reported\n", orderID)
+			return nil
+		}
+		return fmt.Errorf("reportConfigurationApplied failed for order %s: %s", orderID, result.Message)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// Simulated Router - keeps one rate limited sub-interface per order and circuit, in memory or persisted to a JSON file so the
+// provisioning loop can be run locally without hardware. A failover removes the order's sub-interface from the failed
+// circuit before configuring the new one. A sub-interface of an order with a bandwidth profile gets a
+// policer with its excess rate, burst size and class of service, the port capacity only counts committed rates.
+// Options: id=<device id>, state=<json file>, capacity=<bandwidth per circuit port such as 10G, 0 for unlimited>
+// ============================================================================================================================
+
+const firstVLAN = 100
+
+type RouterInterface struct {
+	OrderID    string        `json:"OrderID"`
+	CircuitID  string        `json:"CircuitID"`
+	Bandwidth  nsc.Bandwidth `json:"Bandwidth"`
+	OperatorID string        `json:"OperatorID"`
+	VLAN       int           `json:"VLAN"`
+	// the policer on top of the committed Bandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	BurstSize       int           `json:"BurstSize,omitempty"`
+	ClassOfService  string        `json:"ClassOfService,omitempty"`
+}
+
+type SimulatedRouter struct {
+	ID         string                     `json:"ID"`
+	Capacity   nsc.Bandwidth              `json:"Capacity"`
+	Interfaces map[string]RouterInterface `json:"Interfaces"`
+
+	statePath string
+	mutex     sync.Mutex
+}
+
+func newSimulatedRouter(options map[string]string) (DeviceDriver, error) {
+	router := &SimulatedRouter{
+		ID:         "simulated-router-1",
+		Interfaces: map[string]RouterInterface{},
+		statePath:  options["state"],
+	}
+
+	if router.statePath != "" {
+		data, err := ioutil.ReadFile(router.statePath)
+		if err == nil {
+			err = json.Unmarshal(data, router)
+			if err != nil {
+				return nil, fmt.Errorf("invalid router state %s: %s", router.statePath, err.Error())
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	if id, ok := options["id"]; ok {
+		router.ID = id
+	}
+	if capacity, ok := options["capacity"]; ok {
+		value, err := nsc.ParseBandwidth(capacity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid router capacity %q: %s", capacity, err.Error())
+		}
+		router.Capacity = value
+	}
+	if router.Interfaces == nil {
+		router.Interfaces = map[string]RouterInterface{}
+	}
+	return router, nil
+}
+
+func (r *SimulatedRouter) DeviceID() string {
+	return r.ID
+}
+
+func (r *SimulatedRouter) Apply(config DeviceConfig) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// the legs of an order split across circuits get a sub-interface each
+	key := config.OrderID + "/" + config.CircuitID
+	if existing, ok := r.Interfaces[key]; ok {
+		if existing.Bandwidth == config.Bandwidth {
+			return nil
+		}
+		return fmt.Errorf("order %s is already configured on circuit %s with bandwidth %s", config.OrderID, existing.CircuitID, existing.Bandwidth)
+	}
+
+	if r.Capacity > 0 {
+		used := nsc.Bandwidth(0)
+		for _, configured := range r.Interfaces {
+			if configured.CircuitID == config.CircuitID {
+				used += configured.Bandwidth
+			}
+		}
+		if used+config.Bandwidth > r.Capacity {
+			return fmt.Errorf("port of circuit %s has %s of %s left, cannot shape %s for order %s",
+				config.CircuitID, r.Capacity-used, r.Capacity, config.Bandwidth, config.OrderID)
+		}
+	}
+
+	r.Interfaces[key] = RouterInterface{config.OrderID, config.CircuitID, config.Bandwidth, config.OperatorID, r.nextVLAN(),
+		config.ExcessBandwidth, config.BurstSize, config.ClassOfService}
+	err := r.save()
+	if err != nil {
+		delete(r.Interfaces, key)
+		return err
+	}
+
+	fmt.Printf("%s: configured VLAN %d on circuit %s at %s for order %s\n",
+		r.ID, r.Interfaces[key].VLAN, config.CircuitID, config.Bandwidth, config.OrderID)
+	if config.ClassOfService != "" {
+		fmt.Printf("%s: policer on VLAN %d: CIR %s, EIR %s, burst %d KB, class %s\n",
+			r.ID, r.Interfaces[key].VLAN, config.Bandwidth, config.ExcessBandwidth, config.BurstSize, config.ClassOfService)
+	}
+	return nil
+}
+
+func (r *SimulatedRouter) Remove(orderID string, circuitID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := orderID + "/" + circuitID
+	removed, ok := r.Interfaces[key]
+	if !ok {
+		return nil
+	}
+
+	delete(r.Interfaces, key)
+	err := r.save()
+	if err != nil {
+		r.Interfaces[key] = removed
+		return err
+	}
+
+	fmt.Printf("%s: removed VLAN %d from circuit %s for order %s\n", r.ID, removed.VLAN, circuitID, orderID)
+	if removed.ClassOfService != "" {
+		fmt.Printf("%s: removed the policer on VLAN %d\n", r.ID, removed.VLAN)
+	}
+	return nil
+}
+
+func (r *SimulatedRouter) nextVLAN() int {
+	vlan := firstVLAN
+	for _, configured := range r.Interfaces {
+		if configured.VLAN >= vlan {
+			vlan = configured.VLAN + 1
+		}
+	}
+	return vlan
+}
+
+// save writes the state file through a rename so a crash never leaves it half written
+func (r *SimulatedRouter) save() error {
+	if r.statePath == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := r.statePath + ".tmp"
+	err = ioutil.WriteFile(tmpPath, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, r.statePath)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// ============================================================================================================================
+// Event Sources - chaincode events are read live from the REST API's event stream, or replayed from a recording: either
+// the newline delimited JSON written by that stream, or block files fetched with `peer channel fetch`.
+// ============================================================================================================================
+
+// ChaincodeEvent is one line of the REST API's event stream
+type ChaincodeEvent struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	TxID        string `json:"txId"`
+	ChaincodeID string `json:"chaincodeId"`
+	EventName   string `json:"eventName"`
+	Payload     string `json:"payload"`
+}
+
+// EventSource returns events in ledger order, io.EOF once a recording is exhausted
+type EventSource interface {
+	Next() (ChaincodeEvent, error)
+	Close() error
+}
+
+// ========================================== recorded event stream ==========================================================
+
+type eventLogSource struct {
+	file    *os.File
+	scanner *bufio.Scanner
+}
+
+func newEventLogSource(path string) (EventSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &eventLogSource{file, scanner}, nil
+}
+
+func (s *eventLogSource) Next() (ChaincodeEvent, error) {
+	return nextEventLine(s.scanner)
+}
+
+func (s *eventLogSource) Close() error {
+	return s.file.Close()
+}
+
+// nextEventLine decodes the next non blank line of an event stream
+func nextEventLine(scanner *bufio.Scanner) (ChaincodeEvent, error) {
+	var event ChaincodeEvent
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		err := json.Unmarshal(line, &event)
+		if err != nil {
+			return event, fmt.Errorf("invalid event line %q: %s", line, err.Error())
+		}
+		return event, nil
+	}
+	if scanner.Err() != nil {
+		return event, scanner.Err()
+	}
+	return event, io.EOF
+}
+
+// ========================================== recorded blocks ================================================================
+
+type blockFileSource struct {
+	paths   []string
+	pending []ChaincodeEvent
+}
+
+func newBlockFileSource(paths []string) (EventSource, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no block files given")
+	}
+	return &blockFileSource{paths: paths}, nil
+}
+
+func (s *blockFileSource) Next() (ChaincodeEvent, error) {
+	for len(s.pending) == 0 {
+		if len(s.paths) == 0 {
+			return ChaincodeEvent{}, io.EOF
+		}
+		path := s.paths[0]
+		s.paths = s.paths[1:]
+
+		events, err := readBlockFile(path)
+		if err != nil {
+			return ChaincodeEvent{}, fmt.Errorf("%s: %s", path, err.Error())
+		}
+		s.pending = events
+	}
+
+	event := s.pending[0]
+	s.pending = s.pending[1:]
+	return event, nil
+}
+
+func (s *blockFileSource) Close() error {
+	return nil
+}
+
+// readBlockFile returns the chaincode events of the valid endorser transactions of a block
+func readBlockFile(path string) ([]ChaincodeEvent, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &common.Block{}
+	err = proto.Unmarshal(data, block)
+	if err != nil {
+		return nil, err
+	}
+
+	var txFilter []byte
+	if block.Metadata != nil && len(block.Metadata.Metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		txFilter = block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var events []ChaincodeEvent
+	for i, envelopeBytes := range block.Data.Data {
+		if i < len(txFilter) && pb.TxValidationCode(txFilter[i]) != pb.TxValidationCode_VALID {
+			continue
+		}
+
+		event, found, err := chaincodeEventOfEnvelope(envelopeBytes)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %s", i, err.Error())
+		}
+		if found {
+			event.BlockNumber = block.Header.Number
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func chaincodeEventOfEnvelope(envelopeBytes []byte) (ChaincodeEvent, bool, error) {
+	var event ChaincodeEvent
+
+	envelope, err := utils.GetEnvelopeFromBlock(envelopeBytes)
+	if err != nil {
+		return event, false, err
+	}
+	payload, err := utils.GetPayload(envelope)
+	if err != nil {
+		return event, false, err
+	}
+	channelHeader, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return event, false, err
+	}
+	if channelHeader.Type != int32(common.HeaderType_ENDORSER_TRANSACTION) {
+		return event, false, nil
+	}
+
+	transaction, err := utils.GetTransaction(payload.Data)
+	if err != nil {
+		return event, false, err
+	}
+	for _, action := range transaction.Actions {
+		actionPayload, err := utils.GetChaincodeActionPayload(action.Payload)
+		if err != nil {
+			return event, false, err
+		}
+		responsePayload, err := utils.GetProposalResponsePayload(actionPayload.Action.ProposalResponsePayload)
+		if err != nil {
+			return event, false, err
+		}
+		chaincodeAction, err := utils.GetChaincodeAction(responsePayload.Extension)
+		if err != nil {
+			return event, false, err
+		}
+		if len(chaincodeAction.Events) == 0 {
+			continue
+		}
+		chaincodeEvent, err := utils.GetChaincodeEvents(chaincodeAction.Events)
+		if err != nil {
+			return event, false, err
+		}
+		if chaincodeEvent.EventName == "" {
+			continue
+		}
+
+		event.TxID = channelHeader.TxId
+		event.ChaincodeID = chaincodeEvent.ChaincodeId
+		event.EventName = chaincodeEvent.EventName
+		event.Payload = string(chaincodeEvent.Payload)
+		return event, true, nil
+	}
+	return event, false, nil
+}
+
+// ========================================== live event stream ==============================================================
+
+// streamSource follows the event streams of several chaincodes, reconnecting from the last seen block when one drops.
+// A transaction's event is emitted by the chaincode the client invoked, so every chaincode that can sit on top of ANCS
+// in a call chain has to be followed.
+type streamSource struct {
+	events chan ChaincodeEvent
+	done   chan struct{}
+}
+
+type streamConfig struct {
+	APIURL     string
+	UserName   string
+	OrgName    string
+	ChannelID  string
+	Chaincodes []string
+	EventName  string
+	Peer       string
+	StartBlock int64
+	RetryDelay time.Duration
+}
+
+func newStreamSource(config streamConfig) (EventSource, error) {
+	if len(config.Chaincodes) == 0 {
+		return nil, fmt.Errorf("no chaincodes to follow")
+	}
+
+	s := &streamSource{make(chan ChaincodeEvent), make(chan struct{})}
+	for _, chaincode := range config.Chaincodes {
+		go s.follow(config, chaincode)
+	}
+	return s, nil
+}
+
+func (s *streamSource) Next() (ChaincodeEvent, error) {
+	select {
+	case event := <-s.events:
+		return event, nil
+	case <-s.done:
+		return ChaincodeEvent{}, io.EOF
+	}
+}
+
+func (s *streamSource) Close() error {
+	close(s.done)
+	return nil
+}
+
+func (s *streamSource) follow(config streamConfig, chaincode string) {
+	startBlock := config.StartBlock
+	for {
+		lastBlock, err := s.stream(config, chaincode, startBlock)
+		if lastBlock >= 0 {
+			// blocks hold several transactions, a block is replayed and already reported jobs are answered with CONFLICT
+			startBlock = lastBlock
+		}
+		if err != nil {
+			fmt.Printf("event stream of %s dropped: %s\n", chaincode, err.Error())
+		}
+
+		select {
+		case <-s.done:
+			return
+		case <-time.After(config.RetryDelay):
+		}
+	}
+}
+
+// stream reads one connection, returning the last block number it saw or -1
+func (s *streamSource) stream(config streamConfig, chaincode string, startBlock int64) (int64, error) {
+	lastBlock := int64(-1)
+
+	query := url.Values{}
+	query.Set("eventName", config.EventName)
+	if startBlock >= 0 {
+		query.Set("startBlock", strconv.FormatInt(startBlock, 10))
+	}
+	if config.Peer != "" {
+		query.Set("peer", config.Peer)
+	}
+	streamURL := fmt.Sprintf("%s/queriesAPI/username/%s/orgName/%s/channels/%s/chaincodes/%s/events?%s",
+		config.APIURL, url.PathEscape(config.UserName), url.PathEscape(config.OrgName),
+		url.PathEscape(config.ChannelID), url.PathEscape(chaincode), query.Encode())
+
+	response, err := http.Get(streamURL)
+	if err != nil {
+		return lastBlock, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(response.Body)
+		return lastBlock, fmt.Errorf("%s: %s", response.Status, body)
+	}
+
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for {
+		event, err := nextEventLine(scanner)
+		if err != nil {
+			if err == io.EOF {
+				return lastBlock, nil
+			}
+			return lastBlock, err
+		}
+		lastBlock = int64(event.BlockNumber)
+
+		select {
+		case s.events <- event:
+		case <-s.done:
+			return lastBlock, nil
+		}
+	}
+}
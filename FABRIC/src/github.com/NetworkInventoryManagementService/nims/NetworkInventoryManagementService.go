@@ -0,0 +1,898 @@
+package nims
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// NetworkInventoryManagementChaincode example simple Chaincode implementation
+type NetworkInventoryManagementChaincode struct {
+}
+
+const chaincodeName = "NetworkInventoryManagementService"
+
+const chaincodeVersion = "1.0"
+
+// ============================================================================================================================
+// Structure of assets
+// ============================================================================================================================
+
+type DataCircuit struct {
+	CircuitID            string        `json:"CircuitID"`
+	CircuitNetwork       string        `json:"CircuitNetwork"`
+	ProviderID           string        `json:"ProviderID"`
+	IsConfigured         bool          `json:"IsConfigured"`
+	TotalBandwidth       nsc.Bandwidth `json:"TotalBandwidth"`
+	AllocatedBandwidth   nsc.Bandwidth `json:"AllowedBandwidth"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"unallowedBandwidth"`
+	CreatedOn            string        `json:"CreatedOn"`
+	// Attributes such as a site or path are compared when choosing diverse primary and backup circuits
+	Attributes map[string]string `json:"Attributes,omitempty"`
+	// Status is Up or Down, circuits added before it was tracked are Up
+	Status string `json:"Status,omitempty"`
+	// ServiceClass is the class set on the circuit itself, SellableBandwidth is TotalBandwidth times the oversubscription
+	// ratio of its class and left out when it is sold 1:1
+	ServiceClass      string        `json:"ServiceClass,omitempty"`
+	SellableBandwidth nsc.Bandwidth `json:"SellableBandwidth,omitempty"`
+	// ExcessBandwidth is the excess rate allocated on top of the committed rates, ExcessLimit caps it and is left out
+	// when it is the TotalBandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	ExcessLimit     nsc.Bandwidth `json:"ExcessLimit,omitempty"`
+	// Performance is the latency, jitter, loss and availability recorded with setCircuitPerformance
+	Performance *CircuitPerformance `json:"Performance,omitempty"`
+	// FaultyEquipment names the faulty or RMA equipment above the ports the circuit is terminated on
+	FaultyEquipment []string `json:"FaultyEquipment,omitempty"`
+}
+
+// BandwidthChange is the data of BandwidthAllocated and BandwidthReleased events
+type BandwidthChange struct {
+	CircuitID            string        `json:"CircuitID"`
+	OrderID              string        `json:"OrderID,omitempty"`
+	Bandwidth            nsc.Bandwidth `json:"Bandwidth"`
+	AllocatedBandwidth   nsc.Bandwidth `json:"AllocatedBandwidth"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	Reason               string        `json:"Reason,omitempty"`
+	// MaintenanceWindowID flags an allocation made inside an AtRisk maintenance window
+	MaintenanceWindowID string `json:"MaintenanceWindowID,omitempty"`
+	// ExcessBandwidth is the excess rate allocated or released with Bandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+}
+
+// ============================================================================================================================
+// Init - initialize the chaincode
+// ============================================================================================================================
+func (t *NetworkInventoryManagementChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	fmt.Println("DataCircuit Store Channel Is Starting Up")
+	funcName, args := stub.GetFunctionAndParameters()
+	var err error
+	txId := stub.GetTxID()
+
+	fmt.Println("  Init() is running")
+	fmt.Println("  Transaction ID: ", txId)
+	fmt.Println("  GetFunctionAndParameters() function: ", funcName)
+	fmt.Println("  GetFunctionAndParameters() args count: ", len(args))
+	fmt.Println("  GetFunctionAndParameters() args found: ", args)
+
+	// expecting 2 args for instantiate or upgrade
+	if len(args) != 2 {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Init(): Incorrect number of arguments. Expecting 2 but got %d", len(args)))
+	}
+	// this is a very simple test. let's write to the ledger and error out on any errors
+	// it's handy to read this right away to verify network is healthy if it wrote the correct value
+	err = stub.PutState(args[0], []byte(args[1]))
+	if err != nil {
+		return nsc.ErrorResponse(err) //self-test fail
+	}
+
+	fmt.Println("Ready for action") //self-test pass
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Invoke - Our entry point for Invocations
+// ============================================================================================================================
+func (t *NetworkInventoryManagementChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	fmt.Println(" ")
+	fmt.Println("starting invoke, for - " + function)
+
+	return nsc.InvokeFunction(stub, chaincodeName, chaincodeVersion, chaincodeFunctions, function, args)
+}
+
+// ============================================================================================================================
+// Query - legacy function
+// ============================================================================================================================
+func (t *NetworkInventoryManagementChaincode) Query(stub shim.ChaincodeStubInterface) pb.Response {
+	return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Unknown supported call - Query()"))
+}
+
+// ============================================================================================================================
+// Function Registry - see github.com/NetworkServiceCommon/nsc/registry.go. Inventory writes require the admin role, writes
+// on a circuit are further limited to the organization whose MSP ID is the circuit's ProviderID. Allocations and bookings
+// are made for operators through BPM, so they only run for transactions proposed to the chaincodes registered as OMS or
+// BPM, or by an admin.
+// ============================================================================================================================
+
+// the aliases of the chaincodes allocation and booking transactions are proposed to
+var orderChaincodes = []string{omsDependency, bpmDependency}
+
+var chaincodeFunctions = []nsc.ChaincodeFunction{
+	{
+		Name:         "addNewDataCircuit",
+		Description:  "Adds a DataCircuit with its total bandwidth to the inventory",
+		Arguments:    addNewDataCircuitArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      addNewDataCircuit,
+	},
+	{
+		Name:        "allocateDataCircuitBandwidth",
+		Description: "Allocates bandwidth on a DataCircuit, failing when it does not fit",
+		Arguments:   allocateDataCircuitBandwidthArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     allocateDataCircuitBandwidth,
+	},
+	{
+		Name:        "checkDataCircuitBandwidth",
+		Description: "Checks that allocateDataCircuitBandwidth would admit an allocation, without allocating",
+		Arguments:   allocateDataCircuitBandwidthArguments,
+		ReadOnly:    true,
+		Handler:     checkDataCircuitBandwidth,
+	},
+	{
+		Name:         "releaseDataCircuitBandwidth",
+		Description:  "Returns allocated bandwidth of a DataCircuit, all or part of an order's allocation, to its unallocated pool",
+		Arguments:    releaseDataCircuitBandwidthArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      releaseDataCircuitBandwidth,
+	},
+	{
+		Name:        "allocateProtectedBandwidth",
+		Description: "Allocates bandwidth for a protected order on a primary and a backup DataCircuit of different providers",
+		Arguments:   allocateProtectedBandwidthArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     allocateProtectedBandwidth,
+	},
+	{
+		Name:        "getProtectionGroup",
+		Description: "Returns the primary and backup DataCircuit of a protected order",
+		Arguments:   getProtectionGroupArguments,
+		ReadOnly:    true,
+		Handler:     getProtectionGroup,
+	},
+	{
+		Name:         "setDataCircuitAttribute",
+		Description:  "Sets or, without a Value, removes an attribute such as a site or path of a DataCircuit",
+		Arguments:    setDataCircuitAttributeArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setDataCircuitAttribute,
+	},
+	{
+		Name:         "setCircuitPerformance",
+		Description:  "Records the measured or contractual latency, jitter, loss and availability of a DataCircuit",
+		Arguments:    setCircuitPerformanceArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setCircuitPerformance,
+	},
+	{
+		Name:         "addSite",
+		Description:  "Adds a Site that DataCircuits can be linked between",
+		Arguments:    addSiteArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      addSite,
+	},
+	{
+		Name:        "getSite",
+		Description: "Returns a Site by its ID",
+		Arguments:   getSiteArguments,
+		ReadOnly:    true,
+		Handler:     getSite,
+	},
+	{
+		Name:         "setCircuitEndpoints",
+		Description:  "Links a DataCircuit between an A-end and a Z-end site, replacing its previous endpoints",
+		Arguments:    setCircuitEndpointsArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setCircuitEndpoints,
+	},
+	{
+		Name:        "getCircuitEndpoints",
+		Description: "Returns the A-end and Z-end endpoints of a DataCircuit",
+		Arguments:   getCircuitEndpointsArguments,
+		ReadOnly:    true,
+		Handler:     getCircuitEndpoints,
+	},
+	{
+		Name:        "getSiteNeighbours",
+		Description: "Returns the sites one DataCircuit away from a site, with the circuits linking them",
+		Arguments:   getSiteNeighboursArguments,
+		ReadOnly:    true,
+		Handler:     getSiteNeighbours,
+	},
+	{
+		Name:        "getCircuitsBetweenSites",
+		Description: "Returns the DataCircuits linking two sites in either direction",
+		Arguments:   getCircuitsBetweenSitesArguments,
+		ReadOnly:    true,
+		Handler:     getCircuitsBetweenSites,
+	},
+	{
+		Name:        "listCircuitLinks",
+		Description: "Lists the DataCircuits of a network that are linked between two sites, with their A-end and Z-end site",
+		Arguments:   listCircuitLinksArguments,
+		ReadOnly:    true,
+		Handler:     listCircuitLinks,
+	},
+	{
+		Name:         "addDevice",
+		Description:  "Adds a Device, optionally at a Site, to the physical inventory",
+		Arguments:    addDeviceArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      addDevice,
+	},
+	{
+		Name:        "getDevice",
+		Description: "Returns a Device with its cards and their ports",
+		Arguments:   getDeviceArguments,
+		ReadOnly:    true,
+		Handler:     getDevice,
+	},
+	{
+		Name:         "addCard",
+		Description:  "Adds a Card in a slot of a Device",
+		Arguments:    addCardArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      addCard,
+	},
+	{
+		Name:         "addPort",
+		Description:  "Adds a free Port with an optional speed to a Card",
+		Arguments:    addPortArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      addPort,
+	},
+	{
+		Name:         "setPortState",
+		Description:  "Marks a Port free, reserved or faulty, or a repaired Port that terminates a circuit in-use again",
+		Arguments:    setPortStateArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setPortState,
+	},
+	{
+		Name:         "setCircuitPort",
+		Description:  "Terminates the A-end or Z-end of a DataCircuit on a free or reserved Port, freeing its previous Port",
+		Arguments:    setCircuitPortArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setCircuitPort,
+	},
+	{
+		Name:         "clearCircuitPort",
+		Description:  "Removes the Port of a DataCircuit end and frees it",
+		Arguments:    clearCircuitPortArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      clearCircuitPort,
+	},
+	{
+		Name:        "listFreePorts",
+		Description: "Lists the free Ports of a Device, optionally of at least a speed",
+		Arguments:   listFreePortsArguments,
+		ReadOnly:    true,
+		Handler:     listFreePorts,
+	},
+	{
+		Name:        "getCircuitPorts",
+		Description: "Returns the site, device, card and port at both ends of a DataCircuit",
+		Arguments:   getCircuitPortsArguments,
+		ReadOnly:    true,
+		Handler:     getCircuitPorts,
+	},
+	{
+		Name:         "addEquipment",
+		Description:  "Adds in-stock Equipment by serial number with its model, vendor and warranty end",
+		Arguments:    addEquipmentArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      addEquipment,
+	},
+	{
+		Name:        "getEquipment",
+		Description: "Returns Equipment by its serial number",
+		Arguments:   equipmentArguments,
+		ReadOnly:    true,
+		Handler:     getEquipment,
+	},
+	{
+		Name:         "installEquipment",
+		Description:  "Installs in-stock Equipment as the chassis of a Device or in the slot of one of its cards",
+		Arguments:    installEquipmentArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      installEquipment,
+	},
+	{
+		Name:         "removeEquipment",
+		Description:  "Takes Equipment out of its position, clearing the DataCircuits it flagged",
+		Arguments:    equipmentArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      removeEquipment,
+	},
+	{
+		Name:         "setEquipmentStatus",
+		Description:  "Marks Equipment faulty, flagging the DataCircuits below it, installed again once repaired, or retired",
+		Arguments:    setEquipmentStatusArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setEquipmentStatus,
+	},
+	{
+		Name:        "listDeviceEquipment",
+		Description: "Lists the Equipment installed in a Device, the chassis first and then by slot",
+		Arguments:   listDeviceEquipmentArguments,
+		ReadOnly:    true,
+		Handler:     listDeviceEquipment,
+	},
+	{
+		Name:         "openRMA",
+		Description:  "Opens an RMA case for faulty Equipment, which is then on RMA",
+		Arguments:    openRMAArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      openRMA,
+	},
+	{
+		Name:        "getRMA",
+		Description: "Returns an RMA case with its swap record",
+		Arguments:   rmaArguments,
+		ReadOnly:    true,
+		Handler:     getRMA,
+	},
+	{
+		Name:         "swapEquipment",
+		Description:  "Replaces the Equipment of an open RMA case in its position with in-stock Equipment and records the swap",
+		Arguments:    swapEquipmentArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      swapEquipment,
+	},
+	{
+		Name:         "closeRMA",
+		Description:  "Closes an RMA case, returning the Equipment to stock or its position, or scrapping it",
+		Arguments:    closeRMAArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      closeRMA,
+	},
+	{
+		Name:         "setDataCircuitStatus",
+		Description:  "Marks a DataCircuit Up or Down, BPM failoverCircuit moves the allocations off a Down circuit",
+		Arguments:    setDataCircuitStatusArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setDataCircuitStatus,
+	},
+	{
+		Name:         "moveAllocations",
+		Description:  "Moves the allocations of orders from one DataCircuit to others, or onto their protection backups, all or none",
+		Arguments:    moveAllocationsArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      moveAllocations,
+	},
+	{
+		Name:         "scheduleMaintenanceWindow",
+		Description:  "Schedules an Outage or AtRisk maintenance window on a DataCircuit and notifies the operators holding bandwidth on it",
+		Arguments:    scheduleMaintenanceWindowArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      scheduleMaintenanceWindow,
+	},
+	{
+		Name:         "cancelMaintenanceWindow",
+		Description:  "Cancels a scheduled maintenance window and notifies the operators holding bandwidth on the circuit",
+		Arguments:    maintenanceWindowArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      cancelMaintenanceWindow,
+	},
+	{
+		Name:        "listMaintenanceWindows",
+		Description: "Lists the scheduled, and optionally the cancelled, maintenance windows of a DataCircuit",
+		Arguments:   listMaintenanceWindowsArguments,
+		ReadOnly:    true,
+		Handler:     listMaintenanceWindows,
+	},
+	{
+		Name:        "listOpenOutages",
+		Description: "Lists the Outage windows open now on the DataCircuits of a network, optionally of one provider",
+		Arguments:   listDataCircuitsArguments,
+		ReadOnly:    true,
+		Handler:     listOpenOutages,
+	},
+	{
+		Name:        "bookBandwidth",
+		Description: "Books bandwidth on a DataCircuit for a time interval if it fits next to the peak usage over that interval",
+		Arguments:   bookBandwidthArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     bookBandwidth,
+	},
+	{
+		Name:        "cancelBooking",
+		Description: "Cancels a booking that is not over yet",
+		Arguments:   cancelBookingArguments,
+		CalledBy:    orderChaincodes,
+		Handler:     cancelBooking,
+	},
+	{
+		Name:        "getCircuitUsage",
+		Description: "Returns the usage and availability of a DataCircuit over a time window with the bookings overlapping it",
+		Arguments:   getCircuitUsageArguments,
+		ReadOnly:    true,
+		Handler:     getCircuitUsage,
+	},
+	{
+		Name:         "setServiceClass",
+		Description:  "Adds a service class or changes its oversubscription ratio, refitting the circuits it applies to",
+		Arguments:    setServiceClassArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setServiceClass,
+	},
+	{
+		Name:         "setCircuitServiceClass",
+		Description:  "Sets or clears the service class of a DataCircuit, a circuit without one uses its network's",
+		Arguments:    setCircuitServiceClassArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setCircuitServiceClass,
+	},
+	{
+		Name:         "setNetworkServiceClass",
+		Description:  "Sets or clears the service class of the circuits of a network that have none of their own",
+		Arguments:    setNetworkServiceClassArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      setNetworkServiceClass,
+	},
+	{
+		Name:        "getCapacityReport",
+		Description: "Reports the physical and sold utilization of the circuits of a network, or of every circuit",
+		Arguments:   getCapacityReportArguments,
+		ReadOnly:    true,
+		Handler:     getCapacityReport,
+	},
+	{
+		Name:         "resizeDataCircuit",
+		Description:  "Changes the total bandwidth of a DataCircuit, never below its allocated bandwidth",
+		Arguments:    resizeDataCircuitArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      resizeDataCircuit,
+	},
+	{
+		Name:         "expireAllocations",
+		Description:  "Releases the allocations of a DataCircuit whose ExpiresOn has passed",
+		Arguments:    circuitIDArguments,
+		RequiredRole: nsc.AdminRole,
+		Handler:      expireAllocations,
+	},
+	{
+		Name:        "getCircuitAllocations",
+		Description: "Lists the per order allocations of a DataCircuit",
+		Arguments:   circuitIDArguments,
+		ReadOnly:    true,
+		Handler:     getCircuitAllocations,
+	},
+	{
+		Name:        "listDataCircuits",
+		Description: "Lists the DataCircuits of a network, optionally of one provider, in provider and CircuitID order",
+		Arguments:   listDataCircuitsArguments,
+		ReadOnly:    true,
+		Handler:     listDataCircuits,
+	},
+	{
+		Name:         "registerChaincodeDependency",
+		Description:  "Registers the chaincode name, version and channel behind a dependency alias",
+		Arguments:    nsc.DependencyArguments(knownDependencies),
+		RequiredRole: nsc.AdminRole,
+		Handler:      nsc.RegisterChaincodeDependency,
+	},
+	{
+		Name:        "getChaincodeDependencies",
+		Description: "Lists the registered chaincode dependencies",
+		Arguments:   nsc.ArgumentSchema{},
+		ReadOnly:    true,
+		Handler:     nsc.GetChaincodeDependencies,
+	},
+	{
+		Name:        "checkBandwithAllowanceOnCircuit",
+		Description: "Returns a DataCircuit with its allocated and unallocated bandwidth",
+		Arguments:   circuitIDArguments,
+		ReadOnly:    true,
+		Handler:     checkBandwithAllowanceOnCircuit,
+	},
+	{
+		Name:        "queryDataCircuitBandwidthDataById",
+		Description: "Runs a rich query for a DataCircuit by its ID",
+		Arguments:   circuitIDArguments,
+		ReadOnly:    true,
+		Handler:     queryDataCircuitBandwidthDataById,
+	},
+}
+
+// ========================================================
+// Argument Schemas - see github.com/NetworkServiceCommon/nsc/arguments.go
+// ========================================================
+var addNewDataCircuitArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ProviderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "TotalBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	// links the circuit between two sites as setCircuitEndpoints does, both or neither
+	{Name: "ASiteID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ZSiteID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// with an OrderID the allocation is recorded per order, ExpiresOn lets expireAllocations release it
+var allocateDataCircuitBandwidthArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OrderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	// the excess rate of the order's bandwidth profile, it needs an OrderID to be released
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+var circuitIDArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+func addNewDataCircuit(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	var err error
+	fmt.Println("starting addNewDataCircuit")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	fmt.Println(arguments)
+	//check if marble id already exists
+	dataCircuitAsBytes, err := stub.GetState(dataCircuitID)
+	if err != nil { //this seems to always succeed, even if key didn't exist
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "error in finding DataCircuit for - %s: %s", dataCircuitID, err.Error()))
+	}
+	if dataCircuitAsBytes != nil {
+		fmt.Println("This DataCircuit already exists - " + dataCircuitID)
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "This DataCircuit already exists - %s", dataCircuitID)) //all stop a marble by this id exists
+	}
+
+	dataCircuitObject, err := createDataCircuitObject(stub, arguments)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertCircuitProvider(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	if arguments.Has("ASiteID") != arguments.Has("ZSiteID") {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "a DataCircuit is linked between an ASiteID and a ZSiteID, name both or neither").
+			WithDetail("CircuitID", dataCircuitID))
+	}
+	if arguments.Has("ASiteID") {
+		_, err = linkDataCircuit(stub, dataCircuitID, arguments)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	fmt.Println(dataCircuitObject)
+	buff, err := dataCircuitToJSON(dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuit to json"))
+	}
+
+	err = stub.PutState(dataCircuitID, buff) //store marble with id as key
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	err = indexDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, dataCircuitID)
+	err = events.Add(nsc.EventCircuitAdded, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end addNewDataCircuit")
+	return events.Emit(stub)
+}
+
+func allocateDataCircuitBandwidth(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	var err error
+	fmt.Println("starting allocateDataCircuitBandwidth")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	toAllocateBandwidth := arguments.Bandwidth("Bandwidth")
+	fmt.Println(arguments)
+
+	dataCircuitObject, err := getDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	allocatedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	expiresOn := allocationExpiresOn(arguments)
+	maintenanceWindowID, err := admitAllocation(stub, &dataCircuitObject, arguments, allocatedOn, expiresOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	dataCircuitObject.AllocatedBandwidth = dataCircuitObject.AllocatedBandwidth + toAllocateBandwidth
+	dataCircuitObject.UnallocatedBandwidth = dataCircuitObject.UnallocatedBandwidth - toAllocateBandwidth
+
+	// allocations made for an order are tracked so they can be released or expire per order
+	if arguments.Has("OrderID") {
+		err = recordAllocation(stub, dataCircuitID, arguments.Str("OrderID"), arguments.Str("OperatorID"), toAllocateBandwidth, arguments.Bandwidth("ExcessBandwidth"), expiresOn)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	fmt.Println(dataCircuitObject)
+	buff, err := dataCircuitToJSON(dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuit to json"))
+	}
+
+	err = stub.PutState(dataCircuitID, buff) //store marble with id as key
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, arguments.Str("OrderID"))
+	change := newBandwidthChange(dataCircuitObject, arguments.Str("OrderID"), toAllocateBandwidth, "")
+	change.MaintenanceWindowID = maintenanceWindowID
+	change.ExcessBandwidth = arguments.Bandwidth("ExcessBandwidth")
+	err = events.Add(nsc.EventBandwidthAllocated, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end allocateDataCircuitBandwidth")
+	return events.Emit(stub)
+}
+
+// checkDataCircuitBandwidth runs the admission of allocateDataCircuitBandwidth without allocating, a caller checks every
+// allocation it is about to make before the first one writes
+func checkDataCircuitBandwidth(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	dataCircuitObject, err := getDataCircuit(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	checkedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	_, err = admitAllocation(stub, &dataCircuitObject, arguments, checkedOn, allocationExpiresOn(arguments))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return shim.Success(nil)
+}
+
+// admitAllocation checks that allocateDataCircuitBandwidth arguments fit the circuit: it must be up and out of an
+// Outage window, the bandwidth must fit its unallocated pool and booking calendar and the excess rate its excess limit.
+// The excess rate is added to the circuit, the ID of an AtRisk window the allocation starts in is returned.
+func admitAllocation(stub shim.ChaincodeStubInterface, dataCircuitObject *DataCircuit, arguments nsc.FunctionArgs, allocatedOn string, expiresOn string) (string, error) {
+	dataCircuitID := dataCircuitObject.CircuitID
+	toAllocateBandwidth := arguments.Bandwidth("Bandwidth")
+
+	err := assertCircuitUp(*dataCircuitObject)
+	if err != nil {
+		return "", err
+	}
+	maintenanceWindowID, err := checkMaintenance(stub, dataCircuitID, allocatedOn)
+	if err != nil {
+		return "", err
+	}
+
+	if toAllocateBandwidth > dataCircuitObject.UnallocatedBandwidth {
+		fmt.Println("allocateDataCircuitBandwidth() : Failed Cannot allocate Data Circuit Bandwidth for write : " + dataCircuitID)
+		return "", nsc.NewError(nsc.CodeInsufficientCapacity, "allocateDataCircuitBandwidth() : Failed Cannot allocate Data Circuit Bandwidth for write : %s", dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("RequestedBandwidth", toAllocateBandwidth).
+			WithDetail("UnallocatedBandwidth", dataCircuitObject.UnallocatedBandwidth)
+	}
+	if arguments.Has("ExcessBandwidth") && !arguments.Has("OrderID") {
+		return "", nsc.NewError(nsc.CodeInvalidArgument, "allocateDataCircuitBandwidth() : ExcessBandwidth is only allocated for an order").
+			WithDetail("CircuitID", dataCircuitID)
+	}
+	err = allocateExcess(dataCircuitObject, arguments.Bandwidth("ExcessBandwidth"))
+	if err != nil {
+		return "", err
+	}
+	return maintenanceWindowID, assertFitsCalendar(stub, *dataCircuitObject, toAllocateBandwidth, allocatedOn, expiresOn)
+}
+
+// allocationExpiresOn returns the ExpiresOn of an allocation, only an allocation recorded for an order expires, any other
+// holds its bandwidth for good
+func allocationExpiresOn(arguments nsc.FunctionArgs) string {
+	if arguments.Has("OrderID") {
+		return arguments.Str("ExpiresOn")
+	}
+	return ""
+}
+
+// CreateAssetObject creates an asset from validated addNewDataCircuit arguments
+func createDataCircuitObject(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) (DataCircuit, error) {
+	var myDataCircuit DataCircuit
+
+	ttlBandwidth := arguments.Bandwidth("TotalBandwidth")
+
+	createdOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return myDataCircuit, err
+	}
+
+	myDataCircuit = DataCircuit{arguments.Str("CircuitID"), arguments.Str("CircuitNetwork"), arguments.Str("ProviderID"), false, ttlBandwidth, 0, ttlBandwidth, createdOn, nil, circuitUp, "", 0, 0, 0, nil, nil}
+
+	// a circuit added to a network with a service class is sold at its ratio
+	class, err := resolveServiceClass(stub, myDataCircuit)
+	if err != nil {
+		return myDataCircuit, err
+	}
+	err = applyServiceClass(&myDataCircuit, class, ttlBandwidth)
+	return myDataCircuit, err
+}
+
+// getDataCircuit reads a DataCircuit, NOT_FOUND when it was never added
+func getDataCircuit(stub shim.ChaincodeStubInterface, dataCircuitID string) (DataCircuit, error) {
+	var dataCircuitObject DataCircuit
+
+	dataCircuitAsBytes, err := stub.GetState(dataCircuitID)
+	if err != nil { //this seems to always succeed, even if key didn't exist
+		return dataCircuitObject, nsc.NewError(nsc.CodeInternal, "error in finding DataCircuit for - %s: %s", dataCircuitID, err.Error())
+	}
+	if dataCircuitAsBytes == nil {
+		fmt.Println("This DataCircuit does not exists - " + dataCircuitID)
+		return dataCircuitObject, nsc.NewError(nsc.CodeNotFound, "This DataCircuit does not exists - %s", dataCircuitID).WithDetail("CircuitID", dataCircuitID)
+	}
+
+	dataCircuitObject, err = jsonToDataCircuit(dataCircuitAsBytes)
+	if err != nil {
+		return dataCircuitObject, nsc.NewError(nsc.CodeInternal, "unable to read DataCircuit %s: %s", dataCircuitID, err.Error())
+	}
+	return dataCircuitObject, nil
+}
+
+// getProvidedDataCircuit reads a DataCircuit that is about to be changed, FORBIDDEN unless the invoker belongs to the
+// circuit's provider
+func getProvidedDataCircuit(stub shim.ChaincodeStubInterface, dataCircuitID string) (DataCircuit, error) {
+	dataCircuitObject, err := getDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return dataCircuitObject, err
+	}
+	return dataCircuitObject, assertCircuitProvider(stub, dataCircuitObject)
+}
+
+// assertCircuitProvider fails unless the MSP ID of the transaction creator is the ProviderID of the circuit, a
+// provider's admin cannot change the circuits of another provider
+func assertCircuitProvider(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit) error {
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return nsc.NewError(nsc.CodeForbidden, "unable to read the invoker identity: %s", err.Error())
+	}
+	if mspID != dataCircuitObject.ProviderID {
+		return nsc.NewError(nsc.CodeForbidden, "DataCircuit %s is provided by %s, %s cannot change it", dataCircuitObject.CircuitID, dataCircuitObject.ProviderID, mspID).
+			WithDetail("CircuitID", dataCircuitObject.CircuitID).
+			WithDetail("ProviderID", dataCircuitObject.ProviderID)
+	}
+	return nil
+}
+
+func dataCircuitToJSON(eval DataCircuit) ([]byte, error) {
+
+	djson, err := json.Marshal(eval)
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	return djson, nil
+}
+
+func jsonToDataCircuit(data []byte) (DataCircuit, error) {
+
+	eval := DataCircuit{}
+	err := json.Unmarshal([]byte(data), &eval)
+	if err != nil {
+		fmt.Println("Unmarshal failed : ", err)
+		return eval, err
+	}
+
+	return eval, nil
+}
+
+// getTxTimestamp formats the proposal timestamp so every endorser records the same value
+func getTxTimestamp(stub shim.ChaincodeStubInterface) (string, error) {
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(txTime.Seconds, int64(txTime.Nanos)).UTC().Format("20060102150405"), nil
+}
+
+// query callback representing the query of a chaincode
+func checkBandwithAllowanceOnCircuit(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("sarting the checkBandwithAllowanceOnCircuit() with the args: ")
+	fmt.Println(arguments)
+	fmt.Println("========================")
+
+	circuitID := arguments.Str("CircuitID")
+
+	// Get the state from the ledger
+	circuitbytes, err := stub.GetState(circuitID)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "Failed to get state for %s", circuitID))
+	}
+
+	if circuitbytes == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "Nil data for %s", circuitID).WithDetail("CircuitID", circuitID))
+	}
+
+	jsonResp := "{\"CircuitID\":\"" + circuitID + "\",\"data\":\"" + string(circuitbytes) + "\"}"
+	fmt.Printf("Query Response:%s\n", jsonResp)
+
+	// respond back to BPm for this
+
+	return shim.Success(circuitbytes)
+}
+
+// very important as it is required by the Answer chaincode to query
+func queryDataCircuitBandwidthDataById(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	CircuitID := arguments.Str("CircuitID")
+
+	queryString := fmt.Sprintf("{\"selector\":{\"CircuitID\":\"%s\"}}", CircuitID)
+
+	queryResults, err := getQueryResultForQueryString(stub, queryString)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return shim.Success(queryResults)
+}
+
+func getQueryResultForQueryString(stub shim.ChaincodeStubInterface, queryString string) ([]byte, error) {
+
+	fmt.Printf("- getQueryResultForQueryString queryString:\n%s\n", queryString)
+
+	resultsIterator, err := stub.GetQueryResult(queryString)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	// buffer is a JSON array containing QueryRecords
+	var buffer bytes.Buffer
+	buffer.WriteString("[")
+
+	bArrayMemberAlreadyWritten := false
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		// Add a comma before array members, suppress it for the first array member
+		if bArrayMemberAlreadyWritten == true {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("{\"Key\":")
+		buffer.WriteString("\"")
+		buffer.WriteString(queryResponse.Key)
+		buffer.WriteString("\"")
+
+		buffer.WriteString(", \"Record\":")
+		// Record is a JSON object, so we write as-is
+		buffer.WriteString(string(queryResponse.Value))
+		buffer.WriteString("}")
+		bArrayMemberAlreadyWritten = true
+	}
+	buffer.WriteString("]")
+
+	fmt.Printf("- getQueryResultForQueryString queryResult:\n%s\n", buffer.String())
+
+	return buffer.Bytes(), nil
+}
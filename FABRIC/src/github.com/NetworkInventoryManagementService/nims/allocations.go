@@ -0,0 +1,415 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Allocations - bandwidth allocated for an order is recorded under "Allocation"[CircuitID, OrderID] next to the circuit
+// totals, so it can be released per order and expire on its ExpiresOn. Releasing, resizing and expiring are what frees
+// capacity for the BPM waitlist, its processWaitlist is run after any of them.
+// ============================================================================================================================
+
+const allocationObjectType = "Allocation"
+
+// release reasons carried by BandwidthReleased records
+const (
+	releaseRequested = "Released"
+	releaseExpired   = "Expired"
+)
+
+type Allocation struct {
+	CircuitID   string        `json:"CircuitID"`
+	OrderID     string        `json:"OrderID"`
+	Bandwidth   nsc.Bandwidth `json:"Bandwidth"`
+	AllocatedOn string        `json:"AllocatedOn"`
+	ExpiresOn   string        `json:"ExpiresOn,omitempty"`
+	// OperatorID is notified of maintenance on the circuit
+	OperatorID string `json:"OperatorID,omitempty"`
+	// ExcessBandwidth is the excess rate held next to the committed Bandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+}
+
+// CircuitResize is the data of CircuitResized events
+type CircuitResize struct {
+	CircuitID              string        `json:"CircuitID"`
+	PreviousTotalBandwidth nsc.Bandwidth `json:"PreviousTotalBandwidth"`
+	TotalBandwidth         nsc.Bandwidth `json:"TotalBandwidth"`
+	AllocatedBandwidth     nsc.Bandwidth `json:"AllocatedBandwidth"`
+	UnallocatedBandwidth   nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	// set when the circuit is oversubscribed or its service class changed
+	ServiceClass              string        `json:"ServiceClass,omitempty"`
+	PreviousSellableBandwidth nsc.Bandwidth `json:"PreviousSellableBandwidth,omitempty"`
+	SellableBandwidth         nsc.Bandwidth `json:"SellableBandwidth,omitempty"`
+}
+
+// Bandwidth defaults to the whole allocation of OrderID, it is required when no OrderID is given and can then only release
+// bandwidth no allocation records
+var releaseDataCircuitBandwidthArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "OrderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var resizeDataCircuitArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "TotalBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+func releaseDataCircuitBandwidth(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting releaseDataCircuitBandwidth")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	orderID := arguments.Str("OrderID")
+	fmt.Println(arguments)
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	// allocations made before they were recorded per order are released by amount only
+	var allocation *Allocation
+	if orderID != "" {
+		allocation, err = getAllocation(stub, dataCircuitID, orderID)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	var toReleaseBandwidth nsc.Bandwidth
+	switch {
+	case arguments.Has("Bandwidth"):
+		toReleaseBandwidth = arguments.Bandwidth("Bandwidth")
+	case allocation != nil:
+		toReleaseBandwidth = allocation.Bandwidth
+	default:
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "releaseDataCircuitBandwidth() : Bandwidth is required without a recorded allocation to release").
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("OrderID", orderID))
+	}
+
+	if allocation != nil && toReleaseBandwidth > allocation.Bandwidth {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "releaseDataCircuitBandwidth() : cannot release more than the allocation of order %s", orderID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("OrderID", orderID).
+			WithDetail("RequestedBandwidth", toReleaseBandwidth).
+			WithDetail("AllocationBandwidth", allocation.Bandwidth))
+	}
+	if allocation == nil {
+		// without a recorded allocation only bandwidth no record accounts for can go, or the records would go stale
+		untracked, err := untrackedBandwidth(stub, dataCircuitObject)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if toReleaseBandwidth > untracked {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "releaseDataCircuitBandwidth() : only %s of %s is held without a recorded allocation, release the rest by OrderID", untracked, dataCircuitID).
+				WithDetail("CircuitID", dataCircuitID).
+				WithDetail("RequestedBandwidth", toReleaseBandwidth).
+				WithDetail("UntrackedBandwidth", untracked))
+		}
+	}
+	if toReleaseBandwidth > dataCircuitObject.AllocatedBandwidth {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "releaseDataCircuitBandwidth() : cannot release more than the allocated bandwidth of %s", dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("RequestedBandwidth", toReleaseBandwidth).
+			WithDetail("AllocatedBandwidth", dataCircuitObject.AllocatedBandwidth))
+	}
+
+	releasedExcess := nsc.Bandwidth(0)
+	if allocation != nil {
+		allocation.Bandwidth = allocation.Bandwidth - toReleaseBandwidth
+		releasedExcess = releaseExcess(&dataCircuitObject, allocation)
+		err = putAllocation(stub, *allocation)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	dataCircuitObject.AllocatedBandwidth = dataCircuitObject.AllocatedBandwidth - toReleaseBandwidth
+	dataCircuitObject.UnallocatedBandwidth = dataCircuitObject.UnallocatedBandwidth + toReleaseBandwidth
+
+	err = putDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	change := newBandwidthChange(dataCircuitObject, orderID, toReleaseBandwidth, releaseRequested)
+	change.ExcessBandwidth = releasedExcess
+	err = events.Add(nsc.EventBandwidthReleased, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end releaseDataCircuitBandwidth")
+	return events.Emit(stub)
+}
+
+func resizeDataCircuit(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting resizeDataCircuit")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	totalBandwidth := arguments.Bandwidth("TotalBandwidth")
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	resize := CircuitResize{
+		CircuitID:                 dataCircuitID,
+		PreviousTotalBandwidth:    dataCircuitObject.TotalBandwidth,
+		TotalBandwidth:            totalBandwidth,
+		PreviousSellableBandwidth: sellableBandwidth(dataCircuitObject),
+	}
+
+	// an oversubscribed circuit keeps its ratio, it cannot shrink below what it has sold
+	class, err := resolveServiceClass(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = applyServiceClass(&dataCircuitObject, class, totalBandwidth)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	// the bookings still to come must fit the new size as well
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	slots, _, err := usageProfile(stub, dataCircuitObject, now, calendarEnd)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if peak := peakUsage(slots); sellableBandwidth(dataCircuitObject) < peak {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "resizeDataCircuit() : %s cannot shrink below its booked peak usage", dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("TotalBandwidth", totalBandwidth).
+			WithDetail("PeakUsage", peak))
+	}
+
+	resize.AllocatedBandwidth = dataCircuitObject.AllocatedBandwidth
+	resize.UnallocatedBandwidth = dataCircuitObject.UnallocatedBandwidth
+	if class != nil {
+		resize.ServiceClass = class.ClassName
+		resize.SellableBandwidth = sellableBandwidth(dataCircuitObject)
+	} else {
+		resize.PreviousSellableBandwidth = 0
+	}
+
+	err = putDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, dataCircuitID)
+	err = events.Add(nsc.EventCircuitResized, resize)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end resizeDataCircuit")
+	return events.Emit(stub)
+}
+
+// expireAllocations releases every allocation of the circuit whose ExpiresOn is not after the transaction timestamp
+func expireAllocations(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting expireAllocations")
+
+	dataCircuitID := arguments.Str("CircuitID")
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	allocations, err := getAllocations(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, dataCircuitID)
+	for _, allocation := range allocations {
+		if allocation.ExpiresOn == "" || allocation.ExpiresOn > now || allocation.Bandwidth == 0 {
+			continue
+		}
+		fmt.Println("expiring allocation of order " + allocation.OrderID)
+
+		// never release more than the circuit holds, its totals must not go negative
+		released := allocation.Bandwidth
+		if released > dataCircuitObject.AllocatedBandwidth {
+			released = dataCircuitObject.AllocatedBandwidth
+		}
+		allocation.Bandwidth = 0
+		releasedExcess := releaseExcess(&dataCircuitObject, &allocation)
+		err = putAllocation(stub, allocation)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+
+		dataCircuitObject.AllocatedBandwidth = dataCircuitObject.AllocatedBandwidth - released
+		dataCircuitObject.UnallocatedBandwidth = dataCircuitObject.UnallocatedBandwidth + released
+		change := newBandwidthChange(dataCircuitObject, allocation.OrderID, released, releaseExpired)
+		change.ExcessBandwidth = releasedExcess
+		err = events.Add(nsc.EventBandwidthReleased, change)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	if len(events.Records) > 0 {
+		err = putDataCircuit(stub, dataCircuitObject)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	fmt.Println("- end expireAllocations")
+	return events.Emit(stub)
+}
+
+func getCircuitAllocations(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	allocations, err := getAllocations(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	allocationsAsBytes, err := json.Marshal(allocations)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert allocations to json"))
+	}
+	return shim.Success(allocationsAsBytes)
+}
+
+// recordAllocation stores the allocation of an order, CONFLICT when the order already holds bandwidth on the circuit
+func recordAllocation(stub shim.ChaincodeStubInterface, dataCircuitID string, orderID string, operatorID string, bandwidth nsc.Bandwidth, excess nsc.Bandwidth, expiresOn string) error {
+	existing, err := getAllocation(stub, dataCircuitID, orderID)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Bandwidth > 0 {
+		return nsc.NewError(nsc.CodeConflict, "Order %s already holds bandwidth on %s", orderID, dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("OrderID", orderID)
+	}
+
+	allocatedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return err
+	}
+	return putAllocation(stub, Allocation{dataCircuitID, orderID, bandwidth, allocatedOn, expiresOn, operatorID, excess})
+}
+
+// getAllocation reads the allocation of an order, nil when none was recorded
+func getAllocation(stub shim.ChaincodeStubInterface, dataCircuitID string, orderID string) (*Allocation, error) {
+	allocationKey, err := stub.CreateCompositeKey(allocationObjectType, []string{dataCircuitID, orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	allocationAsBytes, err := stub.GetState(allocationKey)
+	if err != nil {
+		return nil, err
+	}
+	if allocationAsBytes == nil {
+		return nil, nil
+	}
+
+	var allocation Allocation
+	err = json.Unmarshal(allocationAsBytes, &allocation)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInternal, "unable to read allocation of order %s: %s", orderID, err.Error())
+	}
+	return &allocation, nil
+}
+
+// putAllocation stores an allocation, a fully released one is deleted
+func putAllocation(stub shim.ChaincodeStubInterface, allocation Allocation) error {
+	allocationKey, err := stub.CreateCompositeKey(allocationObjectType, []string{allocation.CircuitID, allocation.OrderID})
+	if err != nil {
+		return err
+	}
+	if allocation.Bandwidth == 0 {
+		return stub.DelState(allocationKey)
+	}
+
+	buff, err := json.Marshal(allocation)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert Allocation to json")
+	}
+	return stub.PutState(allocationKey, buff)
+}
+
+// getAllocations lists the allocations of a circuit in OrderID order
+func getAllocations(stub shim.ChaincodeStubInterface, dataCircuitID string) ([]Allocation, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(allocationObjectType, []string{dataCircuitID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	allocations := []Allocation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var allocation Allocation
+		err = json.Unmarshal(queryResponse.Value, &allocation)
+		if err != nil {
+			return nil, nsc.NewError(nsc.CodeInternal, "unable to read allocation %s: %s", queryResponse.Key, err.Error())
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, nil
+}
+
+// untrackedBandwidth is the part of a circuit's AllocatedBandwidth that no Allocation records, the bandwidth of
+// allocations made before they were recorded per order
+func untrackedBandwidth(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit) (nsc.Bandwidth, error) {
+	allocations, err := getAllocations(stub, dataCircuitObject.CircuitID)
+	if err != nil {
+		return 0, err
+	}
+	untracked := dataCircuitObject.AllocatedBandwidth
+	for _, allocation := range allocations {
+		untracked = untracked - allocation.Bandwidth
+	}
+	if untracked < 0 {
+		return 0, nil
+	}
+	return untracked, nil
+}
+
+func putDataCircuit(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit) error {
+	buff, err := dataCircuitToJSON(dataCircuitObject)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuit to json")
+	}
+	return stub.PutState(dataCircuitObject.CircuitID, buff)
+}
+
+// newBandwidthChange is the event record of an allocation or release
+func newBandwidthChange(dataCircuitObject DataCircuit, orderID string, bandwidth nsc.Bandwidth, reason string) BandwidthChange {
+	return BandwidthChange{
+		CircuitID:            dataCircuitObject.CircuitID,
+		OrderID:              orderID,
+		Bandwidth:            bandwidth,
+		AllocatedBandwidth:   dataCircuitObject.AllocatedBandwidth,
+		UnallocatedBandwidth: dataCircuitObject.UnallocatedBandwidth,
+		Reason:               reason,
+	}
+}
@@ -0,0 +1,73 @@
+package nims_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NetworkServiceSimulator"
+)
+
+func seedAllocations(t *testing.T, s *simulator.Simulator, allocations map[string]string) {
+	t.Helper()
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+	for orderID, expiresOn := range allocations {
+		result := s.Invoke(simulator.NIMS, provider, "allocateDataCircuitBandwidth", "C1", "30M", orderID, expiresOn)
+		if !result.OK() {
+			t.Fatal(result.Error())
+		}
+	}
+}
+
+func TestReleaseWithoutOrderKeepsRecordsCurrent(t *testing.T) {
+	s := simulator.NewForTest(t)
+	seedAllocations(t, s, map[string]string{"O1": ""})
+	// an allocation made without an OrderID is held by no record
+	if result := s.Invoke(simulator.NIMS, provider, "allocateDataCircuitBandwidth", "C1", "10M"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+
+	expectCode(t, s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "20M"), "CONFLICT")
+	if result := s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "10M"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if err := s.ExpectBandwidth("C1", 30*simulator.Mbps, 70*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+
+	expectCode(t, s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "1M"), "CONFLICT")
+	if result := s.Invoke(simulator.NIMS, provider, "releaseDataCircuitBandwidth", "C1", "", "O1"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExpireAllocationsNeverGoesNegative(t *testing.T) {
+	s := simulator.NewForTest(t)
+	// the simulator's transactions are timestamped 20231114221320, both allocations have expired
+	seedAllocations(t, s, map[string]string{"O1": "20230101000000", "O2": "20230101000000"})
+
+	// circuit totals that fell behind the records, as left by an earlier release by amount
+	stub := s.Stub(simulator.NIMS)
+	circuit, err := s.Circuit("C1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	circuit.AllocatedBandwidth = 40 * simulator.Mbps
+	circuit.UnallocatedBandwidth = 60 * simulator.Mbps
+	stub.State["C1"], _ = json.Marshal(circuit)
+
+	result := s.Invoke(simulator.NIMS, provider, "expireAllocations", "C1")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if released := result.Records("BandwidthReleased"); len(released) != 2 {
+		t.Fatalf("expected 2 BandwidthReleased records, got %d", len(released))
+	}
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+}
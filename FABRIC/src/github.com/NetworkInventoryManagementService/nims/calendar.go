@@ -0,0 +1,384 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Bandwidth Calendar - a booking holds bandwidth on a DataCircuit from StartsOn up to, not including, EndsOn and is kept
+// under "Booking"[CircuitID, BookingID]. AllocatedBandwidth stays the bandwidth of the standing allocations, bookings only
+// live in the calendar. The usage of a circuit at a time is its standing allocations that have not expired by then plus
+// the bookings active then, and anything that holds bandwidth, a booking or an allocation, must fit under the sellable
+// bandwidth of the circuit at the peak of that usage over its whole interval.
+// ============================================================================================================================
+
+const bookingObjectType = "Booking"
+
+// booking states
+const (
+	bookingBooked    = "Booked"
+	bookingCancelled = "Cancelled"
+)
+
+// the end of an interval that has none, e.g. an allocation without ExpiresOn
+const calendarEnd = "99991231235959"
+
+var bookBandwidthArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "BookingID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "StartsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "EndsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "OrderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var cancelBookingArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "BookingID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var getCircuitUsageArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "StartsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "EndsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+}
+
+type Booking struct {
+	CircuitID  string        `json:"CircuitID"`
+	BookingID  string        `json:"BookingID"`
+	OrderID    string        `json:"OrderID,omitempty"`
+	OperatorID string        `json:"OperatorID,omitempty"`
+	Bandwidth  nsc.Bandwidth `json:"Bandwidth"`
+	StartsOn   string        `json:"StartsOn"`
+	EndsOn     string        `json:"EndsOn"`
+	Status     string        `json:"Status"`
+	BookedOn   string        `json:"BookedOn"`
+	// MaintenanceWindowID flags a booking that starts inside an AtRisk maintenance window
+	MaintenanceWindowID string `json:"MaintenanceWindowID,omitempty"`
+	CancelledOn         string `json:"CancelledOn,omitempty"`
+}
+
+// UsageSlot is an interval over which the usage of a circuit does not change
+type UsageSlot struct {
+	StartsOn  string        `json:"StartsOn"`
+	EndsOn    string        `json:"EndsOn"`
+	Usage     nsc.Bandwidth `json:"Usage"`
+	Available nsc.Bandwidth `json:"Available"`
+}
+
+// CircuitUsage is the result of getCircuitUsage, Available is what can still be booked for the whole window
+type CircuitUsage struct {
+	CircuitID      string        `json:"CircuitID"`
+	StartsOn       string        `json:"StartsOn"`
+	EndsOn         string        `json:"EndsOn"`
+	TotalBandwidth nsc.Bandwidth `json:"TotalBandwidth"`
+	// SellableBandwidth is what usage is counted against, TotalBandwidth unless the circuit is oversubscribed
+	SellableBandwidth nsc.Bandwidth `json:"SellableBandwidth"`
+	PeakUsage         nsc.Bandwidth `json:"PeakUsage"`
+	Available         nsc.Bandwidth `json:"Available"`
+	Slots             []UsageSlot   `json:"Slots"`
+	Bookings          []Booking     `json:"Bookings"`
+}
+
+func bookBandwidth(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting bookBandwidth")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	fmt.Println(arguments)
+
+	dataCircuitObject, err := getDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertCircuitUp(dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	booking := Booking{
+		CircuitID:  dataCircuitID,
+		BookingID:  arguments.Str("BookingID"),
+		OrderID:    arguments.Str("OrderID"),
+		OperatorID: arguments.Str("OperatorID"),
+		Bandwidth:  arguments.Bandwidth("Bandwidth"),
+		StartsOn:   arguments.Str("StartsOn"),
+		EndsOn:     arguments.Str("EndsOn"),
+		Status:     bookingBooked,
+		BookedOn:   now,
+	}
+	if booking.EndsOn <= booking.StartsOn || booking.EndsOn <= now {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "a booking must end after it starts and after now").
+			WithDetail("StartsOn", booking.StartsOn).
+			WithDetail("EndsOn", booking.EndsOn))
+	}
+
+	existing, err := getBooking(stub, dataCircuitID, booking.BookingID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if existing != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Booking %s of %s already exists", booking.BookingID, dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("BookingID", booking.BookingID))
+	}
+
+	booking.MaintenanceWindowID, err = checkMaintenance(stub, dataCircuitID, booking.StartsOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertFitsCalendar(stub, dataCircuitObject, booking.Bandwidth, booking.StartsOn, booking.EndsOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	err = putBooking(stub, booking)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, booking.BookingID)
+	err = events.Add(nsc.EventBandwidthBooked, booking)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end bookBandwidth")
+	return events.Emit(stub)
+}
+
+func cancelBooking(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting cancelBooking")
+
+	booking, err := getBooking(stub, arguments.Str("CircuitID"), arguments.Str("BookingID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if booking == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "Booking %s of %s does not exist", arguments.Str("BookingID"), arguments.Str("CircuitID")).
+			WithDetail("CircuitID", arguments.Str("CircuitID")).
+			WithDetail("BookingID", arguments.Str("BookingID")))
+	}
+
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if booking.Status != bookingBooked || booking.EndsOn <= now {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Booking %s of %s is cancelled or over", booking.BookingID, booking.CircuitID).
+			WithDetail("BookingID", booking.BookingID).
+			WithDetail("Status", booking.Status).
+			WithDetail("EndsOn", booking.EndsOn))
+	}
+
+	booking.Status = bookingCancelled
+	booking.CancelledOn = now
+	err = putBooking(stub, *booking)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, booking.BookingID)
+	err = events.Add(nsc.EventBookingCancelled, booking)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end cancelBooking")
+	return events.Emit(stub)
+}
+
+// getCircuitUsage returns the usage of a circuit over a time window, slot by slot, with the bookings overlapping it
+func getCircuitUsage(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	startsOn, endsOn := arguments.Str("StartsOn"), arguments.Str("EndsOn")
+	if endsOn <= startsOn {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "the window must end after it starts").
+			WithDetail("StartsOn", startsOn).
+			WithDetail("EndsOn", endsOn))
+	}
+
+	dataCircuitObject, err := getDataCircuit(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	slots, bookings, err := usageProfile(stub, dataCircuitObject, startsOn, endsOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	usage := CircuitUsage{
+		CircuitID:         dataCircuitObject.CircuitID,
+		StartsOn:          startsOn,
+		EndsOn:            endsOn,
+		TotalBandwidth:    dataCircuitObject.TotalBandwidth,
+		SellableBandwidth: sellableBandwidth(dataCircuitObject),
+		PeakUsage:         peakUsage(slots),
+		Slots:             slots,
+		Bookings:          bookings,
+	}
+	usage.Available = usage.SellableBandwidth - usage.PeakUsage
+
+	usageAsBytes, err := json.Marshal(usage)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CircuitUsage to json"))
+	}
+	return shim.Success(usageAsBytes)
+}
+
+// assertFitsCalendar refuses bandwidth that would take the circuit over its sellable bandwidth anywhere between startsOn
+// and endsOn, an empty endsOn holds the bandwidth for good
+func assertFitsCalendar(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit, bandwidth nsc.Bandwidth, startsOn string, endsOn string) error {
+	if endsOn == "" {
+		endsOn = calendarEnd
+	}
+
+	slots, _, err := usageProfile(stub, dataCircuitObject, startsOn, endsOn)
+	if err != nil {
+		return err
+	}
+	peak := peakUsage(slots)
+	if peak+bandwidth > sellableBandwidth(dataCircuitObject) {
+		return nsc.NewError(nsc.CodeInsufficientCapacity, "%s does not fit on %s next to its peak usage of %s between %s and %s", bandwidth, dataCircuitObject.CircuitID, peak, startsOn, endsOn).
+			WithDetail("CircuitID", dataCircuitObject.CircuitID).
+			WithDetail("RequestedBandwidth", bandwidth).
+			WithDetail("PeakUsage", peak).
+			WithDetail("SellableBandwidth", sellableBandwidth(dataCircuitObject))
+	}
+	return nil
+}
+
+// usageProfile splits the window at every booking start or end and allocation expiry inside it and returns the usage
+// of each slot, with the bookings that overlap the window
+func usageProfile(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit, startsOn string, endsOn string) ([]UsageSlot, []Booking, error) {
+	allocations, err := getAllocations(stub, dataCircuitObject.CircuitID)
+	if err != nil {
+		return nil, nil, err
+	}
+	allBookings, err := getBookings(stub, dataCircuitObject.CircuitID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bookings := []Booking{}
+	boundaries := []string{startsOn, endsOn}
+	for _, booking := range allBookings {
+		if booking.Status != bookingBooked || booking.EndsOn <= startsOn || booking.StartsOn >= endsOn {
+			continue
+		}
+		bookings = append(bookings, booking)
+		boundaries = append(boundaries, booking.StartsOn, booking.EndsOn)
+	}
+	for _, allocation := range allocations {
+		if allocation.ExpiresOn != "" {
+			boundaries = append(boundaries, allocation.ExpiresOn)
+		}
+	}
+
+	sort.Strings(boundaries)
+	slots := []UsageSlot{}
+	for i, boundary := range boundaries {
+		if boundary < startsOn || boundary >= endsOn || (i > 0 && boundaries[i-1] == boundary) {
+			continue
+		}
+
+		// allocations recorded before they were tracked per order have no ExpiresOn and stay in the standing usage
+		usage := dataCircuitObject.AllocatedBandwidth
+		for _, allocation := range allocations {
+			if allocation.ExpiresOn != "" && allocation.ExpiresOn <= boundary {
+				usage = usage - allocation.Bandwidth
+			}
+		}
+		for _, booking := range bookings {
+			if booking.StartsOn <= boundary && boundary < booking.EndsOn {
+				usage = usage + booking.Bandwidth
+			}
+		}
+
+		if len(slots) > 0 {
+			slots[len(slots)-1].EndsOn = boundary
+		}
+		slots = append(slots, UsageSlot{boundary, endsOn, usage, sellableBandwidth(dataCircuitObject) - usage})
+	}
+	return slots, bookings, nil
+}
+
+func peakUsage(slots []UsageSlot) nsc.Bandwidth {
+	peak := nsc.Bandwidth(0)
+	for _, slot := range slots {
+		if slot.Usage > peak {
+			peak = slot.Usage
+		}
+	}
+	return peak
+}
+
+// getBooking reads a booking, nil when it does not exist
+func getBooking(stub shim.ChaincodeStubInterface, dataCircuitID string, bookingID string) (*Booking, error) {
+	bookingKey, err := stub.CreateCompositeKey(bookingObjectType, []string{dataCircuitID, bookingID})
+	if err != nil {
+		return nil, err
+	}
+	bookingAsBytes, err := stub.GetState(bookingKey)
+	if err != nil {
+		return nil, err
+	}
+	if bookingAsBytes == nil {
+		return nil, nil
+	}
+
+	var booking Booking
+	err = json.Unmarshal(bookingAsBytes, &booking)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInternal, "unable to read booking %s: %s", bookingID, err.Error())
+	}
+	return &booking, nil
+}
+
+func putBooking(stub shim.ChaincodeStubInterface, booking Booking) error {
+	bookingKey, err := stub.CreateCompositeKey(bookingObjectType, []string{booking.CircuitID, booking.BookingID})
+	if err != nil {
+		return err
+	}
+
+	buff, err := json.Marshal(booking)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert Booking to json")
+	}
+	return stub.PutState(bookingKey, buff)
+}
+
+// getBookings lists every booking of a circuit, cancelled ones included, in BookingID order
+func getBookings(stub shim.ChaincodeStubInterface, dataCircuitID string) ([]Booking, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(bookingObjectType, []string{dataCircuitID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	bookings := []Booking{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var booking Booking
+		err = json.Unmarshal(queryResponse.Value, &booking)
+		if err != nil {
+			return nil, nsc.NewError(nsc.CodeInternal, "unable to read booking %s: %s", queryResponse.Key, err.Error())
+		}
+		bookings = append(bookings, booking)
+	}
+	return bookings, nil
+}
@@ -0,0 +1,76 @@
+package nims_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// circuitUsage returns the usage of C1 from startsOn up to endsOn
+func circuitUsage(t *testing.T, s *simulator.Simulator, startsOn string, endsOn string) nims.CircuitUsage {
+	t.Helper()
+	result := s.Query(simulator.NIMS, provider, "getCircuitUsage", "C1", startsOn, endsOn)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	var usage nims.CircuitUsage
+	if err := json.Unmarshal(result.Response.Payload, &usage); err != nil {
+		t.Fatal(err)
+	}
+	return usage
+}
+
+func TestBookingsFitUnderPeakUsage(t *testing.T) {
+	s := simulator.NewForTest(t)
+	alice := simulator.OperatorIdentity("alice")
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+	if result := s.SubmitOrder(alice, "O1", "C1", 40*simulator.Mbps); !result.OK() {
+		t.Fatal(result.Error())
+	}
+
+	// the simulator's transactions are timestamped 20231114221320
+	expectCode(t, s.BookBandwidth(alice, "C1", "B0", 10*simulator.Mbps, "20231101000000", "20231102000000"), nsc.CodeInvalidArgument)
+
+	// the standing 40M allocation counts against every booking
+	for _, booking := range []struct {
+		id       string
+		bw       nsc.Bandwidth
+		startsOn string
+		endsOn   string
+		fits     bool
+	}{
+		{"B1", 50 * simulator.Mbps, "20231201000000", "20231202000000", true},
+		{"B2", 20 * simulator.Mbps, "20231201120000", "20231203000000", false},
+		{"B2", 10 * simulator.Mbps, "20231201120000", "20231203000000", true},
+		// starts when B1 ends
+		{"B3", 50 * simulator.Mbps, "20231202000000", "20231203000000", true},
+	} {
+		result := s.BookBandwidth(alice, "C1", booking.id, booking.bw, booking.startsOn, booking.endsOn)
+		if result.OK() != booking.fits {
+			t.Fatalf("expected booking %s of %v to fit: %t, got %v", booking.id, booking.bw, booking.fits, result.Error())
+		}
+	}
+	expectCode(t, s.BookBandwidth(alice, "C1", "B1", 1*simulator.Mbps, "20231205000000", "20231206000000"), nsc.CodeConflict)
+
+	usage := circuitUsage(t, s, "20231201000000", "20231203000000")
+	if usage.PeakUsage != 100*simulator.Mbps || usage.Available != 0 || len(usage.Bookings) != 3 {
+		t.Errorf("expected the window fully booked by 3 bookings, got %+v", usage)
+	}
+	// bookings only live in the calendar
+	if err := s.ExpectBandwidth("C1", 40*simulator.Mbps, 60*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	if result := s.CancelBooking(alice, "C1", "B1"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	usage = circuitUsage(t, s, "20231201000000", "20231202000000")
+	if usage.PeakUsage != 50*simulator.Mbps || usage.Available != 50*simulator.Mbps {
+		t.Errorf("expected 50M available once B1 was cancelled, got %+v", usage)
+	}
+}
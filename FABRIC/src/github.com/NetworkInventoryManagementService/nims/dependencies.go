@@ -0,0 +1,14 @@
+package nims
+
+// ============================================================================================================================
+// Chaincode Dependencies - see github.com/NetworkServiceCommon/nsc/dependencies.go
+// NIMS calls no other chaincode, the registry names the chaincodes allocation and booking transactions are proposed to.
+// ============================================================================================================================
+
+// aliases of the chaincodes that call into NIMS
+const (
+	omsDependency = "OMS"
+	bpmDependency = "BPM"
+)
+
+var knownDependencies = []string{omsDependency, bpmDependency}
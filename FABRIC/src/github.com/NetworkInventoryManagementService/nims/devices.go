@@ -0,0 +1,712 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Devices - the physical inventory is a Device, optionally at a Site, kept under "Device"[DeviceID], the Cards in
+// its slots under "Card"[DeviceID, Slot] and the Ports of a card under "Port"[DeviceID, Slot, PortID], so a partial
+// key lists the children of any asset. A Port is free, reserved, in-use or faulty. setCircuitPort terminates one end
+// of a DataCircuit on a free or reserved port, which is then in-use and names the circuit, and indexes it under
+// "CircuitPort"[CircuitID, End]; the port the end was on before is freed. A faulty port keeps its circuit until it is
+// repaired, set in-use again, or the termination is cleared. listFreePorts finds the free ports of a device and
+// getCircuitPorts the physical chain, site, device, card and port, at both ends of a circuit.
+// ============================================================================================================================
+
+const (
+	deviceObjectType = "Device"
+	cardObjectType   = "Card"
+	portObjectType   = "Port"
+	circuitPortIndex = "CircuitPort"
+)
+
+// port states
+const (
+	portFree     = "free"
+	portReserved = "reserved"
+	portInUse    = "in-use"
+	portFaulty   = "faulty"
+)
+
+var addDeviceArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Name", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxNameLength},
+	{Name: "SiteID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Vendor", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+	{Name: "Model", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+}
+
+var getDeviceArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var addCardArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Slot", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Model", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+}
+
+// Speed is the line rate of the port, a circuit terminated on it may not be faster
+var addPortArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Slot", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "PortID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Speed", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+var setPortStateArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Slot", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "PortID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "State", Type: nsc.ArgString, Required: true, Enum: []string{portFree, portReserved, portInUse, portFaulty}},
+}
+
+var setCircuitPortArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "End", Type: nsc.ArgString, Required: true, Enum: []string{endA, endZ}},
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Slot", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "PortID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var clearCircuitPortArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "End", Type: nsc.ArgString, Required: true, Enum: []string{endA, endZ}},
+}
+
+// MinSpeed leaves out the ports that are slower or have no known speed
+var listFreePortsArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "MinSpeed", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+var getCircuitPortsArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type Device struct {
+	DeviceID  string `json:"DeviceID"`
+	Name      string `json:"Name"`
+	SiteID    string `json:"SiteID,omitempty"`
+	Vendor    string `json:"Vendor,omitempty"`
+	Model     string `json:"Model,omitempty"`
+	CreatedBy string `json:"CreatedBy"`
+	CreatedOn string `json:"CreatedOn"`
+}
+
+type Card struct {
+	DeviceID  string `json:"DeviceID"`
+	Slot      string `json:"Slot"`
+	Model     string `json:"Model,omitempty"`
+	CreatedBy string `json:"CreatedBy"`
+	CreatedOn string `json:"CreatedOn"`
+}
+
+// Port names the circuit end it terminates, it is kept while a faulty port is repaired
+type Port struct {
+	DeviceID  string        `json:"DeviceID"`
+	Slot      string        `json:"Slot"`
+	PortID    string        `json:"PortID"`
+	Speed     nsc.Bandwidth `json:"Speed,omitempty"`
+	State     string        `json:"State"`
+	CircuitID string        `json:"CircuitID,omitempty"`
+	End       string        `json:"End,omitempty"`
+	UpdatedBy string        `json:"UpdatedBy"`
+	UpdatedOn string        `json:"UpdatedOn"`
+}
+
+// PortRef is the value of the circuit port index
+type PortRef struct {
+	DeviceID string `json:"DeviceID"`
+	Slot     string `json:"Slot"`
+	PortID   string `json:"PortID"`
+}
+
+// DeviceInventory is returned by getDevice, the cards by Slot with their ports by PortID
+type DeviceInventory struct {
+	Device Device          `json:"Device"`
+	Cards  []CardInventory `json:"Cards"`
+}
+
+type CardInventory struct {
+	Card  Card   `json:"Card"`
+	Ports []Port `json:"Ports"`
+}
+
+// PortChain is the physical chain of a circuit end, SiteID is the site of the device
+type PortChain struct {
+	End    string `json:"End"`
+	SiteID string `json:"SiteID,omitempty"`
+	Device Device `json:"Device"`
+	Card   Card   `json:"Card"`
+	Port   Port   `json:"Port"`
+}
+
+// CircuitPorts is returned by setCircuitPort, clearCircuitPort and getCircuitPorts, an end without a port is nil
+type CircuitPorts struct {
+	CircuitID string     `json:"CircuitID"`
+	AEnd      *PortChain `json:"AEnd"`
+	ZEnd      *PortChain `json:"ZEnd"`
+}
+
+func addDevice(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting addDevice")
+
+	deviceID := arguments.Str("DeviceID")
+	var existing Device
+	found, err := getAssetState(stub, deviceObjectType, []string{deviceID}, &existing)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if found {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Device %s already exists", deviceID).WithDetail("DeviceID", deviceID))
+	}
+	if arguments.Str("SiteID") != "" {
+		_, err = getExistingSite(stub, arguments.Str("SiteID"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	device := Device{
+		DeviceID: deviceID,
+		Name:     arguments.Str("Name"),
+		SiteID:   arguments.Str("SiteID"),
+		Vendor:   arguments.Str("Vendor"),
+		Model:    arguments.Str("Model"),
+	}
+	device.CreatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	device.CreatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := putAssetState(stub, deviceObjectType, []string{deviceID}, device)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end addDevice")
+	return shim.Success(buff)
+}
+
+// getDevice returns a device with its cards and ports
+func getDevice(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	device, err := getExistingDevice(stub, arguments.Str("DeviceID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	inventory := DeviceInventory{Device: device, Cards: []CardInventory{}}
+	cards, err := listCards(stub, device.DeviceID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	for _, card := range cards {
+		ports, err := listPorts(stub, device.DeviceID, card.Slot)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		inventory.Cards = append(inventory.Cards, CardInventory{Card: card, Ports: ports})
+	}
+
+	buff, err := json.Marshal(inventory)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DeviceInventory to json"))
+	}
+	return shim.Success(buff)
+}
+
+func addCard(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting addCard")
+
+	deviceID, slot := arguments.Str("DeviceID"), arguments.Str("Slot")
+	_, err := getExistingDevice(stub, deviceID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	var existing Card
+	found, err := getAssetState(stub, cardObjectType, []string{deviceID, slot}, &existing)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if found {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Slot %s of %s already holds a card", slot, deviceID).
+			WithDetail("DeviceID", deviceID).
+			WithDetail("Slot", slot))
+	}
+
+	card := Card{DeviceID: deviceID, Slot: slot, Model: arguments.Str("Model")}
+	card.CreatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	card.CreatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := putAssetState(stub, cardObjectType, []string{deviceID, slot}, card)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end addCard")
+	return shim.Success(buff)
+}
+
+// addPort adds a free port to a card
+func addPort(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting addPort")
+
+	deviceID, slot, portID := arguments.Str("DeviceID"), arguments.Str("Slot"), arguments.Str("PortID")
+	_, err := getExistingCard(stub, deviceID, slot)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	var existing Port
+	found, err := getAssetState(stub, portObjectType, []string{deviceID, slot, portID}, &existing)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if found {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Port %s already exists on slot %s of %s", portID, slot, deviceID).
+			WithDetail("DeviceID", deviceID).
+			WithDetail("Slot", slot).
+			WithDetail("PortID", portID))
+	}
+
+	port := Port{DeviceID: deviceID, Slot: slot, PortID: portID, Speed: arguments.Bandwidth("Speed"), State: portFree}
+	buff, err := putPort(stub, port)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end addPort")
+	return shim.Success(buff)
+}
+
+// setPortState marks a port free, reserved or faulty. A port that terminates a circuit can only turn faulty and back to
+// in-use, its termination is cleared with clearCircuitPort, and only such a port can be in-use.
+func setPortState(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setPortState")
+
+	port, err := getExistingPort(stub, arguments.Str("DeviceID"), arguments.Str("Slot"), arguments.Str("PortID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	state := arguments.Str("State")
+	switch {
+	case port.CircuitID != "" && state != portInUse && state != portFaulty:
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Port %s terminates %s, clear it with clearCircuitPort first", portName(port), port.CircuitID).
+			WithDetail("CircuitID", port.CircuitID).
+			WithDetail("State", port.State))
+	case port.CircuitID == "" && state == portInUse:
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Port %s terminates no circuit, setCircuitPort puts a port in use", portName(port)).
+			WithDetail("State", port.State))
+	}
+
+	port.State = state
+	buff, err := putPort(stub, port)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setPortState")
+	return shim.Success(buff)
+}
+
+// setCircuitPort terminates an end of a circuit on a free or reserved port, freeing the port it was on before. The port
+// must be at least as fast as the circuit and its device at the site of that end when both are known.
+func setCircuitPort(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setCircuitPort")
+
+	dataCircuitID, end := arguments.Str("CircuitID"), arguments.Str("End")
+	fmt.Println(arguments)
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	port, err := getExistingPort(stub, arguments.Str("DeviceID"), arguments.Str("Slot"), arguments.Str("PortID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	device, err := getExistingDevice(stub, port.DeviceID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	if port.CircuitID == dataCircuitID && port.End == end {
+		return circuitPortsResponse(stub, dataCircuitID)
+	}
+	if port.State != portFree && port.State != portReserved {
+		portError := nsc.NewError(nsc.CodeConflict, "Port %s is %s", portName(port), port.State).WithDetail("State", port.State)
+		if port.CircuitID != "" {
+			portError = portError.WithDetail("CircuitID", port.CircuitID)
+		}
+		return nsc.ErrorResponse(portError)
+	}
+	faults, err := getPortFaults(stub, port.DeviceID, port.Slot)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if len(faults) > 0 {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Port %s is below faulty equipment %s", portName(port), faults[0]).
+			WithDetail("SerialNumber", faults[0]))
+	}
+	if port.Speed > 0 && port.Speed < dataCircuitObject.TotalBandwidth {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Port %s at %s is slower than the %s of %s", portName(port), port.Speed, dataCircuitObject.TotalBandwidth, dataCircuitID).
+			WithDetail("Speed", port.Speed).
+			WithDetail("TotalBandwidth", dataCircuitObject.TotalBandwidth))
+	}
+	endpoints, err := getCircuitEndpointsState(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	endpoint := endpoints.AEnd
+	if end == endZ {
+		endpoint = endpoints.ZEnd
+	}
+	if endpoint != nil && device.SiteID != "" && device.SiteID != endpoint.SiteID {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Device %s is at %s, the %s-end of %s is at %s", device.DeviceID, device.SiteID, end, dataCircuitID, endpoint.SiteID).
+			WithDetail("DeviceID", device.DeviceID).
+			WithDetail("SiteID", endpoint.SiteID))
+	}
+
+	err = releaseCircuitPort(stub, dataCircuitID, end)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	port.State, port.CircuitID, port.End = portInUse, dataCircuitID, end
+	_, err = putPort(stub, port)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	indexKey, err := stub.CreateCompositeKey(circuitPortIndex, []string{dataCircuitID, end})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	buff, err := json.Marshal(PortRef{port.DeviceID, port.Slot, port.PortID})
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert PortRef to json"))
+	}
+	err = stub.PutState(indexKey, buff)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = refreshEquipmentFlags(stub, []string{dataCircuitID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setCircuitPort")
+	return circuitPortsResponse(stub, dataCircuitID)
+}
+
+// clearCircuitPort removes the port of a circuit end, the port is freed unless it is faulty
+func clearCircuitPort(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting clearCircuitPort")
+
+	dataCircuitID, end := arguments.Str("CircuitID"), arguments.Str("End")
+	_, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	ref, err := getCircuitPortRef(stub, dataCircuitID, end)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if ref == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "The %s-end of %s is not on a port", end, dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("End", end))
+	}
+
+	err = releaseCircuitPort(stub, dataCircuitID, end)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = refreshEquipmentFlags(stub, []string{dataCircuitID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end clearCircuitPort")
+	return circuitPortsResponse(stub, dataCircuitID)
+}
+
+// listFreePorts returns the free ports of a device by Slot and PortID, leaving out the ports below faulty equipment
+func listFreePorts(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	deviceID := arguments.Str("DeviceID")
+	_, err := getExistingDevice(stub, deviceID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	ports, err := listPorts(stub, deviceID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	minSpeed := arguments.Bandwidth("MinSpeed")
+	free := []Port{}
+	for _, port := range ports {
+		if port.State != portFree || port.Speed < minSpeed {
+			continue
+		}
+		faults, err := getPortFaults(stub, port.DeviceID, port.Slot)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if len(faults) == 0 {
+			free = append(free, port)
+		}
+	}
+
+	buff, err := json.Marshal(free)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert Ports to json"))
+	}
+	return shim.Success(buff)
+}
+
+func getCircuitPorts(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	dataCircuitID := arguments.Str("CircuitID")
+	_, err := getDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	return circuitPortsResponse(stub, dataCircuitID)
+}
+
+func circuitPortsResponse(stub shim.ChaincodeStubInterface, dataCircuitID string) pb.Response {
+	circuitPorts := CircuitPorts{CircuitID: dataCircuitID}
+	for _, end := range []string{endA, endZ} {
+		chain, err := getPortChain(stub, dataCircuitID, end)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if end == endA {
+			circuitPorts.AEnd = chain
+		} else {
+			circuitPorts.ZEnd = chain
+		}
+	}
+
+	buff, err := json.Marshal(circuitPorts)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CircuitPorts to json"))
+	}
+	return shim.Success(buff)
+}
+
+// getPortChain returns nil when the circuit end is not on a port
+func getPortChain(stub shim.ChaincodeStubInterface, dataCircuitID string, end string) (*PortChain, error) {
+	ref, err := getCircuitPortRef(stub, dataCircuitID, end)
+	if err != nil || ref == nil {
+		return nil, err
+	}
+
+	chain := PortChain{End: end}
+	chain.Device, err = getExistingDevice(stub, ref.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	chain.Card, err = getExistingCard(stub, ref.DeviceID, ref.Slot)
+	if err != nil {
+		return nil, err
+	}
+	chain.Port, err = getExistingPort(stub, ref.DeviceID, ref.Slot, ref.PortID)
+	if err != nil {
+		return nil, err
+	}
+	chain.SiteID = chain.Device.SiteID
+	return &chain, nil
+}
+
+// releaseCircuitPort removes the port of a circuit end from the index and frees it, a faulty port stays faulty
+func releaseCircuitPort(stub shim.ChaincodeStubInterface, dataCircuitID string, end string) error {
+	ref, err := getCircuitPortRef(stub, dataCircuitID, end)
+	if err != nil || ref == nil {
+		return err
+	}
+
+	port, err := getExistingPort(stub, ref.DeviceID, ref.Slot, ref.PortID)
+	if err != nil {
+		return err
+	}
+	port.CircuitID, port.End = "", ""
+	if port.State == portInUse {
+		port.State = portFree
+	}
+	_, err = putPort(stub, port)
+	if err != nil {
+		return err
+	}
+
+	indexKey, err := stub.CreateCompositeKey(circuitPortIndex, []string{dataCircuitID, end})
+	if err != nil {
+		return err
+	}
+	return stub.DelState(indexKey)
+}
+
+// getCircuitPortRef returns nil when the circuit end is not on a port
+func getCircuitPortRef(stub shim.ChaincodeStubInterface, dataCircuitID string, end string) (*PortRef, error) {
+	var ref PortRef
+	found, err := getAssetState(stub, circuitPortIndex, []string{dataCircuitID, end}, &ref)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// putPort stamps and writes a port, returning it as json
+func putPort(stub shim.ChaincodeStubInterface, port Port) ([]byte, error) {
+	var err error
+	port.UpdatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nil, err
+	}
+	port.UpdatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	return putAssetState(stub, portObjectType, []string{port.DeviceID, port.Slot, port.PortID}, port)
+}
+
+func getExistingDevice(stub shim.ChaincodeStubInterface, deviceID string) (Device, error) {
+	var device Device
+	found, err := getAssetState(stub, deviceObjectType, []string{deviceID}, &device)
+	if err != nil {
+		return device, err
+	}
+	if !found {
+		return device, nsc.NewError(nsc.CodeNotFound, "Device %s does not exist", deviceID).WithDetail("DeviceID", deviceID)
+	}
+	return device, nil
+}
+
+func getExistingCard(stub shim.ChaincodeStubInterface, deviceID string, slot string) (Card, error) {
+	var card Card
+	found, err := getAssetState(stub, cardObjectType, []string{deviceID, slot}, &card)
+	if err != nil {
+		return card, err
+	}
+	if !found {
+		return card, nsc.NewError(nsc.CodeNotFound, "Slot %s of %s holds no card", slot, deviceID).
+			WithDetail("DeviceID", deviceID).
+			WithDetail("Slot", slot)
+	}
+	return card, nil
+}
+
+func getExistingPort(stub shim.ChaincodeStubInterface, deviceID string, slot string, portID string) (Port, error) {
+	var port Port
+	found, err := getAssetState(stub, portObjectType, []string{deviceID, slot, portID}, &port)
+	if err != nil {
+		return port, err
+	}
+	if !found {
+		return port, nsc.NewError(nsc.CodeNotFound, "Port %s does not exist on slot %s of %s", portID, slot, deviceID).
+			WithDetail("DeviceID", deviceID).
+			WithDetail("Slot", slot).
+			WithDetail("PortID", portID)
+	}
+	return port, nil
+}
+
+// listCards lists the cards of a device by Slot
+func listCards(stub shim.ChaincodeStubInterface, deviceID string) ([]Card, error) {
+	cards := []Card{}
+	err := scanAssets(stub, cardObjectType, []string{deviceID}, func(value []byte) error {
+		var card Card
+		err := json.Unmarshal(value, &card)
+		cards = append(cards, card)
+		return err
+	})
+	return cards, err
+}
+
+// listPorts lists the ports of a device, or of one of its cards, by Slot and PortID
+func listPorts(stub shim.ChaincodeStubInterface, keyParts ...string) ([]Port, error) {
+	ports := []Port{}
+	err := scanAssets(stub, portObjectType, keyParts, func(value []byte) error {
+		var port Port
+		err := json.Unmarshal(value, &port)
+		ports = append(ports, port)
+		return err
+	})
+	return ports, err
+}
+
+// getAssetState reads the asset under a composite key into value, false when there is none
+func getAssetState(stub shim.ChaincodeStubInterface, objectType string, keyParts []string, value interface{}) (bool, error) {
+	key, err := stub.CreateCompositeKey(objectType, keyParts)
+	if err != nil {
+		return false, err
+	}
+	valueAsBytes, err := stub.GetState(key)
+	if err != nil {
+		return false, err
+	}
+	if valueAsBytes == nil {
+		return false, nil
+	}
+	err = json.Unmarshal(valueAsBytes, value)
+	if err != nil {
+		return false, nsc.NewError(nsc.CodeInternal, "unable to read %s %v: %s", objectType, keyParts, err.Error())
+	}
+	return true, nil
+}
+
+// putAssetState writes an asset under a composite key, returning it as json
+func putAssetState(stub shim.ChaincodeStubInterface, objectType string, keyParts []string, value interface{}) ([]byte, error) {
+	key, err := stub.CreateCompositeKey(objectType, keyParts)
+	if err != nil {
+		return nil, err
+	}
+	buff, err := json.Marshal(value)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInternal, "unable to convert %s to json", objectType)
+	}
+	return buff, stub.PutState(key, buff)
+}
+
+// scanAssets calls read with every asset under a partial composite key, in key order
+func scanAssets(stub shim.ChaincodeStubInterface, objectType string, keyParts []string, read func([]byte) error) error {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(objectType, keyParts)
+	if err != nil {
+		return err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+		err = read(queryResponse.Value)
+		if err != nil {
+			return nsc.NewError(nsc.CodeInternal, "unable to read %s %s: %s", objectType, queryResponse.Key, err.Error())
+		}
+	}
+	return nil
+}
+
+// portName is how errors name a port
+func portName(port Port) string {
+	return port.DeviceID + "/" + port.Slot + "/" + port.PortID
+}
@@ -0,0 +1,94 @@
+package nims_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// freePorts lists the free ports of R1 of at least 1G
+func freePorts(t *testing.T, s *simulator.Simulator) []string {
+	t.Helper()
+	result := s.Query(simulator.NIMS, provider, "listFreePorts", "R1", "1G")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	var ports []nims.Port
+	if err := json.Unmarshal(result.Response.Payload, &ports); err != nil {
+		t.Fatal(err)
+	}
+	portIDs := []string{}
+	for _, port := range ports {
+		portIDs = append(portIDs, port.PortID)
+	}
+	return portIDs
+}
+
+func TestCircuitEndsAreTerminatedOnPorts(t *testing.T) {
+	s := simulator.NewForTest(t)
+	for _, siteID := range []string{"S1", "S2"} {
+		if err := s.AddSite(siteID, "site "+siteID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", simulator.Gbps); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LinkCircuit("C1", "S1", "S2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddDevice("R1", "router 1", "S1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddCard("R1", "1"); err != nil {
+		t.Fatal(err)
+	}
+	for portID, speed := range map[string]nsc.Bandwidth{"p1": 10 * simulator.Gbps, "p2": 100 * simulator.Mbps, "p3": 10 * simulator.Gbps} {
+		if err := s.AddPort("R1", "1", portID, speed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectCode(t, s.Invoke(simulator.NIMS, simulator.AdminIdentity, "addCard", "R1", "1"), nsc.CodeConflict)
+	expectCode(t, s.Invoke(simulator.NIMS, simulator.AdminIdentity, "addPort", "R1", "2", "p1"), nsc.CodeNotFound)
+
+	// p2 is too slow for the 1G circuit and R1 is not at the Z-end's site
+	expectCode(t, s.SetCircuitPort("C1", "A", "R1", "1", "p2"), nsc.CodeInvalidArgument)
+	expectCode(t, s.SetCircuitPort("C1", "Z", "R1", "1", "p1"), nsc.CodeInvalidArgument)
+
+	if result := s.SetCircuitPort("C1", "A", "R1", "1", "p1"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if free := freePorts(t, s); len(free) != 1 || free[0] != "p3" {
+		t.Errorf("expected p3 to be the only free 1G port, got %v", free)
+	}
+	expectCode(t, s.Invoke(simulator.NIMS, provider, "setPortState", "R1", "1", "p1", "free"), nsc.CodeConflict)
+
+	// moving the A-end frees the port it was on
+	if result := s.SetCircuitPort("C1", "A", "R1", "1", "p3"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if free := freePorts(t, s); len(free) != 1 || free[0] != "p1" {
+		t.Errorf("expected p1 to be freed, got %v", free)
+	}
+
+	result := s.Query(simulator.NIMS, provider, "getCircuitPorts", "C1")
+	var ports nims.CircuitPorts
+	if err := json.Unmarshal(result.Response.Payload, &ports); err != nil {
+		t.Fatal(err)
+	}
+	if ports.AEnd == nil || ports.AEnd.SiteID != "S1" || ports.AEnd.Port.PortID != "p3" || ports.AEnd.Port.State != "in-use" || ports.ZEnd != nil {
+		t.Errorf("expected the A-end on p3 of R1 at S1 and no Z-end port, got %+v", ports)
+	}
+
+	result = s.Query(simulator.NIMS, provider, "getDevice", "R1")
+	var inventory nims.DeviceInventory
+	if err := json.Unmarshal(result.Response.Payload, &inventory); err != nil {
+		t.Fatal(err)
+	}
+	if len(inventory.Cards) != 1 || len(inventory.Cards[0].Ports) != 3 || inventory.Cards[0].Ports[2].CircuitID != "C1" {
+		t.Errorf("expected R1 with one card of 3 ports, p3 on C1, got %+v", inventory)
+	}
+}
@@ -0,0 +1,815 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Equipment Lifecycle - a piece of hardware is Equipment, kept under "Equipment"[SerialNumber] with its model, warranty
+// and status: in-stock, installed, faulty, rma or retired. Installed equipment sits in a position of a Device, the
+// chassis or the card in a slot, indexed under "EquipmentPosition"[DeviceID, Slot] with an empty Slot for the chassis.
+// While the equipment in a position is faulty or out on RMA, every DataCircuit terminated on a port below it is flagged
+// with its SerialNumber in FaultyEquipment, BPM places no new orders on a flagged circuit and setCircuitPort and
+// listFreePorts leave its ports out. An RMA case, kept under "RMA"[RMAID], follows faulty equipment to the vendor:
+// swapEquipment installs an in-stock replacement in its position and records the swap, closeRMA returns the unit to
+// stock or scraps it. Every status change is an EquipmentStatusChanged event naming the circuits flagged or cleared.
+// ============================================================================================================================
+
+const (
+	equipmentObjectType    = "Equipment"
+	equipmentPositionIndex = "EquipmentPosition"
+	rmaObjectType          = "RMA"
+)
+
+// equipment statuses
+const (
+	equipmentInStock   = "in-stock"
+	equipmentInstalled = "installed"
+	equipmentFaulty    = "faulty"
+	equipmentRMA       = "rma"
+	equipmentRetired   = "retired"
+)
+
+// RMA statuses and outcomes, a returned unit goes back to stock or to its position and a scrapped one is retired
+const (
+	rmaOpen     = "open"
+	rmaClosed   = "closed"
+	rmaReturned = "returned"
+	rmaScrapped = "scrapped"
+)
+
+var addEquipmentArguments = nsc.ArgumentSchema{
+	{Name: "SerialNumber", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Model", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxNameLength},
+	{Name: "Vendor", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+	{Name: "WarrantyEndsOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+}
+
+var equipmentArguments = nsc.ArgumentSchema{
+	{Name: "SerialNumber", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// an empty Slot installs the equipment as the chassis of the device, InstalledOn defaults to the transaction time
+var installEquipmentArguments = nsc.ArgumentSchema{
+	{Name: "SerialNumber", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Slot", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "InstalledOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+}
+
+var setEquipmentStatusArguments = nsc.ArgumentSchema{
+	{Name: "SerialNumber", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Status", Type: nsc.ArgString, Required: true, Enum: []string{equipmentInstalled, equipmentFaulty, equipmentRetired}},
+	{Name: "Note", Type: nsc.ArgString, MaxLength: 512},
+}
+
+var listDeviceEquipmentArguments = nsc.ArgumentSchema{
+	{Name: "DeviceID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var openRMAArguments = nsc.ArgumentSchema{
+	{Name: "RMAID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "SerialNumber", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Reason", Type: nsc.ArgString, Required: true, MaxLength: 512},
+	{Name: "VendorReference", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+}
+
+var rmaArguments = nsc.ArgumentSchema{
+	{Name: "RMAID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var swapEquipmentArguments = nsc.ArgumentSchema{
+	{Name: "RMAID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ReplacementSerialNumber", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var closeRMAArguments = nsc.ArgumentSchema{
+	{Name: "RMAID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Outcome", Type: nsc.ArgString, Required: true, Enum: []string{rmaReturned, rmaScrapped}},
+}
+
+// Equipment is in a position of DeviceID while it is installed, or faulty or on RMA without having been removed
+type Equipment struct {
+	SerialNumber   string `json:"SerialNumber"`
+	Model          string `json:"Model"`
+	Vendor         string `json:"Vendor,omitempty"`
+	WarrantyEndsOn string `json:"WarrantyEndsOn,omitempty"`
+	Status         string `json:"Status"`
+	DeviceID       string `json:"DeviceID,omitempty"`
+	Slot           string `json:"Slot,omitempty"`
+	InstalledOn    string `json:"InstalledOn,omitempty"`
+	Note           string `json:"Note,omitempty"`
+	CreatedBy      string `json:"CreatedBy"`
+	CreatedOn      string `json:"CreatedOn"`
+	UpdatedBy      string `json:"UpdatedBy"`
+	UpdatedOn      string `json:"UpdatedOn"`
+}
+
+// RMACase is a return to the vendor, UnderWarranty is whether the warranty had not ended when it was opened
+type RMACase struct {
+	RMAID           string         `json:"RMAID"`
+	SerialNumber    string         `json:"SerialNumber"`
+	Reason          string         `json:"Reason"`
+	VendorReference string         `json:"VendorReference,omitempty"`
+	UnderWarranty   bool           `json:"UnderWarranty"`
+	Status          string         `json:"Status"`
+	Swap            *EquipmentSwap `json:"Swap,omitempty"`
+	Outcome         string         `json:"Outcome,omitempty"`
+	OpenedBy        string         `json:"OpenedBy"`
+	OpenedOn        string         `json:"OpenedOn"`
+	ClosedBy        string         `json:"ClosedBy,omitempty"`
+	ClosedOn        string         `json:"ClosedOn,omitempty"`
+}
+
+// EquipmentSwap records the replacement of faulty equipment in its position
+type EquipmentSwap struct {
+	FaultySerialNumber      string `json:"FaultySerialNumber"`
+	ReplacementSerialNumber string `json:"ReplacementSerialNumber"`
+	DeviceID                string `json:"DeviceID"`
+	Slot                    string `json:"Slot,omitempty"`
+	SwappedBy               string `json:"SwappedBy"`
+	SwappedOn               string `json:"SwappedOn"`
+}
+
+// EquipmentStatusChange is the data of EquipmentStatusChanged events
+type EquipmentStatusChange struct {
+	SerialNumber    string   `json:"SerialNumber"`
+	PreviousStatus  string   `json:"PreviousStatus,omitempty"`
+	Status          string   `json:"Status"`
+	DeviceID        string   `json:"DeviceID,omitempty"`
+	Slot            string   `json:"Slot,omitempty"`
+	RMAID           string   `json:"RMAID,omitempty"`
+	FlaggedCircuits []string `json:"FlaggedCircuits,omitempty"`
+	ClearedCircuits []string `json:"ClearedCircuits,omitempty"`
+}
+
+func addEquipment(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting addEquipment")
+
+	serialNumber := arguments.Str("SerialNumber")
+	var existing Equipment
+	found, err := getAssetState(stub, equipmentObjectType, []string{serialNumber}, &existing)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if found {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s already exists", serialNumber).WithDetail("SerialNumber", serialNumber))
+	}
+
+	equipment := Equipment{
+		SerialNumber:   serialNumber,
+		Model:          arguments.Str("Model"),
+		Vendor:         arguments.Str("Vendor"),
+		WarrantyEndsOn: arguments.Str("WarrantyEndsOn"),
+		Status:         equipmentInStock,
+	}
+	equipment.CreatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	equipment.CreatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := putEquipment(stub, equipment)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end addEquipment")
+	return shim.Success(buff)
+}
+
+func getEquipment(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	equipment, err := getExistingEquipment(stub, arguments.Str("SerialNumber"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(equipment)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert Equipment to json"))
+	}
+	return shim.Success(buff)
+}
+
+// installEquipment puts in-stock equipment in a vacant position of a device
+func installEquipment(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting installEquipment")
+
+	equipment, err := getExistingEquipment(stub, arguments.Str("SerialNumber"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	if equipment.Status != equipmentInStock {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s is %s, only in-stock equipment can be installed", equipment.SerialNumber, equipment.Status).
+			WithDetail("SerialNumber", equipment.SerialNumber).
+			WithDetail("Status", equipment.Status))
+	}
+	installedOn := arguments.Str("InstalledOn")
+	if installedOn == "" {
+		installedOn, err = getTxTimestamp(stub)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	change, err := placeEquipment(stub, &equipment, arguments.Str("DeviceID"), arguments.Str("Slot"), installedOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, equipment.SerialNumber)
+	err = events.Add(nsc.EventEquipmentStatusChanged, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end installEquipment")
+	return events.Emit(stub)
+}
+
+// removeEquipment takes equipment out of its position, installed equipment goes back in stock and faulty or RMA
+// equipment keeps its status. The circuits it flagged are cleared.
+func removeEquipment(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting removeEquipment")
+
+	equipment, err := getExistingEquipment(stub, arguments.Str("SerialNumber"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if equipment.DeviceID == "" {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s is not installed", equipment.SerialNumber).
+			WithDetail("SerialNumber", equipment.SerialNumber).
+			WithDetail("Status", equipment.Status))
+	}
+
+	change, err := vacateEquipment(stub, &equipment)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, equipment.SerialNumber)
+	err = events.Add(nsc.EventEquipmentStatusChanged, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end removeEquipment")
+	return events.Emit(stub)
+}
+
+// setEquipmentStatus marks installed or in-stock equipment faulty, faulty equipment still in its position installed
+// again once it is repaired, and equipment that is not in a position retired. RMA cases move equipment to and from rma.
+func setEquipmentStatus(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setEquipmentStatus")
+
+	equipment, err := getExistingEquipment(stub, arguments.Str("SerialNumber"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	status := arguments.Str("Status")
+	allowed := false
+	switch status {
+	case equipmentFaulty:
+		allowed = equipment.Status == equipmentInstalled || equipment.Status == equipmentInStock
+	case equipmentInstalled:
+		allowed = equipment.Status == equipmentFaulty && equipment.DeviceID != ""
+	case equipmentRetired:
+		allowed = (equipment.Status == equipmentInStock || equipment.Status == equipmentFaulty) && equipment.DeviceID == ""
+	}
+	if !allowed {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s cannot turn %s from %s", equipment.SerialNumber, status, equipment.Status).
+			WithDetail("SerialNumber", equipment.SerialNumber).
+			WithDetail("Status", equipment.Status))
+	}
+
+	equipment.Note = arguments.Str("Note")
+	change, err := changeEquipmentStatus(stub, &equipment, status)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, equipment.SerialNumber)
+	err = events.Add(nsc.EventEquipmentStatusChanged, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setEquipmentStatus")
+	return events.Emit(stub)
+}
+
+// listDeviceEquipment returns the equipment in the positions of a device, the chassis first and then by Slot
+func listDeviceEquipment(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	deviceID := arguments.Str("DeviceID")
+	_, err := getExistingDevice(stub, deviceID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	serialNumbers := []string{}
+	err = scanAssets(stub, equipmentPositionIndex, []string{deviceID}, func(value []byte) error {
+		serialNumbers = append(serialNumbers, string(value))
+		return nil
+	})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	equipments := []Equipment{}
+	for _, serialNumber := range serialNumbers {
+		equipment, err := getExistingEquipment(stub, serialNumber)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		equipments = append(equipments, equipment)
+	}
+
+	buff, err := json.Marshal(equipments)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert Equipment to json"))
+	}
+	return shim.Success(buff)
+}
+
+// openRMA opens a case for faulty equipment, which is then on RMA and keeps flagging its circuits until it is swapped
+// or removed
+func openRMA(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting openRMA")
+
+	rmaID := arguments.Str("RMAID")
+	var existing RMACase
+	found, err := getAssetState(stub, rmaObjectType, []string{rmaID}, &existing)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if found {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "RMA %s already exists", rmaID).WithDetail("RMAID", rmaID))
+	}
+	equipment, err := getExistingEquipment(stub, arguments.Str("SerialNumber"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	if equipment.Status != equipmentFaulty {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s is %s, only faulty equipment can be returned", equipment.SerialNumber, equipment.Status).
+			WithDetail("SerialNumber", equipment.SerialNumber).
+			WithDetail("Status", equipment.Status))
+	}
+
+	rma := RMACase{
+		RMAID:           rmaID,
+		SerialNumber:    equipment.SerialNumber,
+		Reason:          arguments.Str("Reason"),
+		VendorReference: arguments.Str("VendorReference"),
+		Status:          rmaOpen,
+	}
+	rma.OpenedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	rma.OpenedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	rma.UnderWarranty = equipment.WarrantyEndsOn != "" && rma.OpenedOn <= equipment.WarrantyEndsOn
+
+	change, err := changeEquipmentStatus(stub, &equipment, equipmentRMA)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	change.RMAID = rmaID
+	_, err = putAssetState(stub, rmaObjectType, []string{rmaID}, rma)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, rmaID)
+	err = events.Add(nsc.EventEquipmentStatusChanged, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end openRMA")
+	return events.Emit(stub)
+}
+
+func getRMA(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	rma, err := getExistingRMA(stub, arguments.Str("RMAID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(rma)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert RMACase to json"))
+	}
+	return shim.Success(buff)
+}
+
+// swapEquipment takes the equipment of an open case out of its position and installs an in-stock replacement there,
+// clearing the circuits it flagged. A case records one swap.
+func swapEquipment(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting swapEquipment")
+
+	rma, err := getExistingRMA(stub, arguments.Str("RMAID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	if rma.Status != rmaOpen {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "RMA %s is closed and cannot record a swap", rma.RMAID).WithDetail("RMAID", rma.RMAID))
+	}
+	if rma.Swap != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "RMA %s already swapped %s for %s", rma.RMAID, rma.Swap.FaultySerialNumber, rma.Swap.ReplacementSerialNumber).
+			WithDetail("RMAID", rma.RMAID).
+			WithDetail("SerialNumber", rma.Swap.ReplacementSerialNumber))
+	}
+	faulty, err := getExistingEquipment(stub, rma.SerialNumber)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if faulty.DeviceID == "" {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s is not installed, there is nothing to swap", faulty.SerialNumber).
+			WithDetail("SerialNumber", faulty.SerialNumber))
+	}
+	replacement, err := getExistingEquipment(stub, arguments.Str("ReplacementSerialNumber"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if replacement.Status != equipmentInStock {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s is %s, only in-stock equipment can replace %s", replacement.SerialNumber, replacement.Status, faulty.SerialNumber).
+			WithDetail("SerialNumber", replacement.SerialNumber).
+			WithDetail("Status", replacement.Status))
+	}
+
+	swap := EquipmentSwap{
+		FaultySerialNumber:      faulty.SerialNumber,
+		ReplacementSerialNumber: replacement.SerialNumber,
+		DeviceID:                faulty.DeviceID,
+		Slot:                    faulty.Slot,
+	}
+	swap.SwappedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	swap.SwappedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	removed, err := vacateEquipment(stub, &faulty)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	removed.RMAID = rma.RMAID
+	installed, err := placeEquipment(stub, &replacement, swap.DeviceID, swap.Slot, swap.SwappedOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	installed.RMAID = rma.RMAID
+
+	rma.Swap = &swap
+	_, err = putAssetState(stub, rmaObjectType, []string{rma.RMAID}, rma)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, rma.RMAID)
+	for _, change := range []EquipmentStatusChange{removed, installed} {
+		err = events.Add(nsc.EventEquipmentStatusChanged, change)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+	err = events.Add(nsc.EventEquipmentSwapped, swap)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end swapEquipment")
+	return events.Emit(stub)
+}
+
+// closeRMA closes a case with the vendor's outcome. A returned unit goes back into its position when it was never
+// swapped out and in stock otherwise, a scrapped unit must be out of its position and is retired.
+func closeRMA(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting closeRMA")
+
+	rma, err := getExistingRMA(stub, arguments.Str("RMAID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	if rma.Status != rmaOpen {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "RMA %s is already closed", rma.RMAID).WithDetail("RMAID", rma.RMAID))
+	}
+	equipment, err := getExistingEquipment(stub, rma.SerialNumber)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	outcome := arguments.Str("Outcome")
+	status := equipmentInStock
+	switch {
+	case outcome == rmaScrapped && equipment.DeviceID != "":
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Equipment %s is still in %s, swap or remove it before scrapping it", equipment.SerialNumber, equipment.DeviceID).
+			WithDetail("SerialNumber", equipment.SerialNumber).
+			WithDetail("DeviceID", equipment.DeviceID))
+	case outcome == rmaScrapped:
+		status = equipmentRetired
+	case equipment.DeviceID != "":
+		status = equipmentInstalled
+	}
+
+	change, err := changeEquipmentStatus(stub, &equipment, status)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	change.RMAID = rma.RMAID
+
+	rma.Status, rma.Outcome = rmaClosed, outcome
+	rma.ClosedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	rma.ClosedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	_, err = putAssetState(stub, rmaObjectType, []string{rma.RMAID}, rma)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, rma.RMAID)
+	err = events.Add(nsc.EventEquipmentStatusChanged, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end closeRMA")
+	return events.Emit(stub)
+}
+
+// placeEquipment installs equipment in a vacant position and writes it, the caller checks it may be installed
+func placeEquipment(stub shim.ChaincodeStubInterface, equipment *Equipment, deviceID string, slot string, installedOn string) (EquipmentStatusChange, error) {
+	change := EquipmentStatusChange{SerialNumber: equipment.SerialNumber, PreviousStatus: equipment.Status, Status: equipmentInstalled, DeviceID: deviceID, Slot: slot}
+
+	_, err := getExistingDevice(stub, deviceID)
+	if err != nil {
+		return change, err
+	}
+	if slot != "" {
+		_, err = getExistingCard(stub, deviceID, slot)
+		if err != nil {
+			return change, err
+		}
+	}
+	occupant, err := getPositionEquipment(stub, deviceID, slot)
+	if err != nil {
+		return change, err
+	}
+	if occupant != "" {
+		return change, nsc.NewError(nsc.CodeConflict, "%s already holds equipment %s", positionName(deviceID, slot), occupant).
+			WithDetail("DeviceID", deviceID).
+			WithDetail("Slot", slot).
+			WithDetail("SerialNumber", occupant)
+	}
+
+	positionKey, err := stub.CreateCompositeKey(equipmentPositionIndex, []string{deviceID, slot})
+	if err != nil {
+		return change, err
+	}
+	err = stub.PutState(positionKey, []byte(equipment.SerialNumber))
+	if err != nil {
+		return change, err
+	}
+
+	equipment.Status, equipment.DeviceID, equipment.Slot, equipment.InstalledOn = equipmentInstalled, deviceID, slot, installedOn
+	_, err = putEquipment(stub, *equipment)
+	return change, err
+}
+
+// vacateEquipment takes equipment out of its position and writes it, clearing the circuits it flagged
+func vacateEquipment(stub shim.ChaincodeStubInterface, equipment *Equipment) (EquipmentStatusChange, error) {
+	status := equipment.Status
+	if status == equipmentInstalled {
+		status = equipmentInStock
+	}
+	change := EquipmentStatusChange{SerialNumber: equipment.SerialNumber, PreviousStatus: equipment.Status, Status: status, DeviceID: equipment.DeviceID, Slot: equipment.Slot}
+
+	circuitIDs, err := listPositionCircuits(stub, equipment.DeviceID, equipment.Slot)
+	if err != nil {
+		return change, err
+	}
+	positionKey, err := stub.CreateCompositeKey(equipmentPositionIndex, []string{equipment.DeviceID, equipment.Slot})
+	if err != nil {
+		return change, err
+	}
+	err = stub.DelState(positionKey)
+	if err != nil {
+		return change, err
+	}
+
+	flagged := flagsCircuits(equipment.Status)
+	equipment.Status, equipment.DeviceID, equipment.Slot, equipment.InstalledOn = status, "", "", ""
+	_, err = putEquipment(stub, *equipment)
+	if err != nil || !flagged {
+		return change, err
+	}
+	change.ClearedCircuits = circuitIDs
+	return change, refreshEquipmentFlags(stub, circuitIDs)
+}
+
+// changeEquipmentStatus sets the status of equipment and writes it, flagging or clearing the circuits below its
+// position
+func changeEquipmentStatus(stub shim.ChaincodeStubInterface, equipment *Equipment, status string) (EquipmentStatusChange, error) {
+	change := EquipmentStatusChange{SerialNumber: equipment.SerialNumber, PreviousStatus: equipment.Status, Status: status, DeviceID: equipment.DeviceID, Slot: equipment.Slot}
+
+	flagged := flagsCircuits(equipment.Status)
+	equipment.Status = status
+	_, err := putEquipment(stub, *equipment)
+	if err != nil || equipment.DeviceID == "" || flagged == flagsCircuits(status) {
+		return change, err
+	}
+
+	circuitIDs, err := listPositionCircuits(stub, equipment.DeviceID, equipment.Slot)
+	if err != nil {
+		return change, err
+	}
+	if flagged {
+		change.ClearedCircuits = circuitIDs
+	} else {
+		change.FlaggedCircuits = circuitIDs
+	}
+	return change, refreshEquipmentFlags(stub, circuitIDs)
+}
+
+// flagsCircuits is whether equipment of a status flags the circuits below its position
+func flagsCircuits(status string) bool {
+	return status == equipmentFaulty || status == equipmentRMA
+}
+
+// listPositionCircuits returns the circuits terminated on a port below a position by CircuitID, the chassis covers
+// every port of the device
+func listPositionCircuits(stub shim.ChaincodeStubInterface, deviceID string, slot string) ([]string, error) {
+	keyParts := []string{deviceID}
+	if slot != "" {
+		keyParts = append(keyParts, slot)
+	}
+	ports, err := listPorts(stub, keyParts...)
+	if err != nil {
+		return nil, err
+	}
+
+	circuitIDs := []string{}
+	seen := map[string]bool{}
+	for _, port := range ports {
+		if port.CircuitID != "" && !seen[port.CircuitID] {
+			seen[port.CircuitID] = true
+			circuitIDs = append(circuitIDs, port.CircuitID)
+		}
+	}
+	sort.Strings(circuitIDs)
+	return circuitIDs, nil
+}
+
+// refreshEquipmentFlags sets the FaultyEquipment of circuits to the faulty or RMA equipment above the ports at their
+// ends, writing the circuits it changes
+func refreshEquipmentFlags(stub shim.ChaincodeStubInterface, circuitIDs []string) error {
+	for _, dataCircuitID := range circuitIDs {
+		dataCircuitObject, err := getDataCircuit(stub, dataCircuitID)
+		if err != nil {
+			return err
+		}
+
+		var faultyEquipment []string
+		seen := map[string]bool{}
+		for _, end := range []string{endA, endZ} {
+			ref, err := getCircuitPortRef(stub, dataCircuitID, end)
+			if err != nil {
+				return err
+			}
+			if ref == nil {
+				continue
+			}
+			faults, err := getPortFaults(stub, ref.DeviceID, ref.Slot)
+			if err != nil {
+				return err
+			}
+			for _, serialNumber := range faults {
+				if !seen[serialNumber] {
+					seen[serialNumber] = true
+					faultyEquipment = append(faultyEquipment, serialNumber)
+				}
+			}
+		}
+
+		if strings.Join(faultyEquipment, ",") == strings.Join(dataCircuitObject.FaultyEquipment, ",") {
+			continue
+		}
+		dataCircuitObject.FaultyEquipment = faultyEquipment
+		err = putDataCircuit(stub, dataCircuitObject)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPortFaults returns the SerialNumbers of faulty or RMA equipment in the chassis and the card of a port
+func getPortFaults(stub shim.ChaincodeStubInterface, deviceID string, slot string) ([]string, error) {
+	faults := []string{}
+	for _, position := range []string{"", slot} {
+		serialNumber, err := getPositionEquipment(stub, deviceID, position)
+		if err != nil {
+			return nil, err
+		}
+		if serialNumber == "" {
+			continue
+		}
+		equipment, err := getExistingEquipment(stub, serialNumber)
+		if err != nil {
+			return nil, err
+		}
+		if flagsCircuits(equipment.Status) {
+			faults = append(faults, serialNumber)
+		}
+	}
+	return faults, nil
+}
+
+// getPositionEquipment returns the SerialNumber of the equipment in a position, empty when it is vacant
+func getPositionEquipment(stub shim.ChaincodeStubInterface, deviceID string, slot string) (string, error) {
+	positionKey, err := stub.CreateCompositeKey(equipmentPositionIndex, []string{deviceID, slot})
+	if err != nil {
+		return "", err
+	}
+	serialNumber, err := stub.GetState(positionKey)
+	if err != nil {
+		return "", err
+	}
+	return string(serialNumber), nil
+}
+
+// putEquipment stamps and writes equipment, returning it as json
+func putEquipment(stub shim.ChaincodeStubInterface, equipment Equipment) ([]byte, error) {
+	var err error
+	equipment.UpdatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nil, err
+	}
+	equipment.UpdatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nil, err
+	}
+	return putAssetState(stub, equipmentObjectType, []string{equipment.SerialNumber}, equipment)
+}
+
+func getExistingEquipment(stub shim.ChaincodeStubInterface, serialNumber string) (Equipment, error) {
+	var equipment Equipment
+	found, err := getAssetState(stub, equipmentObjectType, []string{serialNumber}, &equipment)
+	if err != nil {
+		return equipment, err
+	}
+	if !found {
+		return equipment, nsc.NewError(nsc.CodeNotFound, "Equipment %s does not exist", serialNumber).WithDetail("SerialNumber", serialNumber)
+	}
+	return equipment, nil
+}
+
+func getExistingRMA(stub shim.ChaincodeStubInterface, rmaID string) (RMACase, error) {
+	var rma RMACase
+	found, err := getAssetState(stub, rmaObjectType, []string{rmaID}, &rma)
+	if err != nil {
+		return rma, err
+	}
+	if !found {
+		return rma, nsc.NewError(nsc.CodeNotFound, "RMA %s does not exist", rmaID).WithDetail("RMAID", rmaID)
+	}
+	return rma, nil
+}
+
+// positionName is how errors name a position
+func positionName(deviceID string, slot string) string {
+	if slot == "" {
+		return "The chassis of " + deviceID
+	}
+	return "Slot " + slot + " of " + deviceID
+}
@@ -0,0 +1,105 @@
+package nims_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// equipmentStatus reads a piece of equipment back from the inventory
+func equipmentStatus(t *testing.T, s *simulator.Simulator, serialNumber string) nims.Equipment {
+	t.Helper()
+	result := s.Query(simulator.NIMS, provider, "getEquipment", serialNumber)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	var equipment nims.Equipment
+	if err := json.Unmarshal(result.Response.Payload, &equipment); err != nil {
+		t.Fatal(err)
+	}
+	return equipment
+}
+
+func expectFaultyEquipment(t *testing.T, s *simulator.Simulator, circuitID string, expected int) {
+	t.Helper()
+	circuit, err := s.Circuit(circuitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(circuit.FaultyEquipment) != expected {
+		t.Errorf("expected %s to be flagged with %d faulty equipment, got %v", circuitID, expected, circuit.FaultyEquipment)
+	}
+}
+
+func TestRMAFlagsCircuitsUntilTheSwap(t *testing.T) {
+	s := simulator.NewForTest(t)
+	alice := simulator.OperatorIdentity("alice")
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", simulator.Gbps); err != nil {
+		t.Fatal(err)
+	}
+	for _, setup := range []error{
+		s.AddDevice("R1", "router 1", ""),
+		s.AddCard("R1", "1"),
+		s.AddPort("R1", "1", "p1", simulator.Gbps),
+		s.AddEquipment("LC1", "line card", "20991231000000"),
+		s.AddEquipment("LC2", "line card", ""),
+	} {
+		if setup != nil {
+			t.Fatal(setup)
+		}
+	}
+	for _, result := range []simulator.Result{s.SetCircuitPort("C1", "A", "R1", "1", "p1"), s.InstallEquipment("LC1", "R1", "1")} {
+		if !result.OK() {
+			t.Fatal(result.Error())
+		}
+	}
+
+	// only faulty equipment goes out on RMA
+	expectCode(t, s.OpenRMA("RMA1", "LC1", "link flaps"), nsc.CodeConflict)
+	if result := s.SetEquipmentStatus("LC1", "faulty"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	expectFaultyEquipment(t, s, "C1", 1)
+	expectCode(t, s.PlaceOrder(alice, "O1", "NET1", "", 100*simulator.Mbps, ""), nsc.CodeNotFound)
+
+	result := s.OpenRMA("RMA1", "LC1", "link flaps")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	var rma nims.RMACase
+	result = s.Query(simulator.NIMS, provider, "getRMA", "RMA1")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if err := json.Unmarshal(result.Response.Payload, &rma); err != nil {
+		t.Fatal(err)
+	}
+	if !rma.UnderWarranty || rma.Status != "open" || equipmentStatus(t, s, "LC1").Status != "rma" {
+		t.Errorf("expected an open RMA under warranty for LC1, got %+v", rma)
+	}
+	expectFaultyEquipment(t, s, "C1", 1)
+
+	// the replacement takes the position and clears the circuit
+	if result = s.SwapEquipment("RMA1", "LC2"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	replacement := equipmentStatus(t, s, "LC2")
+	if replacement.Status != "installed" || replacement.DeviceID != "R1" || replacement.Slot != "1" {
+		t.Errorf("expected LC2 installed in slot 1 of R1, got %+v", replacement)
+	}
+	expectFaultyEquipment(t, s, "C1", 0)
+	if result = s.PlaceOrder(alice, "O1", "NET1", "", 100*simulator.Mbps, ""); !result.OK() {
+		t.Fatal(result.Error())
+	}
+
+	if result = s.CloseRMA("RMA1", "returned"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if returned := equipmentStatus(t, s, "LC1"); returned.Status != "in-stock" || returned.DeviceID != "" {
+		t.Errorf("expected LC1 back in stock, got %+v", returned)
+	}
+	expectCode(t, s.CloseRMA("RMA1", "scrapped"), nsc.CodeConflict)
+}
@@ -0,0 +1,356 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Circuit Status and Failover - a DataCircuit is Up or Down. Nothing new is allocated on a Down circuit, its existing
+// allocations stay until BPM failoverCircuit moves them with moveAllocations, either onto the backup circuit of a
+// protected order, which already holds the bandwidth, or onto a newly placed circuit. NIMS does not call back into BPM,
+// failoverCircuit is run after setDataCircuitStatus, e.g. by a listener on its CircuitStatusChanged record. A transaction
+// does not read its own writes, so every allocation of the failed circuit is moved in one moveAllocations call, which
+// reads each circuit once and writes it once after the last move.
+// ============================================================================================================================
+
+const (
+	circuitUp   = "Up"
+	circuitDown = "Down"
+)
+
+// the release reason carried by the BandwidthReleased record of a moved allocation
+const releaseFailover = "Failover"
+
+var setDataCircuitStatusArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Status", Type: nsc.ArgString, Required: true, Enum: []string{circuitUp, circuitDown}},
+}
+
+var moveAllocationsArguments = nsc.ArgumentSchema{
+	{Name: "FromCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	// a JSON array of AllocationMoveRequest, see parseAllocationMoves
+	{Name: "Moves", Type: nsc.ArgString, Required: true, MaxLength: 16384},
+}
+
+// AllocationMoveRequest names the circuit the allocation of an order on FromCircuitID moves to
+type AllocationMoveRequest struct {
+	OrderID     string `json:"OrderID"`
+	ToCircuitID string `json:"ToCircuitID"`
+}
+
+// CircuitStatusChange is the data of CircuitStatusChanged events
+type CircuitStatusChange struct {
+	CircuitID          string        `json:"CircuitID"`
+	PreviousStatus     string        `json:"PreviousStatus"`
+	Status             string        `json:"Status"`
+	AllocatedBandwidth nsc.Bandwidth `json:"AllocatedBandwidth"`
+}
+
+// AllocationMove is the data of AllocationMoved events, ToBackup is set when the backup of a protected order took over
+type AllocationMove struct {
+	OrderID       string        `json:"OrderID"`
+	FromCircuitID string        `json:"FromCircuitID"`
+	ToCircuitID   string        `json:"ToCircuitID"`
+	Bandwidth     nsc.Bandwidth `json:"Bandwidth"`
+	ToBackup      bool          `json:"ToBackup,omitempty"`
+}
+
+func setDataCircuitStatus(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setDataCircuitStatus")
+
+	dataCircuitID := arguments.Str("CircuitID")
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	change := CircuitStatusChange{
+		CircuitID:          dataCircuitID,
+		PreviousStatus:     circuitStatus(dataCircuitObject),
+		Status:             arguments.Str("Status"),
+		AllocatedBandwidth: dataCircuitObject.AllocatedBandwidth,
+	}
+	dataCircuitObject.Status = change.Status
+
+	err = putDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, dataCircuitID)
+	err = events.Add(nsc.EventCircuitStatusChanged, change)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setDataCircuitStatus")
+	return events.Emit(stub)
+}
+
+// moveAllocations moves the whole allocations of orders off FromCircuitID, every move must succeed. The circuits are read
+// once into a circuitBatch and the moves are made on it in order, a later move sees the bandwidth an earlier one took.
+func moveAllocations(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting moveAllocations")
+
+	fromCircuitID := arguments.Str("FromCircuitID")
+	fmt.Println(arguments)
+
+	moves, err := parseAllocationMoves(arguments)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	circuits := newCircuitBatch()
+	fromCircuit, err := getProvidedDataCircuit(stub, fromCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	circuits.circuits[fromCircuitID] = &fromCircuit
+
+	events := nsc.NewEventBatch(stub, chaincodeName, fromCircuitID)
+	for _, move := range moves {
+		err = moveAllocation(stub, circuits, events, move.OrderID, fromCircuitID, move.ToCircuitID)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	err = circuits.put(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end moveAllocations")
+	return events.Emit(stub)
+}
+
+// parseAllocationMoves reads the Moves argument, every order is moved once and not onto FromCircuitID
+func parseAllocationMoves(arguments nsc.FunctionArgs) ([]AllocationMoveRequest, error) {
+	var moves []AllocationMoveRequest
+	err := json.Unmarshal([]byte(arguments.Str("Moves")), &moves)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "Moves is not a JSON array of allocation moves: %s", err.Error())
+	}
+	if len(moves) == 0 {
+		return nil, nsc.NewError(nsc.CodeInvalidArgument, "Moves names no allocation to move")
+	}
+
+	orders := map[string]bool{}
+	for _, move := range moves {
+		if !nsc.MatchesPattern(nsc.IDPattern, move.OrderID) || !nsc.MatchesPattern(nsc.IDPattern, move.ToCircuitID) || orders[move.OrderID] {
+			return nil, nsc.NewError(nsc.CodeInvalidArgument, "invalid move of order %q onto circuit %q", move.OrderID, move.ToCircuitID)
+		}
+		if move.ToCircuitID == arguments.Str("FromCircuitID") {
+			return nil, nsc.NewError(nsc.CodeInvalidArgument, "an allocation cannot be moved onto the circuit it is on").
+				WithDetail("CircuitID", move.ToCircuitID)
+		}
+		orders[move.OrderID] = true
+	}
+	return moves, nil
+}
+
+// circuitBatch holds the circuits a batch of changes reads, each is read from the ledger once and the changed ones are
+// written once by put
+type circuitBatch struct {
+	circuits map[string]*DataCircuit
+	changed  map[string]bool
+}
+
+func newCircuitBatch() circuitBatch {
+	return circuitBatch{map[string]*DataCircuit{}, map[string]bool{}}
+}
+
+// get returns the circuit as changed by the batch so far
+func (c circuitBatch) get(stub shim.ChaincodeStubInterface, dataCircuitID string) (*DataCircuit, error) {
+	if dataCircuitObject, ok := c.circuits[dataCircuitID]; ok {
+		return dataCircuitObject, nil
+	}
+	dataCircuitObject, err := getDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nil, err
+	}
+	c.circuits[dataCircuitID] = &dataCircuitObject
+	return &dataCircuitObject, nil
+}
+
+// put writes the changed circuits in key order
+func (c circuitBatch) put(stub shim.ChaincodeStubInterface) error {
+	changed := make([]string, 0, len(c.changed))
+	for dataCircuitID := range c.changed {
+		changed = append(changed, dataCircuitID)
+	}
+	sort.Strings(changed)
+	for _, dataCircuitID := range changed {
+		err := putDataCircuit(stub, *c.circuits[dataCircuitID])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveAllocation moves the whole allocation of an order off fromCircuitID within a batch. When toCircuitID is the other
+// circuit of the order's protection group it already holds the bandwidth and only the allocation on fromCircuitID is
+// released, otherwise toCircuitID must have room for it and stay diverse from the other circuit of the group.
+func moveAllocation(stub shim.ChaincodeStubInterface, circuits circuitBatch, events *nsc.EventBatch, orderID string, fromCircuitID string, toCircuitID string) error {
+	allocation, err := getAllocation(stub, fromCircuitID, orderID)
+	if err != nil {
+		return err
+	}
+	if allocation == nil {
+		return nsc.NewError(nsc.CodeNotFound, "Order %s holds no bandwidth on %s", orderID, fromCircuitID).
+			WithDetail("CircuitID", fromCircuitID).
+			WithDetail("OrderID", orderID)
+	}
+
+	fromCircuit, err := circuits.get(stub, fromCircuitID)
+	if err != nil {
+		return err
+	}
+	toCircuit, err := circuits.get(stub, toCircuitID)
+	if err != nil {
+		return err
+	}
+	err = assertCircuitUp(*toCircuit)
+	if err != nil {
+		return err
+	}
+
+	group, err := getProtectionGroupState(stub, orderID)
+	if err != nil {
+		return err
+	}
+
+	move := AllocationMove{orderID, fromCircuitID, toCircuitID, allocation.Bandwidth, false}
+
+	if group != nil && group.PrimaryCircuitID == fromCircuitID && group.BackupCircuitID == toCircuitID {
+		move.ToBackup = true
+		group.PrimaryCircuitID = toCircuitID
+		group.BackupCircuitID = ""
+		group.FailedOverOn, err = getTxTimestamp(stub)
+		if err != nil {
+			return err
+		}
+	} else {
+		if group != nil {
+			err = replaceProtectedCircuit(stub, circuits, group, fromCircuitID, *toCircuit)
+			if err != nil {
+				return err
+			}
+		}
+
+		now, err := getTxTimestamp(stub)
+		if err != nil {
+			return err
+		}
+		maintenanceWindowID, err := checkMaintenance(stub, toCircuitID, now)
+		if err != nil {
+			return err
+		}
+		if allocation.Bandwidth > toCircuit.UnallocatedBandwidth {
+			return nsc.NewError(nsc.CodeInsufficientCapacity, "moveAllocation() : %s has no room for the allocation of order %s", toCircuitID, orderID).
+				WithDetail("CircuitID", toCircuitID).
+				WithDetail("RequestedBandwidth", allocation.Bandwidth).
+				WithDetail("UnallocatedBandwidth", toCircuit.UnallocatedBandwidth)
+		}
+		err = assertFitsCalendar(stub, *toCircuit, allocation.Bandwidth, now, allocation.ExpiresOn)
+		if err != nil {
+			return err
+		}
+		err = allocateExcess(toCircuit, allocation.ExcessBandwidth)
+		if err != nil {
+			return err
+		}
+		err = recordAllocation(stub, toCircuitID, orderID, allocation.OperatorID, allocation.Bandwidth, allocation.ExcessBandwidth, allocation.ExpiresOn)
+		if err != nil {
+			return err
+		}
+
+		toCircuit.AllocatedBandwidth = toCircuit.AllocatedBandwidth + allocation.Bandwidth
+		toCircuit.UnallocatedBandwidth = toCircuit.UnallocatedBandwidth - allocation.Bandwidth
+		circuits.changed[toCircuitID] = true
+		change := newBandwidthChange(*toCircuit, orderID, allocation.Bandwidth, "")
+		change.MaintenanceWindowID = maintenanceWindowID
+		change.ExcessBandwidth = allocation.ExcessBandwidth
+		err = events.Add(nsc.EventBandwidthAllocated, change)
+		if err != nil {
+			return err
+		}
+	}
+
+	if group != nil {
+		err = putProtectionGroup(stub, *group)
+		if err != nil {
+			return err
+		}
+	}
+
+	released := allocation.Bandwidth
+	allocation.Bandwidth = 0
+	releasedExcess := releaseExcess(fromCircuit, allocation)
+	err = putAllocation(stub, *allocation)
+	if err != nil {
+		return err
+	}
+
+	fromCircuit.AllocatedBandwidth = fromCircuit.AllocatedBandwidth - released
+	fromCircuit.UnallocatedBandwidth = fromCircuit.UnallocatedBandwidth + released
+	circuits.changed[fromCircuitID] = true
+
+	change := newBandwidthChange(*fromCircuit, orderID, released, releaseFailover)
+	change.ExcessBandwidth = releasedExcess
+	err = events.Add(nsc.EventBandwidthReleased, change)
+	if err != nil {
+		return err
+	}
+	return events.Add(nsc.EventAllocationMoved, move)
+}
+
+// replaceProtectedCircuit puts toCircuit in the place of fromCircuitID in the group, it must stay diverse from the other
+func replaceProtectedCircuit(stub shim.ChaincodeStubInterface, circuits circuitBatch, group *ProtectionGroup, fromCircuitID string, toCircuit DataCircuit) error {
+	var otherCircuitID string
+	switch fromCircuitID {
+	case group.PrimaryCircuitID:
+		otherCircuitID = group.BackupCircuitID
+		group.PrimaryCircuitID = toCircuit.CircuitID
+	case group.BackupCircuitID:
+		otherCircuitID = group.PrimaryCircuitID
+		group.BackupCircuitID = toCircuit.CircuitID
+	default:
+		return nil
+	}
+	if otherCircuitID == "" {
+		return nil
+	}
+
+	otherCircuit, err := circuits.get(stub, otherCircuitID)
+	if err != nil {
+		return err
+	}
+	return assertDiverse(*otherCircuit, toCircuit, group.DiverseOn)
+}
+
+// assertCircuitUp refuses new bandwidth on a Down circuit
+func assertCircuitUp(dataCircuitObject DataCircuit) error {
+	if circuitStatus(dataCircuitObject) == circuitDown {
+		return nsc.NewError(nsc.CodeConflict, "DataCircuit %s is Down", dataCircuitObject.CircuitID).
+			WithDetail("CircuitID", dataCircuitObject.CircuitID).
+			WithDetail("Status", circuitDown)
+	}
+	return nil
+}
+
+// circuitStatus reads the status of a circuit, circuits stored before it was tracked are Up
+func circuitStatus(dataCircuitObject DataCircuit) string {
+	if dataCircuitObject.Status == "" {
+		return circuitUp
+	}
+	return dataCircuitObject.Status
+}
@@ -0,0 +1,19 @@
+package nims_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceSimulator"
+)
+
+var provider = simulator.ProviderAdminIdentity("Org1MSP")
+
+func expectCode(t *testing.T, result simulator.Result, code string) {
+	t.Helper()
+	if result.OK() {
+		t.Fatalf("expected %s, the call succeeded", code)
+	}
+	if result.Err == nil || result.Err.Code != code {
+		t.Fatalf("expected %s, got %v", code, result.Error())
+	}
+}
@@ -0,0 +1,83 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Inventory Index - every DataCircuit is indexed under "CircuitNetwork"[CircuitNetwork, ProviderID, CircuitID] when it is
+// added, so BPM can choose a circuit for an order that only names a network and optionally a provider. The index is
+// ordered, listing it returns the same circuits in the same order on every endorser.
+// ============================================================================================================================
+
+const circuitNetworkIndex = "CircuitNetwork"
+
+var listDataCircuitsArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ProviderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+func listDataCircuits(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting listDataCircuits")
+
+	attributes := []string{arguments.Str("CircuitNetwork")}
+	if arguments.Has("ProviderID") {
+		attributes = append(attributes, arguments.Str("ProviderID"))
+	}
+
+	dataCircuits, err := listIndexedDataCircuits(stub, attributes...)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	dataCircuitsAsBytes, err := json.Marshal(dataCircuits)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuits to json"))
+	}
+
+	fmt.Println("- end listDataCircuits")
+	return shim.Success(dataCircuitsAsBytes)
+}
+
+// indexDataCircuit adds a DataCircuit to the network index, the value is only a marker
+func indexDataCircuit(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit) error {
+	indexKey, err := stub.CreateCompositeKey(circuitNetworkIndex, []string{dataCircuitObject.CircuitNetwork, dataCircuitObject.ProviderID, dataCircuitObject.CircuitID})
+	if err != nil {
+		return err
+	}
+	return stub.PutState(indexKey, []byte{0x00})
+}
+
+// listIndexedDataCircuits reads the circuits under a prefix of the network index, every circuit without one
+func listIndexedDataCircuits(stub shim.ChaincodeStubInterface, attributes ...string) ([]DataCircuit, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(circuitNetworkIndex, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	dataCircuits := []DataCircuit{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		dataCircuitObject, err := getDataCircuit(stub, keyParts[2])
+		if err != nil {
+			return nil, err
+		}
+		dataCircuits = append(dataCircuits, dataCircuitObject)
+	}
+	return dataCircuits, nil
+}
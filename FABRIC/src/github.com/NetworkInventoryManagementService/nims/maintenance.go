@@ -0,0 +1,342 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Maintenance Windows - a provider schedules maintenance on a DataCircuit from StartsOn up to, not including, EndsOn.
+// Windows are kept under "MaintenanceWindow"[CircuitID, WindowID] and never overlap on a circuit. An allocation that
+// starts inside an Outage window is refused, one that starts inside an AtRisk window is made and flagged with the window
+// on its BandwidthAllocated record. Scheduling or cancelling a window notifies the operators of the allocations that
+// are still held when the window starts through the MaintenanceScheduled and MaintenanceCancelled records.
+// ============================================================================================================================
+
+const maintenanceWindowObjectType = "MaintenanceWindow"
+
+// maintenance types
+const (
+	maintenanceOutage = "Outage"
+	maintenanceAtRisk = "AtRisk"
+)
+
+// maintenance window states
+const (
+	maintenanceScheduled = "Scheduled"
+	maintenanceCancelled = "Cancelled"
+)
+
+var scheduleMaintenanceWindowArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "WindowID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "StartsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "EndsOn", Type: nsc.ArgString, Required: true, Pattern: nsc.TimestampPattern},
+	{Name: "Type", Type: nsc.ArgString, Required: true, Enum: []string{maintenanceOutage, maintenanceAtRisk}},
+	{Name: "Notice", Type: nsc.ArgString, MaxLength: 512},
+}
+
+var maintenanceWindowArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "WindowID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var listMaintenanceWindowsArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "IncludeCancelled", Type: nsc.ArgBoolean},
+}
+
+type MaintenanceWindow struct {
+	CircuitID   string `json:"CircuitID"`
+	WindowID    string `json:"WindowID"`
+	StartsOn    string `json:"StartsOn"`
+	EndsOn      string `json:"EndsOn"`
+	Type        string `json:"Type"`
+	Notice      string `json:"Notice,omitempty"`
+	Status      string `json:"Status"`
+	ScheduledBy string `json:"ScheduledBy"`
+	ScheduledOn string `json:"ScheduledOn"`
+	CancelledBy string `json:"CancelledBy,omitempty"`
+	CancelledOn string `json:"CancelledOn,omitempty"`
+}
+
+// MaintenanceNotice is the data of MaintenanceScheduled and MaintenanceCancelled events
+type MaintenanceNotice struct {
+	Window MaintenanceWindow `json:"Window"`
+	// Affected lists the allocations still held when the window starts, each names the operator to notify
+	Affected []Allocation `json:"Affected"`
+}
+
+func scheduleMaintenanceWindow(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting scheduleMaintenanceWindow")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	windowID := arguments.Str("WindowID")
+	fmt.Println(arguments)
+
+	_, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	window := MaintenanceWindow{
+		CircuitID:   dataCircuitID,
+		WindowID:    windowID,
+		StartsOn:    arguments.Str("StartsOn"),
+		EndsOn:      arguments.Str("EndsOn"),
+		Type:        arguments.Str("Type"),
+		Notice:      arguments.Str("Notice"),
+		Status:      maintenanceScheduled,
+		ScheduledOn: now,
+	}
+	if window.EndsOn <= window.StartsOn || window.EndsOn <= now {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "a maintenance window must end after it starts and after now").
+			WithDetail("StartsOn", window.StartsOn).
+			WithDetail("EndsOn", window.EndsOn))
+	}
+
+	windows, err := getMaintenanceWindows(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	for _, existing := range windows {
+		if existing.WindowID == windowID {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Maintenance window %s of %s already exists", windowID, dataCircuitID).
+				WithDetail("CircuitID", dataCircuitID).
+				WithDetail("WindowID", windowID))
+		}
+		if existing.Status == maintenanceScheduled && existing.StartsOn < window.EndsOn && window.StartsOn < existing.EndsOn {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Maintenance window %s overlaps window %s of %s", windowID, existing.WindowID, dataCircuitID).
+				WithDetail("CircuitID", dataCircuitID).
+				WithDetail("WindowID", existing.WindowID).
+				WithDetail("StartsOn", existing.StartsOn).
+				WithDetail("EndsOn", existing.EndsOn))
+		}
+	}
+
+	window.ScheduledBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = putMaintenanceWindow(stub, window)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	return emitMaintenanceNotice(stub, nsc.EventMaintenanceScheduled, window)
+}
+
+func cancelMaintenanceWindow(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting cancelMaintenanceWindow")
+
+	_, err := getProvidedDataCircuit(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	window, err := getMaintenanceWindow(stub, arguments.Str("CircuitID"), arguments.Str("WindowID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if window.Status != maintenanceScheduled || window.EndsOn <= now {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Maintenance window %s of %s is cancelled or over", window.WindowID, window.CircuitID).
+			WithDetail("WindowID", window.WindowID).
+			WithDetail("Status", window.Status).
+			WithDetail("EndsOn", window.EndsOn))
+	}
+
+	window.Status = maintenanceCancelled
+	window.CancelledOn = now
+	window.CancelledBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = putMaintenanceWindow(stub, window)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	return emitMaintenanceNotice(stub, nsc.EventMaintenanceCancelled, window)
+}
+
+// listMaintenanceWindows lists the windows of a circuit in WindowID order, cancelled ones only on request
+func listMaintenanceWindows(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	windows, err := getMaintenanceWindows(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	listed := []MaintenanceWindow{}
+	for _, window := range windows {
+		if window.Status == maintenanceScheduled || arguments.Boolean("IncludeCancelled") {
+			listed = append(listed, window)
+		}
+	}
+
+	windowsAsBytes, err := json.Marshal(listed)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert maintenance windows to json"))
+	}
+	return shim.Success(windowsAsBytes)
+}
+
+// listOpenOutages lists the scheduled Outage windows that are open now on the circuits of a network, reading every
+// window once instead of once per circuit
+func listOpenOutages(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	attributes := []string{arguments.Str("CircuitNetwork")}
+	if arguments.Has("ProviderID") {
+		attributes = append(attributes, arguments.Str("ProviderID"))
+	}
+	dataCircuits, err := listIndexedDataCircuits(stub, attributes...)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	inNetwork := map[string]bool{}
+	for _, dataCircuit := range dataCircuits {
+		inNetwork[dataCircuit.CircuitID] = true
+	}
+
+	windows, err := getMaintenanceWindows(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	now, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	open := []MaintenanceWindow{}
+	for _, window := range windows {
+		if inNetwork[window.CircuitID] && window.Type == maintenanceOutage && window.Status == maintenanceScheduled &&
+			window.StartsOn <= now && now < window.EndsOn {
+			open = append(open, window)
+		}
+	}
+
+	windowsAsBytes, err := json.Marshal(open)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert maintenance windows to json"))
+	}
+	return shim.Success(windowsAsBytes)
+}
+
+// emitMaintenanceNotice emits the window with the allocations that are still held when it starts
+func emitMaintenanceNotice(stub shim.ChaincodeStubInterface, eventType string, window MaintenanceWindow) pb.Response {
+	allocations, err := getAllocations(stub, window.CircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	notice := MaintenanceNotice{Window: window, Affected: []Allocation{}}
+	for _, allocation := range allocations {
+		if allocation.ExpiresOn == "" || allocation.ExpiresOn > window.StartsOn {
+			notice.Affected = append(notice.Affected, allocation)
+		}
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, window.CircuitID)
+	err = events.Add(eventType, notice)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end " + eventType)
+	return events.Emit(stub)
+}
+
+// checkMaintenance refuses an allocation starting at startsOn inside an Outage window of the circuit and returns the
+// WindowID of an AtRisk window it starts in, empty when there is none
+func checkMaintenance(stub shim.ChaincodeStubInterface, dataCircuitID string, startsOn string) (string, error) {
+	windows, err := getMaintenanceWindows(stub, dataCircuitID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, window := range windows {
+		if window.Status != maintenanceScheduled || startsOn < window.StartsOn || startsOn >= window.EndsOn {
+			continue
+		}
+		if window.Type == maintenanceOutage {
+			return "", nsc.NewError(nsc.CodeConflict, "DataCircuit %s is in maintenance window %s until %s", dataCircuitID, window.WindowID, window.EndsOn).
+				WithDetail("CircuitID", dataCircuitID).
+				WithDetail("WindowID", window.WindowID).
+				WithDetail("EndsOn", window.EndsOn)
+		}
+		return window.WindowID, nil
+	}
+	return "", nil
+}
+
+func getMaintenanceWindow(stub shim.ChaincodeStubInterface, dataCircuitID string, windowID string) (MaintenanceWindow, error) {
+	var window MaintenanceWindow
+
+	windowKey, err := stub.CreateCompositeKey(maintenanceWindowObjectType, []string{dataCircuitID, windowID})
+	if err != nil {
+		return window, err
+	}
+	windowAsBytes, err := stub.GetState(windowKey)
+	if err != nil {
+		return window, err
+	}
+	if windowAsBytes == nil {
+		return window, nsc.NewError(nsc.CodeNotFound, "Maintenance window %s of %s does not exist", windowID, dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("WindowID", windowID)
+	}
+
+	err = json.Unmarshal(windowAsBytes, &window)
+	if err != nil {
+		return window, nsc.NewError(nsc.CodeInternal, "unable to read maintenance window %s: %s", windowID, err.Error())
+	}
+	return window, nil
+}
+
+func putMaintenanceWindow(stub shim.ChaincodeStubInterface, window MaintenanceWindow) error {
+	windowKey, err := stub.CreateCompositeKey(maintenanceWindowObjectType, []string{window.CircuitID, window.WindowID})
+	if err != nil {
+		return err
+	}
+
+	buff, err := json.Marshal(window)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert MaintenanceWindow to json")
+	}
+	return stub.PutState(windowKey, buff)
+}
+
+// getMaintenanceWindows lists the windows of a circuit, of every circuit without one, cancelled ones included
+func getMaintenanceWindows(stub shim.ChaincodeStubInterface, dataCircuitID ...string) ([]MaintenanceWindow, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(maintenanceWindowObjectType, dataCircuitID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	windows := []MaintenanceWindow{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var window MaintenanceWindow
+		err = json.Unmarshal(queryResponse.Value, &window)
+		if err != nil {
+			return nil, nsc.NewError(nsc.CodeInternal, "unable to read maintenance window %s: %s", queryResponse.Key, err.Error())
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
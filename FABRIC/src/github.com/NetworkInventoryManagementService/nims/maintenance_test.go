@@ -0,0 +1,113 @@
+package nims_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestMaintenanceWindowsRefuseOverlapAndNotifyOperators(t *testing.T) {
+	s := simulator.NewForTest(t)
+	alice := simulator.OperatorIdentity("alice")
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+	if result := s.SubmitOrder(alice, "O1", "C1", 40*simulator.Mbps); !result.OK() {
+		t.Fatal(result.Error())
+	}
+
+	// the simulator's transactions are timestamped 20231114221320
+	expectCode(t, s.ScheduleMaintenance("C1", "W1", "20231115000000", "20231114000000", "Outage"), nsc.CodeInvalidArgument)
+	expectCode(t, s.ScheduleMaintenance("C1", "W1", "20231101000000", "20231102000000", "Outage"), nsc.CodeInvalidArgument)
+
+	result := s.ScheduleMaintenance("C1", "W1", "20231114000000", "20231115000000", "Outage")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	records := result.Records(nsc.EventMaintenanceScheduled)
+	if len(records) != 1 {
+		t.Fatalf("expected one %s record, got %+v", nsc.EventMaintenanceScheduled, result.Event)
+	}
+	var notice nims.MaintenanceNotice
+	if err := json.Unmarshal(records[0].Data, &notice); err != nil {
+		t.Fatal(err)
+	}
+	if len(notice.Affected) != 1 || notice.Affected[0].OrderID != "O1" || notice.Affected[0].OperatorID != "alice" {
+		t.Errorf("expected alice to be notified of O1, got %+v", notice.Affected)
+	}
+
+	expectCode(t, s.ScheduleMaintenance("C1", "W1", "20231201000000", "20231202000000", "AtRisk"), nsc.CodeConflict)
+	expectCode(t, s.ScheduleMaintenance("C1", "W2", "20231114120000", "20231116000000", "AtRisk"), nsc.CodeConflict)
+
+	// no new allocation starts inside an Outage
+	if s.SubmitOrder(alice, "O2", "C1", 10*simulator.Mbps).OK() {
+		t.Error("expected an order on C1 to fail during the Outage")
+	}
+	if err := s.ExpectBandwidth("C1", 40*simulator.Mbps, 60*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	if result = s.Invoke(simulator.NIMS, provider, "cancelMaintenanceWindow", "C1", "W1"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	expectCode(t, s.Invoke(simulator.NIMS, provider, "cancelMaintenanceWindow", "C1", "W1"), nsc.CodeConflict)
+	for includeCancelled, expected := range map[string]int{"false": 0, "true": 1} {
+		result = s.Query(simulator.NIMS, provider, "listMaintenanceWindows", "C1", includeCancelled)
+		var windows []nims.MaintenanceWindow
+		if err := json.Unmarshal(result.Response.Payload, &windows); err != nil {
+			t.Fatal(err)
+		}
+		if len(windows) != expected {
+			t.Errorf("expected %d windows with IncludeCancelled %s, got %+v", expected, includeCancelled, windows)
+		}
+	}
+
+	// an allocation inside an AtRisk window is made and flagged
+	if result = s.ScheduleMaintenance("C1", "W2", "20231114120000", "20231116000000", "AtRisk"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	result = s.SubmitOrder(alice, "O2", "C1", 10*simulator.Mbps)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	records = result.Records(nsc.EventBandwidthAllocated)
+	var change nims.BandwidthChange
+	if len(records) != 1 || json.Unmarshal(records[0].Data, &change) != nil || change.MaintenanceWindowID != "W2" {
+		t.Errorf("expected the allocation of O2 to be flagged with W2, got %+v", records)
+	}
+}
+
+func TestListOpenOutagesOfANetwork(t *testing.T) {
+	s := simulator.NewForTest(t)
+	for _, circuit := range []struct{ id, network string }{{"C1", "NET1"}, {"C2", "NET1"}, {"C3", "NET1"}, {"C4", "NET2"}} {
+		if err := s.SeedCircuit(circuit.id, circuit.network, "Org1MSP", 100*simulator.Mbps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the simulator's transactions are timestamped 20231114221320
+	for _, result := range []simulator.Result{
+		s.ScheduleMaintenance("C1", "W1", "20231114000000", "20231115000000", "Outage"),
+		s.ScheduleMaintenance("C2", "W2", "20231201000000", "20231202000000", "Outage"),
+		s.ScheduleMaintenance("C3", "W3", "20231114000000", "20231115000000", "AtRisk"),
+		s.ScheduleMaintenance("C4", "W4", "20231114000000", "20231115000000", "Outage"),
+	} {
+		if !result.OK() {
+			t.Fatal(result.Error())
+		}
+	}
+
+	result := s.Query(simulator.NIMS, provider, "listOpenOutages", "NET1")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	var windows []nims.MaintenanceWindow
+	if err := json.Unmarshal(result.Response.Payload, &windows); err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 1 || windows[0].CircuitID != "C1" || windows[0].WindowID != "W1" {
+		t.Errorf("expected only W1 of C1 open, got %+v", windows)
+	}
+}
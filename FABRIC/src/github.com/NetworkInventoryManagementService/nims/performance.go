@@ -0,0 +1,98 @@
+package nims
+
+import (
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Circuit Performance - setCircuitPerformance records the latency, jitter, loss and availability of a DataCircuit on the
+// circuit itself, so every inventory listing carries it. The values are measured or contractual as named by Source, a
+// value that is not given is unknown and replaces what was recorded before. Latency and jitter are in microseconds,
+// loss and availability in parts per million. BPM places orders that name SLA constraints only on circuits whose known
+// values meet them, and routes path orders by latency over them.
+// ============================================================================================================================
+
+// performance sources
+const (
+	performanceMeasured    = "measured"
+	performanceContractual = "contractual"
+)
+
+// latency and jitter above this many microseconds, 1000 seconds, are not accepted
+const maxDelay = 1000000000
+
+// loss and availability are in parts per million
+const partsPerMillion = 1000000
+
+var setCircuitPerformanceArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Source", Type: nsc.ArgString, Required: true, Enum: []string{performanceMeasured, performanceContractual}},
+	{Name: "Latency", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(maxDelay)},
+	{Name: "Jitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(maxDelay)},
+	{Name: "Loss", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "Availability", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+// CircuitPerformance is the performance recorded on a DataCircuit, a nil value is unknown
+type CircuitPerformance struct {
+	Latency      *int   `json:"Latency,omitempty"`
+	Jitter       *int   `json:"Jitter,omitempty"`
+	Loss         *int   `json:"Loss,omitempty"`
+	Availability *int   `json:"Availability,omitempty"`
+	Source       string `json:"Source"`
+	UpdatedBy    string `json:"UpdatedBy"`
+	UpdatedOn    string `json:"UpdatedOn"`
+}
+
+func setCircuitPerformance(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setCircuitPerformance")
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	fmt.Println(arguments)
+
+	performance := CircuitPerformance{
+		Latency:      optionalInteger(arguments, "Latency"),
+		Jitter:       optionalInteger(arguments, "Jitter"),
+		Loss:         optionalInteger(arguments, "Loss"),
+		Availability: optionalInteger(arguments, "Availability"),
+		Source:       arguments.Str("Source"),
+	}
+	performance.UpdatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	performance.UpdatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	dataCircuitObject.Performance = &performance
+	err = putDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := dataCircuitToJSON(dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuit to json"))
+	}
+
+	fmt.Println("- end setCircuitPerformance")
+	return shim.Success(buff)
+}
+
+// optionalInteger is an integer argument, nil when it is not given
+func optionalInteger(arguments nsc.FunctionArgs, name string) *int {
+	if !arguments.Has(name) {
+		return nil
+	}
+	value := arguments.Integer(name)
+	return &value
+}
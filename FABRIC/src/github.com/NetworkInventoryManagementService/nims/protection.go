@@ -0,0 +1,268 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Protection Groups - a protected order holds its bandwidth on a primary and a backup DataCircuit. Both are allocated
+// in one call, which refuses circuits of the same provider and, for every attribute named in DiverseOn, circuits that
+// share the attribute value or do not both carry it. The pair is kept under "ProtectionGroup"[OrderID].
+// ============================================================================================================================
+
+const protectionGroupObjectType = "ProtectionGroup"
+
+// comma separated DataCircuit attribute names
+const diverseOnPattern = `^[A-Za-z0-9_]+(,[A-Za-z0-9_]+)*$`
+
+var setDataCircuitAttributeArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Name", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Value", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+}
+
+var allocateProtectedBandwidthArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "PrimaryCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "BackupCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "DiverseOn", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength, Pattern: diverseOnPattern},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	// the backup holds the excess rate as well, it carries the whole profile once it takes over
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+var getProtectionGroupArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type ProtectionGroup struct {
+	OrderID          string        `json:"OrderID"`
+	PrimaryCircuitID string        `json:"PrimaryCircuitID"`
+	BackupCircuitID  string        `json:"BackupCircuitID"`
+	Bandwidth        nsc.Bandwidth `json:"Bandwidth"`
+	DiverseOn        []string      `json:"DiverseOn"`
+	CreatedOn        string        `json:"CreatedOn"`
+	// FailedOverOn is set once the primary failed and the backup took over, the order is then unprotected
+	FailedOverOn string `json:"FailedOverOn,omitempty"`
+}
+
+// setDataCircuitAttribute sets an attribute of a DataCircuit, an empty Value removes it
+func setDataCircuitAttribute(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setDataCircuitAttribute")
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	if arguments.Has("Value") {
+		if dataCircuitObject.Attributes == nil {
+			dataCircuitObject.Attributes = map[string]string{}
+		}
+		dataCircuitObject.Attributes[arguments.Str("Name")] = arguments.Str("Value")
+	} else {
+		delete(dataCircuitObject.Attributes, arguments.Str("Name"))
+	}
+
+	err = putDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := dataCircuitToJSON(dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuit to json"))
+	}
+
+	fmt.Println("- end setDataCircuitAttribute")
+	return shim.Success(buff)
+}
+
+func allocateProtectedBandwidth(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting allocateProtectedBandwidth")
+
+	orderID := arguments.Str("OrderID")
+	bandwidth := arguments.Bandwidth("Bandwidth")
+	fmt.Println(arguments)
+
+	groupKey, err := stub.CreateCompositeKey(protectionGroupObjectType, []string{orderID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	existing, err := stub.GetState(groupKey)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if existing != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Order %s already has a protection group", orderID).WithDetail("OrderID", orderID))
+	}
+
+	primary, err := getDataCircuit(stub, arguments.Str("PrimaryCircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	backup, err := getDataCircuit(stub, arguments.Str("BackupCircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	var diverseOn []string
+	if arguments.Has("DiverseOn") {
+		diverseOn = strings.Split(arguments.Str("DiverseOn"), ",")
+	}
+	err = assertDiverse(primary, backup, diverseOn)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	for _, dataCircuitObject := range []DataCircuit{primary, backup} {
+		err = assertCircuitUp(dataCircuitObject)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	createdOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	for _, dataCircuitObject := range []DataCircuit{primary, backup} {
+		maintenanceWindowID, err := checkMaintenance(stub, dataCircuitObject.CircuitID, createdOn)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if bandwidth > dataCircuitObject.UnallocatedBandwidth {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeInsufficientCapacity, "allocateProtectedBandwidth() : Failed Cannot allocate Data Circuit Bandwidth for write : %s", dataCircuitObject.CircuitID).
+				WithDetail("CircuitID", dataCircuitObject.CircuitID).
+				WithDetail("RequestedBandwidth", bandwidth).
+				WithDetail("UnallocatedBandwidth", dataCircuitObject.UnallocatedBandwidth))
+		}
+		err = assertFitsCalendar(stub, dataCircuitObject, bandwidth, createdOn, arguments.Str("ExpiresOn"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		err = allocateExcess(&dataCircuitObject, arguments.Bandwidth("ExcessBandwidth"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		dataCircuitObject.AllocatedBandwidth = dataCircuitObject.AllocatedBandwidth + bandwidth
+		dataCircuitObject.UnallocatedBandwidth = dataCircuitObject.UnallocatedBandwidth - bandwidth
+
+		err = recordAllocation(stub, dataCircuitObject.CircuitID, orderID, arguments.Str("OperatorID"), bandwidth, arguments.Bandwidth("ExcessBandwidth"), arguments.Str("ExpiresOn"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		err = putDataCircuit(stub, dataCircuitObject)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		change := newBandwidthChange(dataCircuitObject, orderID, bandwidth, "")
+		change.MaintenanceWindowID = maintenanceWindowID
+		change.ExcessBandwidth = arguments.Bandwidth("ExcessBandwidth")
+		err = events.Add(nsc.EventBandwidthAllocated, change)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	group := ProtectionGroup{orderID, primary.CircuitID, backup.CircuitID, bandwidth, diverseOn, createdOn, ""}
+	err = putProtectionGroup(stub, group)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	err = events.Add(nsc.EventProtectionGroupCreated, group)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end allocateProtectedBandwidth")
+	return events.Emit(stub)
+}
+
+func getProtectionGroup(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	orderID := arguments.Str("OrderID")
+
+	groupKey, err := stub.CreateCompositeKey(protectionGroupObjectType, []string{orderID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	groupAsBytes, err := stub.GetState(groupKey)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if groupAsBytes == nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeNotFound, "Order %s has no protection group", orderID).WithDetail("OrderID", orderID))
+	}
+	return shim.Success(groupAsBytes)
+}
+
+// assertDiverse refuses a primary and backup on the same circuit or provider, or not diverse on an attribute
+func assertDiverse(primary DataCircuit, backup DataCircuit, diverseOn []string) error {
+	if primary.CircuitID == backup.CircuitID {
+		return nsc.NewError(nsc.CodeDiversityViolation, "the primary and backup of a protected order must be different circuits").
+			WithDetail("CircuitID", primary.CircuitID)
+	}
+	if primary.ProviderID == backup.ProviderID {
+		return nsc.NewError(nsc.CodeDiversityViolation, "%s and %s are both provided by %s", primary.CircuitID, backup.CircuitID, primary.ProviderID).
+			WithDetail("PrimaryCircuitID", primary.CircuitID).
+			WithDetail("BackupCircuitID", backup.CircuitID).
+			WithDetail("ProviderID", primary.ProviderID)
+	}
+	for _, attribute := range diverseOn {
+		primaryValue, backupValue := primary.Attributes[attribute], backup.Attributes[attribute]
+		if primaryValue == "" || backupValue == "" || primaryValue == backupValue {
+			return nsc.NewError(nsc.CodeDiversityViolation, "%s and %s are not diverse on %s", primary.CircuitID, backup.CircuitID, attribute).
+				WithDetail("Attribute", attribute).
+				WithDetail("PrimaryValue", primaryValue).
+				WithDetail("BackupValue", backupValue)
+		}
+	}
+	return nil
+}
+
+// getProtectionGroupState reads the protection group of an order, nil when the order is not protected
+func getProtectionGroupState(stub shim.ChaincodeStubInterface, orderID string) (*ProtectionGroup, error) {
+	groupKey, err := stub.CreateCompositeKey(protectionGroupObjectType, []string{orderID})
+	if err != nil {
+		return nil, err
+	}
+
+	groupAsBytes, err := stub.GetState(groupKey)
+	if err != nil {
+		return nil, err
+	}
+	if groupAsBytes == nil {
+		return nil, nil
+	}
+
+	var group ProtectionGroup
+	err = json.Unmarshal(groupAsBytes, &group)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInternal, "unable to read protection group of order %s: %s", orderID, err.Error())
+	}
+	return &group, nil
+}
+
+func putProtectionGroup(stub shim.ChaincodeStubInterface, group ProtectionGroup) error {
+	groupKey, err := stub.CreateCompositeKey(protectionGroupObjectType, []string{group.OrderID})
+	if err != nil {
+		return err
+	}
+
+	buff, err := json.Marshal(group)
+	if err != nil {
+		return nsc.NewError(nsc.CodeInternal, "unable to convert ProtectionGroup to json")
+	}
+	return stub.PutState(groupKey, buff)
+}
@@ -0,0 +1,69 @@
+package nims_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestInventoryWritesRequireAdmin(t *testing.T) {
+	s := simulator.NewForTest(t)
+	operator := simulator.OperatorIdentity("alice")
+
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "addNewDataCircuit", "C1", "NET1", "Org1MSP", "100M"), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "addDevice", "R1", "router 1", ""), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "setServiceClass", "gold", "200"), "FORBIDDEN")
+
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "setDataCircuitStatus", "C1", "Down"), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "resizeDataCircuit", "C1", "1G"), "FORBIDDEN")
+}
+
+func TestAllocationsAreMadeThroughBPM(t *testing.T) {
+	s := simulator.NewForTest(t)
+	operator := simulator.OperatorIdentity("alice")
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "allocateDataCircuitBandwidth", "C1", "10M", "O1", "", "alice"), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, operator, "bookBandwidth", "C1", "B1", "10M", "20990101000000", "20990102000000", "", "alice"), "FORBIDDEN")
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	if result := s.PlaceOrder(operator, "O1", "NET1", "", 10*simulator.Mbps, ""); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if result := s.BookBandwidth(operator, "C1", "B1", 10*simulator.Mbps, "20990101000000", "20990102000000"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if err := s.ExpectBandwidth("C1", 10*simulator.Mbps, 90*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCircuitWritesRequireProvider(t *testing.T) {
+	s := simulator.NewForTest(t)
+	otherAdmin := simulator.ProviderAdminIdentity("Org2MSP")
+
+	expectCode(t, s.Invoke(simulator.NIMS, otherAdmin, "addNewDataCircuit", "C1", "NET1", "Org1MSP", "100M"), "FORBIDDEN")
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+
+	expectCode(t, s.Invoke(simulator.NIMS, otherAdmin, "setDataCircuitStatus", "C1", "Down"), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, otherAdmin, "resizeDataCircuit", "C1", "1G"), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, otherAdmin, "releaseDataCircuitBandwidth", "C1", "", "O1"), "FORBIDDEN")
+	expectCode(t, s.Invoke(simulator.NIMS, otherAdmin, "scheduleMaintenanceWindow", "C1", "W1", "20990101000000", "20990102000000", "Outage"), "FORBIDDEN")
+
+	result := s.Invoke(simulator.NIMS, simulator.ProviderAdminIdentity("Org1MSP"), "resizeDataCircuit", "C1", "1G")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if err := s.ExpectBandwidth("C1", 0, simulator.Gbps); err != nil {
+		t.Fatal(err)
+	}
+}
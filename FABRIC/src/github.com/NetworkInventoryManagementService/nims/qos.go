@@ -0,0 +1,50 @@
+package nims
+
+import "github.com/NetworkServiceCommon/nsc"
+
+// ============================================================================================================================
+// QoS Bandwidth Profiles - the bandwidth of an allocation is the committed rate (CIR) of its order and is what circuits
+// are filled with, oversubscribed or not. The excess rate (EIR) an order may burst to is held apart in the ExcessBandwidth
+// of the allocation and of the circuit, capped by the circuit's ExcessLimit: TotalBandwidth times the ExcessPercent of its
+// service class, the physical line rate without one. Excess is released with the last of the committed rate. Burst size
+// and class of service do not take capacity, ANCS hands them to the device agent.
+// ============================================================================================================================
+
+// excessLimit is the excess rate a circuit can carry, its TotalBandwidth unless its service class sets another share
+func excessLimit(dataCircuitObject DataCircuit) nsc.Bandwidth {
+	if dataCircuitObject.ExcessLimit == 0 {
+		return dataCircuitObject.TotalBandwidth
+	}
+	return dataCircuitObject.ExcessLimit
+}
+
+// allocateExcess adds excess rate to a circuit, INSUFFICIENT_CAPACITY when it goes over the circuit's excess limit
+func allocateExcess(dataCircuitObject *DataCircuit, excess nsc.Bandwidth) error {
+	if excess == 0 {
+		return nil
+	}
+	if dataCircuitObject.ExcessBandwidth+excess > excessLimit(*dataCircuitObject) {
+		return nsc.NewError(nsc.CodeInsufficientCapacity, "%s has %s of excess rate left, cannot allocate %s", dataCircuitObject.CircuitID, excessLimit(*dataCircuitObject)-dataCircuitObject.ExcessBandwidth, excess).
+			WithDetail("CircuitID", dataCircuitObject.CircuitID).
+			WithDetail("RequestedExcessBandwidth", excess).
+			WithDetail("ExcessBandwidth", dataCircuitObject.ExcessBandwidth).
+			WithDetail("ExcessLimit", excessLimit(*dataCircuitObject))
+	}
+	dataCircuitObject.ExcessBandwidth = dataCircuitObject.ExcessBandwidth + excess
+	return nil
+}
+
+// releaseExcess returns the excess rate of an allocation to its circuit once none of its committed rate is left and
+// returns the amount released
+func releaseExcess(dataCircuitObject *DataCircuit, allocation *Allocation) nsc.Bandwidth {
+	if allocation.Bandwidth > 0 || allocation.ExcessBandwidth == 0 {
+		return 0
+	}
+	released := allocation.ExcessBandwidth
+	if released > dataCircuitObject.ExcessBandwidth {
+		released = dataCircuitObject.ExcessBandwidth
+	}
+	allocation.ExcessBandwidth = 0
+	dataCircuitObject.ExcessBandwidth = dataCircuitObject.ExcessBandwidth - released
+	return released
+}
@@ -0,0 +1,420 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Service Classes - a class sells a circuit's physical bandwidth OversubscriptionPercent/100 times over, 200 is 2:1. A
+// class is set on a circuit, or on a network for every circuit of it that has none of its own. The sellable bandwidth,
+// TotalBandwidth times the ratio, is what allocations, bookings and UnallocatedBandwidth are counted against, so BPM
+// places on sold capacity without knowing about classes. Circuits without a class are sold 1:1. Changing a class or its
+// assignment refits every circuit it applies to and never leaves one with more sold than sellable. ExcessPercent caps the
+// excess rate of the circuits' bandwidth profiles at a share of TotalBandwidth, see qos.go.
+// ============================================================================================================================
+
+const serviceClassObjectType = "ServiceClass"
+
+const networkServiceClassObjectType = "NetworkServiceClass"
+
+// the ratio of a circuit without a class
+const noOversubscription = 100
+
+const maxOversubscriptionPercent = 1000
+
+var setServiceClassArguments = nsc.ArgumentSchema{
+	{Name: "ClassName", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OversubscriptionPercent", Type: nsc.ArgInteger, Required: true, Minimum: nsc.Bound(noOversubscription), Maximum: nsc.Bound(maxOversubscriptionPercent)},
+	{Name: "Description", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+	// defaults to 100, excess up to the line rate
+	{Name: "ExcessPercent", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(maxOversubscriptionPercent)},
+}
+
+// an empty ClassName removes the assignment
+var setCircuitServiceClassArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ClassName", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var setNetworkServiceClassArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ClassName", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// without a CircuitNetwork every circuit is reported
+var getCapacityReportArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type ServiceClass struct {
+	ClassName               string `json:"ClassName"`
+	OversubscriptionPercent int    `json:"OversubscriptionPercent"`
+	Description             string `json:"Description,omitempty"`
+	UpdatedOn               string `json:"UpdatedOn"`
+	ExcessPercent           int    `json:"ExcessPercent"`
+}
+
+type NetworkServiceClass struct {
+	CircuitNetwork string `json:"CircuitNetwork"`
+	ClassName      string `json:"ClassName"`
+}
+
+// CircuitCapacity reports the physical and the sold utilization of a circuit, both in percent
+type CircuitCapacity struct {
+	CircuitID               string        `json:"CircuitID"`
+	CircuitNetwork          string        `json:"CircuitNetwork"`
+	ProviderID              string        `json:"ProviderID"`
+	ServiceClass            string        `json:"ServiceClass,omitempty"`
+	OversubscriptionPercent int           `json:"OversubscriptionPercent"`
+	TotalBandwidth          nsc.Bandwidth `json:"TotalBandwidth"`
+	SellableBandwidth       nsc.Bandwidth `json:"SellableBandwidth"`
+	AllocatedBandwidth      nsc.Bandwidth `json:"AllocatedBandwidth"`
+	UnallocatedBandwidth    nsc.Bandwidth `json:"UnallocatedBandwidth"`
+	ExcessBandwidth         nsc.Bandwidth `json:"ExcessBandwidth"`
+	ExcessLimit             nsc.Bandwidth `json:"ExcessLimit"`
+	PhysicalUtilization     int           `json:"PhysicalUtilization"`
+	SoldUtilization         int           `json:"SoldUtilization"`
+}
+
+type CapacityReport struct {
+	CircuitNetwork      string            `json:"CircuitNetwork,omitempty"`
+	Circuits            []CircuitCapacity `json:"Circuits"`
+	TotalBandwidth      nsc.Bandwidth     `json:"TotalBandwidth"`
+	SellableBandwidth   nsc.Bandwidth     `json:"SellableBandwidth"`
+	AllocatedBandwidth  nsc.Bandwidth     `json:"AllocatedBandwidth"`
+	PhysicalUtilization int               `json:"PhysicalUtilization"`
+	SoldUtilization     int               `json:"SoldUtilization"`
+}
+
+// setServiceClass adds a class or changes its ratio, refitting the circuits it applies to
+func setServiceClass(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setServiceClass")
+
+	updatedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	class := ServiceClass{
+		ClassName:               arguments.Str("ClassName"),
+		OversubscriptionPercent: arguments.Integer("OversubscriptionPercent"),
+		Description:             arguments.Str("Description"),
+		UpdatedOn:               updatedOn,
+		ExcessPercent:           noOversubscription,
+	}
+	if arguments.Has("ExcessPercent") {
+		class.ExcessPercent = arguments.Integer("ExcessPercent")
+	}
+
+	classKey, err := stub.CreateCompositeKey(serviceClassObjectType, []string{class.ClassName})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	buff, err := json.Marshal(class)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert ServiceClass to json"))
+	}
+	err = stub.PutState(classKey, buff)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	dataCircuits, err := listIndexedDataCircuits(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	events := nsc.NewEventBatch(stub, chaincodeName, class.ClassName)
+	for _, dataCircuitObject := range dataCircuits {
+		className, err := resolveServiceClassName(stub, dataCircuitObject)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if className != class.ClassName {
+			continue
+		}
+		err = refitDataCircuit(stub, events, dataCircuitObject, &class)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	fmt.Println("- end setServiceClass")
+	return events.Emit(stub)
+}
+
+func setCircuitServiceClass(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setCircuitServiceClass")
+
+	dataCircuitObject, err := getProvidedDataCircuit(stub, arguments.Str("CircuitID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if arguments.Has("ClassName") {
+		_, err = getServiceClass(stub, arguments.Str("ClassName"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+	dataCircuitObject.ServiceClass = arguments.Str("ClassName")
+
+	class, err := resolveServiceClass(stub, dataCircuitObject)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	events := nsc.NewEventBatch(stub, chaincodeName, dataCircuitObject.CircuitID)
+	err = refitDataCircuit(stub, events, dataCircuitObject, class)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end setCircuitServiceClass")
+	return events.Emit(stub)
+}
+
+func setNetworkServiceClass(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setNetworkServiceClass")
+
+	circuitNetwork := arguments.Str("CircuitNetwork")
+	networkKey, err := stub.CreateCompositeKey(networkServiceClassObjectType, []string{circuitNetwork})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	// the assignment is not read back, a transaction does not see its own writes
+	var class *ServiceClass
+	if arguments.Has("ClassName") {
+		networkClass, err := getServiceClass(stub, arguments.Str("ClassName"))
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		class = &networkClass
+		buff, err := json.Marshal(NetworkServiceClass{circuitNetwork, arguments.Str("ClassName")})
+		if err != nil {
+			return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert NetworkServiceClass to json"))
+		}
+		err = stub.PutState(networkKey, buff)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	} else {
+		err = stub.DelState(networkKey)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	dataCircuits, err := listIndexedDataCircuits(stub, circuitNetwork)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	events := nsc.NewEventBatch(stub, chaincodeName, circuitNetwork)
+	for _, dataCircuitObject := range dataCircuits {
+		// circuits with a class of their own keep it
+		if dataCircuitObject.ServiceClass != "" {
+			continue
+		}
+		err = refitDataCircuit(stub, events, dataCircuitObject, class)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	fmt.Println("- end setNetworkServiceClass")
+	return events.Emit(stub)
+}
+
+// getCapacityReport reports the physical and sold utilization of the circuits of a network, or of every circuit
+func getCapacityReport(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	attributes := []string{}
+	if arguments.Has("CircuitNetwork") {
+		attributes = append(attributes, arguments.Str("CircuitNetwork"))
+	}
+	dataCircuits, err := listIndexedDataCircuits(stub, attributes...)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	report := CapacityReport{CircuitNetwork: arguments.Str("CircuitNetwork"), Circuits: []CircuitCapacity{}}
+	for _, dataCircuitObject := range dataCircuits {
+		class, err := resolveServiceClass(stub, dataCircuitObject)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		capacity := CircuitCapacity{
+			CircuitID:               dataCircuitObject.CircuitID,
+			CircuitNetwork:          dataCircuitObject.CircuitNetwork,
+			ProviderID:              dataCircuitObject.ProviderID,
+			OversubscriptionPercent: noOversubscription,
+			TotalBandwidth:          dataCircuitObject.TotalBandwidth,
+			SellableBandwidth:       sellableBandwidth(dataCircuitObject),
+			AllocatedBandwidth:      dataCircuitObject.AllocatedBandwidth,
+			UnallocatedBandwidth:    dataCircuitObject.UnallocatedBandwidth,
+			ExcessBandwidth:         dataCircuitObject.ExcessBandwidth,
+			ExcessLimit:             excessLimit(dataCircuitObject),
+		}
+		if class != nil {
+			capacity.ServiceClass = class.ClassName
+			capacity.OversubscriptionPercent = class.OversubscriptionPercent
+		}
+		capacity.PhysicalUtilization = utilizationPercent(capacity.AllocatedBandwidth, capacity.TotalBandwidth)
+		capacity.SoldUtilization = utilizationPercent(capacity.AllocatedBandwidth, capacity.SellableBandwidth)
+
+		report.Circuits = append(report.Circuits, capacity)
+		report.TotalBandwidth = report.TotalBandwidth + capacity.TotalBandwidth
+		report.SellableBandwidth = report.SellableBandwidth + capacity.SellableBandwidth
+		report.AllocatedBandwidth = report.AllocatedBandwidth + capacity.AllocatedBandwidth
+	}
+	report.PhysicalUtilization = utilizationPercent(report.AllocatedBandwidth, report.TotalBandwidth)
+	report.SoldUtilization = utilizationPercent(report.AllocatedBandwidth, report.SellableBandwidth)
+
+	reportAsBytes, err := json.Marshal(report)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CapacityReport to json"))
+	}
+	return shim.Success(reportAsBytes)
+}
+
+// refitDataCircuit recounts the sellable and unallocated bandwidth of a circuit for its class, nil when it has none,
+// stores it and adds a CircuitResized record when its sellable bandwidth changed
+func refitDataCircuit(stub shim.ChaincodeStubInterface, events *nsc.EventBatch, dataCircuitObject DataCircuit, class *ServiceClass) error {
+	resize := CircuitResize{
+		CircuitID:                 dataCircuitObject.CircuitID,
+		PreviousTotalBandwidth:    dataCircuitObject.TotalBandwidth,
+		TotalBandwidth:            dataCircuitObject.TotalBandwidth,
+		PreviousSellableBandwidth: sellableBandwidth(dataCircuitObject),
+	}
+
+	err := applyServiceClass(&dataCircuitObject, class, dataCircuitObject.TotalBandwidth)
+	if err != nil {
+		return err
+	}
+	err = putDataCircuit(stub, dataCircuitObject)
+	if err != nil {
+		return err
+	}
+
+	resize.SellableBandwidth = sellableBandwidth(dataCircuitObject)
+	if resize.SellableBandwidth == resize.PreviousSellableBandwidth {
+		return nil
+	}
+	resize.ServiceClass = dataCircuitObject.ServiceClass
+	if class != nil {
+		resize.ServiceClass = class.ClassName
+	}
+	resize.AllocatedBandwidth = dataCircuitObject.AllocatedBandwidth
+	resize.UnallocatedBandwidth = dataCircuitObject.UnallocatedBandwidth
+	return events.Add(nsc.EventCircuitResized, resize)
+}
+
+// applyServiceClass sizes a circuit to totalBandwidth sold at the ratio of class, refusing to sell less than is allocated
+func applyServiceClass(dataCircuitObject *DataCircuit, class *ServiceClass, totalBandwidth nsc.Bandwidth) error {
+	percent, excessPercent := noOversubscription, noOversubscription
+	if class != nil {
+		percent, excessPercent = class.OversubscriptionPercent, class.ExcessPercent
+	}
+
+	sellable := totalBandwidth.PercentOf(percent)
+	if sellable < dataCircuitObject.AllocatedBandwidth {
+		return nsc.NewError(nsc.CodeConflict, "%s would sell %s but has %s allocated", dataCircuitObject.CircuitID, sellable, dataCircuitObject.AllocatedBandwidth).
+			WithDetail("CircuitID", dataCircuitObject.CircuitID).
+			WithDetail("TotalBandwidth", totalBandwidth).
+			WithDetail("SellableBandwidth", sellable).
+			WithDetail("AllocatedBandwidth", dataCircuitObject.AllocatedBandwidth)
+	}
+
+	limit := totalBandwidth.PercentOf(excessPercent)
+	if limit < dataCircuitObject.ExcessBandwidth {
+		return nsc.NewError(nsc.CodeConflict, "%s would carry %s of excess rate but has %s allocated", dataCircuitObject.CircuitID, limit, dataCircuitObject.ExcessBandwidth).
+			WithDetail("CircuitID", dataCircuitObject.CircuitID).
+			WithDetail("ExcessLimit", limit).
+			WithDetail("ExcessBandwidth", dataCircuitObject.ExcessBandwidth)
+	}
+
+	dataCircuitObject.TotalBandwidth = totalBandwidth
+	dataCircuitObject.ExcessLimit = 0
+	if limit != totalBandwidth {
+		dataCircuitObject.ExcessLimit = limit
+	}
+	dataCircuitObject.SellableBandwidth = 0
+	if sellable != totalBandwidth {
+		dataCircuitObject.SellableBandwidth = sellable
+	}
+	dataCircuitObject.UnallocatedBandwidth = sellable - dataCircuitObject.AllocatedBandwidth
+	return nil
+}
+
+// sellableBandwidth is the bandwidth a circuit can sell, its TotalBandwidth when it is not oversubscribed
+func sellableBandwidth(dataCircuitObject DataCircuit) nsc.Bandwidth {
+	if dataCircuitObject.SellableBandwidth == 0 {
+		return dataCircuitObject.TotalBandwidth
+	}
+	return dataCircuitObject.SellableBandwidth
+}
+
+func utilizationPercent(allocated nsc.Bandwidth, capacity nsc.Bandwidth) int {
+	if capacity == 0 {
+		return 0
+	}
+	return int(allocated * 100 / capacity)
+}
+
+// resolveServiceClass returns the class of a circuit, its own or its network's, nil when it has none
+func resolveServiceClass(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit) (*ServiceClass, error) {
+	className, err := resolveServiceClassName(stub, dataCircuitObject)
+	if err != nil || className == "" {
+		return nil, err
+	}
+	class, err := getServiceClass(stub, className)
+	if err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+func resolveServiceClassName(stub shim.ChaincodeStubInterface, dataCircuitObject DataCircuit) (string, error) {
+	if dataCircuitObject.ServiceClass != "" {
+		return dataCircuitObject.ServiceClass, nil
+	}
+
+	networkKey, err := stub.CreateCompositeKey(networkServiceClassObjectType, []string{dataCircuitObject.CircuitNetwork})
+	if err != nil {
+		return "", err
+	}
+	networkAsBytes, err := stub.GetState(networkKey)
+	if err != nil || networkAsBytes == nil {
+		return "", err
+	}
+
+	var network NetworkServiceClass
+	err = json.Unmarshal(networkAsBytes, &network)
+	if err != nil {
+		return "", nsc.NewError(nsc.CodeInternal, "unable to read the service class of network %s: %s", dataCircuitObject.CircuitNetwork, err.Error())
+	}
+	return network.ClassName, nil
+}
+
+func getServiceClass(stub shim.ChaincodeStubInterface, className string) (ServiceClass, error) {
+	var class ServiceClass
+
+	classKey, err := stub.CreateCompositeKey(serviceClassObjectType, []string{className})
+	if err != nil {
+		return class, err
+	}
+	classAsBytes, err := stub.GetState(classKey)
+	if err != nil {
+		return class, err
+	}
+	if classAsBytes == nil {
+		return class, nsc.NewError(nsc.CodeNotFound, "Service class %s does not exist", className).WithDetail("ClassName", className)
+	}
+
+	err = json.Unmarshal(classAsBytes, &class)
+	if err != nil {
+		return class, nsc.NewError(nsc.CodeInternal, "unable to read service class %s: %s", className, err.Error())
+	}
+	return class, nil
+}
@@ -0,0 +1,56 @@
+package nims_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestServiceClassesOversellAndNeverUndersell(t *testing.T) {
+	s := simulator.NewForTest(t)
+	for _, circuitID := range []string{"C1", "C2"} {
+		if err := s.SeedCircuit(circuitID, "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if result := s.Invoke(simulator.NIMS, simulator.AdminIdentity, "setServiceClass", "gold", "100"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if result := s.Invoke(simulator.NIMS, provider, "setCircuitServiceClass", "C2", "gold"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+
+	// the network's class applies to C1 only, C2 keeps its own
+	if err := s.SetServiceClass("bronze", 200, "NET1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ExpectBandwidth("C1", 0, 200*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+	if err := s.ExpectBandwidth("C2", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	if result := s.SubmitOrder(simulator.OperatorIdentity("alice"), "O1", "C1", 150*simulator.Mbps); !result.OK() {
+		t.Fatal(result.Error())
+	}
+
+	// neither a lower ratio nor removing the class may leave C1 with more sold than sellable
+	expectCode(t, s.Invoke(simulator.NIMS, simulator.AdminIdentity, "setServiceClass", "bronze", "120"), nsc.CodeConflict)
+	expectCode(t, s.Invoke(simulator.NIMS, simulator.AdminIdentity, "setNetworkServiceClass", "NET1", ""), nsc.CodeConflict)
+	if err := s.ExpectBandwidth("C1", 150*simulator.Mbps, 50*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	if result := s.Invoke(simulator.NIMS, simulator.AdminIdentity, "setServiceClass", "bronze", "150"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	circuit, err := s.Circuit("C1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if circuit.SellableBandwidth != 150*simulator.Mbps || circuit.UnallocatedBandwidth != 0 {
+		t.Errorf("expected C1 to sell 150M, all of it allocated, got %+v", circuit)
+	}
+}
@@ -0,0 +1,465 @@
+package nims
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Topology - a Site is a location circuits terminate at, kept under "Site"[SiteID]. A DataCircuit is linked between two
+// different sites by its A-end and Z-end Endpoint, kept under "Endpoint"[CircuitID, End], and indexed from both ends
+// under "SiteCircuit"[SiteID, CircuitID] with the site at the far end as value. The index answers the topology queries:
+// the neighbours of a site and the circuits between two sites, in either direction. Circuits are linked when they are
+// added with ASiteID and ZSiteID or later with setCircuitEndpoints, which moves a linked circuit to its new sites.
+// listCircuitLinks returns every linked circuit of a network at once, BPM computes paths between sites over them.
+// ============================================================================================================================
+
+const (
+	siteObjectType     = "Site"
+	endpointObjectType = "Endpoint"
+	siteCircuitIndex   = "SiteCircuit"
+)
+
+// circuit ends
+const (
+	endA = "A"
+	endZ = "Z"
+)
+
+var addSiteArguments = nsc.ArgumentSchema{
+	{Name: "SiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Name", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxNameLength},
+	{Name: "Address", Type: nsc.ArgString, MaxLength: 512},
+}
+
+var getSiteArguments = nsc.ArgumentSchema{
+	{Name: "SiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// the handoffs describe where the circuit is handed over at each end, such as a port or a cross connect
+var setCircuitEndpointsArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ASiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ZSiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "AHandoff", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+	{Name: "ZHandoff", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength},
+}
+
+var getCircuitEndpointsArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var getSiteNeighboursArguments = nsc.ArgumentSchema{
+	{Name: "SiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var listCircuitLinksArguments = nsc.ArgumentSchema{
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+var getCircuitsBetweenSitesArguments = nsc.ArgumentSchema{
+	{Name: "SiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OtherSiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+type Site struct {
+	SiteID    string `json:"SiteID"`
+	Name      string `json:"Name"`
+	Address   string `json:"Address,omitempty"`
+	CreatedBy string `json:"CreatedBy"`
+	CreatedOn string `json:"CreatedOn"`
+}
+
+type Endpoint struct {
+	CircuitID string `json:"CircuitID"`
+	End       string `json:"End"`
+	SiteID    string `json:"SiteID"`
+	Handoff   string `json:"Handoff,omitempty"`
+	UpdatedOn string `json:"UpdatedOn"`
+}
+
+// CircuitEndpoints is returned by setCircuitEndpoints and getCircuitEndpoints, the ends are nil for an unlinked circuit
+type CircuitEndpoints struct {
+	CircuitID string    `json:"CircuitID"`
+	AEnd      *Endpoint `json:"AEnd"`
+	ZEnd      *Endpoint `json:"ZEnd"`
+}
+
+// SiteNeighbour is a site one circuit away, with every circuit between the two sites
+type SiteNeighbour struct {
+	SiteID     string   `json:"SiteID"`
+	CircuitIDs []string `json:"CircuitIDs"`
+}
+
+// CircuitLink is a linked DataCircuit with the sites at its ends
+type CircuitLink struct {
+	CircuitID string `json:"CircuitID"`
+	ASiteID   string `json:"ASiteID"`
+	ZSiteID   string `json:"ZSiteID"`
+}
+
+func addSite(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting addSite")
+
+	siteID := arguments.Str("SiteID")
+	existing, err := getSiteState(stub, siteID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	if existing != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeConflict, "Site %s already exists", siteID).WithDetail("SiteID", siteID))
+	}
+
+	site := Site{SiteID: siteID, Name: arguments.Str("Name"), Address: arguments.Str("Address")}
+	site.CreatedBy, err = nsc.GetInvokerID(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	site.CreatedOn, err = getTxTimestamp(stub)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	siteKey, err := stub.CreateCompositeKey(siteObjectType, []string{siteID})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	buff, err := json.Marshal(site)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert Site to json"))
+	}
+	err = stub.PutState(siteKey, buff)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end addSite")
+	return shim.Success(buff)
+}
+
+func getSite(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	site, err := getExistingSite(stub, arguments.Str("SiteID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(site)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert Site to json"))
+	}
+	return shim.Success(buff)
+}
+
+// setCircuitEndpoints links a DataCircuit between two sites, replacing the sites it was linked between
+func setCircuitEndpoints(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting setCircuitEndpoints")
+
+	dataCircuitID := arguments.Str("CircuitID")
+	fmt.Println(arguments)
+
+	_, err := getProvidedDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	endpoints, err := linkDataCircuit(stub, dataCircuitID, arguments)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(endpoints)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CircuitEndpoints to json"))
+	}
+
+	fmt.Println("- end setCircuitEndpoints")
+	return shim.Success(buff)
+}
+
+func getCircuitEndpoints(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	dataCircuitID := arguments.Str("CircuitID")
+	_, err := getDataCircuit(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	endpoints, err := getCircuitEndpointsState(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(endpoints)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert CircuitEndpoints to json"))
+	}
+	return shim.Success(buff)
+}
+
+// getSiteNeighbours returns the sites linked to a site by at least one circuit, by SiteID
+func getSiteNeighbours(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	siteID := arguments.Str("SiteID")
+	_, err := getExistingSite(stub, siteID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	links, err := getSiteLinks(stub, siteID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	neighbours := []SiteNeighbour{}
+	positions := map[string]int{}
+	for _, link := range links {
+		position, ok := positions[link.farSiteID]
+		if !ok {
+			position = len(neighbours)
+			positions[link.farSiteID] = position
+			neighbours = append(neighbours, SiteNeighbour{SiteID: link.farSiteID, CircuitIDs: []string{}})
+		}
+		neighbours[position].CircuitIDs = append(neighbours[position].CircuitIDs, link.circuitID)
+	}
+	sort.Slice(neighbours, func(i, j int) bool {
+		return neighbours[i].SiteID < neighbours[j].SiteID
+	})
+
+	buff, err := json.Marshal(neighbours)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert site neighbours to json"))
+	}
+	return shim.Success(buff)
+}
+
+// getCircuitsBetweenSites returns the DataCircuits linking two sites whichever end is at which site, by CircuitID
+func getCircuitsBetweenSites(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	siteID, otherSiteID := arguments.Str("SiteID"), arguments.Str("OtherSiteID")
+	for _, id := range []string{siteID, otherSiteID} {
+		_, err := getExistingSite(stub, id)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+	}
+
+	links, err := getSiteLinks(stub, siteID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	dataCircuits := []DataCircuit{}
+	for _, link := range links {
+		if link.farSiteID != otherSiteID {
+			continue
+		}
+		dataCircuitObject, err := getDataCircuit(stub, link.circuitID)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		dataCircuits = append(dataCircuits, dataCircuitObject)
+	}
+
+	buff, err := json.Marshal(dataCircuits)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert DataCircuits to json"))
+	}
+	return shim.Success(buff)
+}
+
+// listCircuitLinks returns the circuits of a network that are linked between two sites, by CircuitID
+func listCircuitLinks(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	dataCircuits, err := listIndexedDataCircuits(stub, arguments.Str("CircuitNetwork"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	links := []CircuitLink{}
+	for _, dataCircuitObject := range dataCircuits {
+		endpoints, err := getCircuitEndpointsState(stub, dataCircuitObject.CircuitID)
+		if err != nil {
+			return nsc.ErrorResponse(err)
+		}
+		if endpoints.AEnd == nil || endpoints.ZEnd == nil {
+			continue
+		}
+		links = append(links, CircuitLink{dataCircuitObject.CircuitID, endpoints.AEnd.SiteID, endpoints.ZEnd.SiteID})
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].CircuitID < links[j].CircuitID
+	})
+
+	buff, err := json.Marshal(links)
+	if err != nil {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInternal, "unable to convert circuit links to json"))
+	}
+	return shim.Success(buff)
+}
+
+// linkDataCircuit writes the endpoints of a circuit from the ASiteID, ZSiteID, AHandoff and ZHandoff arguments and
+// moves its index entries to the new sites. The caller checks that the circuit exists.
+func linkDataCircuit(stub shim.ChaincodeStubInterface, dataCircuitID string, arguments nsc.FunctionArgs) (CircuitEndpoints, error) {
+	aSiteID, zSiteID := arguments.Str("ASiteID"), arguments.Str("ZSiteID")
+	endpoints := CircuitEndpoints{CircuitID: dataCircuitID}
+	if aSiteID == zSiteID {
+		return endpoints, nsc.NewError(nsc.CodeInvalidArgument, "the A-end and Z-end of %s must be at different sites", dataCircuitID).
+			WithDetail("CircuitID", dataCircuitID).
+			WithDetail("SiteID", aSiteID)
+	}
+	for _, siteID := range []string{aSiteID, zSiteID} {
+		_, err := getExistingSite(stub, siteID)
+		if err != nil {
+			return endpoints, err
+		}
+	}
+
+	previous, err := getCircuitEndpointsState(stub, dataCircuitID)
+	if err != nil {
+		return endpoints, err
+	}
+	for _, endpoint := range []*Endpoint{previous.AEnd, previous.ZEnd} {
+		if endpoint == nil {
+			continue
+		}
+		indexKey, err := stub.CreateCompositeKey(siteCircuitIndex, []string{endpoint.SiteID, dataCircuitID})
+		if err != nil {
+			return endpoints, err
+		}
+		err = stub.DelState(indexKey)
+		if err != nil {
+			return endpoints, err
+		}
+	}
+
+	updatedOn, err := getTxTimestamp(stub)
+	if err != nil {
+		return endpoints, err
+	}
+	endpoints.AEnd = &Endpoint{dataCircuitID, endA, aSiteID, arguments.Str("AHandoff"), updatedOn}
+	endpoints.ZEnd = &Endpoint{dataCircuitID, endZ, zSiteID, arguments.Str("ZHandoff"), updatedOn}
+
+	for _, endpoint := range []*Endpoint{endpoints.AEnd, endpoints.ZEnd} {
+		farSiteID := zSiteID
+		if endpoint.End == endZ {
+			farSiteID = aSiteID
+		}
+
+		endpointKey, err := stub.CreateCompositeKey(endpointObjectType, []string{dataCircuitID, endpoint.End})
+		if err != nil {
+			return endpoints, err
+		}
+		buff, err := json.Marshal(endpoint)
+		if err != nil {
+			return endpoints, nsc.NewError(nsc.CodeInternal, "unable to convert Endpoint to json")
+		}
+		err = stub.PutState(endpointKey, buff)
+		if err != nil {
+			return endpoints, err
+		}
+
+		indexKey, err := stub.CreateCompositeKey(siteCircuitIndex, []string{endpoint.SiteID, dataCircuitID})
+		if err != nil {
+			return endpoints, err
+		}
+		err = stub.PutState(indexKey, []byte(farSiteID))
+		if err != nil {
+			return endpoints, err
+		}
+	}
+	return endpoints, nil
+}
+
+// getSiteState returns nil when the site does not exist
+func getSiteState(stub shim.ChaincodeStubInterface, siteID string) (*Site, error) {
+	siteKey, err := stub.CreateCompositeKey(siteObjectType, []string{siteID})
+	if err != nil {
+		return nil, err
+	}
+	siteAsBytes, err := stub.GetState(siteKey)
+	if err != nil {
+		return nil, err
+	}
+	if siteAsBytes == nil {
+		return nil, nil
+	}
+
+	var site Site
+	err = json.Unmarshal(siteAsBytes, &site)
+	if err != nil {
+		return nil, nsc.NewError(nsc.CodeInternal, "unable to read Site %s: %s", siteID, err.Error())
+	}
+	return &site, nil
+}
+
+// getExistingSite is getSiteState with NOT_FOUND for a missing site
+func getExistingSite(stub shim.ChaincodeStubInterface, siteID string) (Site, error) {
+	site, err := getSiteState(stub, siteID)
+	if err != nil {
+		return Site{}, err
+	}
+	if site == nil {
+		return Site{}, nsc.NewError(nsc.CodeNotFound, "Site %s does not exist", siteID).WithDetail("SiteID", siteID)
+	}
+	return *site, nil
+}
+
+func getCircuitEndpointsState(stub shim.ChaincodeStubInterface, dataCircuitID string) (CircuitEndpoints, error) {
+	endpoints := CircuitEndpoints{CircuitID: dataCircuitID}
+
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(endpointObjectType, []string{dataCircuitID})
+	if err != nil {
+		return endpoints, err
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return endpoints, err
+		}
+
+		var endpoint Endpoint
+		err = json.Unmarshal(queryResponse.Value, &endpoint)
+		if err != nil {
+			return endpoints, nsc.NewError(nsc.CodeInternal, "unable to read Endpoint %s: %s", queryResponse.Key, err.Error())
+		}
+		if endpoint.End == endA {
+			endpoints.AEnd = &endpoint
+		} else {
+			endpoints.ZEnd = &endpoint
+		}
+	}
+	return endpoints, nil
+}
+
+// siteLink is one entry of the site index: a circuit of the site and the site at its far end
+type siteLink struct {
+	circuitID string
+	farSiteID string
+}
+
+// getSiteLinks lists the circuits of a site by CircuitID
+func getSiteLinks(stub shim.ChaincodeStubInterface, siteID string) ([]siteLink, error) {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(siteCircuitIndex, []string{siteID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	links := []siteLink{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := stub.SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, siteLink{keyParts[1], string(queryResponse.Value)})
+	}
+	return links, nil
+}
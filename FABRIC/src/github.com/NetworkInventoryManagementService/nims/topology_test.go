@@ -0,0 +1,83 @@
+package nims_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+// siteNeighbours returns the neighbours of a site as "SiteID:CircuitIDs" strings
+func siteNeighbours(t *testing.T, s *simulator.Simulator, siteID string) []string {
+	t.Helper()
+	result := s.Query(simulator.NIMS, provider, "getSiteNeighbours", siteID)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	var neighbours []nims.SiteNeighbour
+	if err := json.Unmarshal(result.Response.Payload, &neighbours); err != nil {
+		t.Fatal(err)
+	}
+	listed := []string{}
+	for _, neighbour := range neighbours {
+		listed = append(listed, fmt.Sprintf("%s:%v", neighbour.SiteID, neighbour.CircuitIDs))
+	}
+	return listed
+}
+
+func TestTopologyIsIndexedFromBothEnds(t *testing.T) {
+	s := simulator.NewForTest(t)
+	for _, siteID := range []string{"S1", "S2", "S3"} {
+		if err := s.AddSite(siteID, "site "+siteID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, circuitID := range []string{"C1", "C2", "C3", "C4"} {
+		if err := s.SeedCircuit(circuitID, "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// C2 runs the other way round, C4 is not linked
+	for circuitID, ends := range map[string][2]string{"C1": {"S1", "S2"}, "C2": {"S2", "S1"}, "C3": {"S2", "S3"}} {
+		if err := s.LinkCircuit(circuitID, ends[0], ends[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectCode(t, s.Invoke(simulator.NIMS, provider, "setCircuitEndpoints", "C4", "S1", "S1"), nsc.CodeInvalidArgument)
+	expectCode(t, s.Invoke(simulator.NIMS, provider, "setCircuitEndpoints", "C4", "S1", "S9"), nsc.CodeNotFound)
+
+	if neighbours := fmt.Sprint(siteNeighbours(t, s, "S2")); neighbours != "[S1:[C1 C2] S3:[C3]]" {
+		t.Errorf("expected S1 over C1 and C2 and S3 over C3 next to S2, got %s", neighbours)
+	}
+	result := s.Query(simulator.NIMS, provider, "getCircuitsBetweenSites", "S1", "S2")
+	var between []nims.DataCircuit
+	if err := json.Unmarshal(result.Response.Payload, &between); err != nil {
+		t.Fatal(err)
+	}
+	if len(between) != 2 || between[0].CircuitID != "C1" || between[1].CircuitID != "C2" {
+		t.Errorf("expected C1 and C2 between S1 and S2, got %+v", between)
+	}
+	result = s.Query(simulator.NIMS, provider, "listCircuitLinks", "NET1")
+	var links []nims.CircuitLink
+	if err := json.Unmarshal(result.Response.Payload, &links); err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 3 || links[1] != (nims.CircuitLink{CircuitID: "C2", ASiteID: "S2", ZSiteID: "S1"}) {
+		t.Errorf("expected the links of C1, C2 and C3, got %+v", links)
+	}
+
+	// moving C2 takes it off the index of S2
+	if err := s.LinkCircuit("C2", "S1", "S3"); err != nil {
+		t.Fatal(err)
+	}
+	if neighbours := fmt.Sprint(siteNeighbours(t, s, "S2")); neighbours != "[S1:[C1] S3:[C3]]" {
+		t.Errorf("expected S1 over C1 and S3 over C3 next to S2, got %s", neighbours)
+	}
+	if neighbours := fmt.Sprint(siteNeighbours(t, s, "S3")); neighbours != "[S1:[C2] S2:[C3]]" {
+		t.Errorf("expected S1 over C2 and S2 over C3 next to S3, got %s", neighbours)
+	}
+}
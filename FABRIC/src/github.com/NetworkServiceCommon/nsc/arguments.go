@@ -0,0 +1,290 @@
+package nsc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ============================================================================================================================
+// Argument Schemas - every function declares its arguments once and accepts them either positionally, in the declared
+// order, or as a single JSON object keyed by field name. Validation reports every invalid field, not just the first one.
+// ============================================================================================================================
+
+const (
+	ArgString  = "string"
+	ArgInteger = "integer"
+	ArgBoolean = "boolean"
+	// a bandwidth with an optional unit, see bandwidth.go
+	ArgBandwidth = "bandwidth"
+)
+
+const (
+	MaxIDLength   = 64
+	MaxNameLength = 128
+	MaxBandwidth  = 100000000000000 // 100Tbps
+	MaxPriority   = 999
+)
+
+// ID formats shared by assets, chaincode names and channel names
+const (
+	IDPattern        = `^[A-Za-z0-9][A-Za-z0-9._:-]*$`
+	ChaincodePattern = `^[A-Za-z0-9]+([-_][A-Za-z0-9]+)*$`
+	VersionPattern   = `^[A-Za-z0-9_.+-]+$`
+	ChannelPattern   = `^[a-z][a-z0-9.-]*$`
+	// timestamps use the getTxTimestamp layout, UTC yyyyMMddHHmmss, so they compare as strings
+	TimestampPattern = `^[0-9]{14}$`
+)
+
+type ArgumentField struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Required  bool     `json:"required"`
+	MaxLength int      `json:"maxLength,omitempty"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Enum      []string `json:"enum,omitempty"`
+	Minimum   *int64   `json:"minimum,omitempty"`
+	Maximum   *int64   `json:"maximum,omitempty"`
+}
+
+type ArgumentSchema []ArgumentField
+
+type fieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// FunctionArgs holds validated values: string, int64, bool or Bandwidth depending on the field type
+type FunctionArgs map[string]interface{}
+
+// compiledPatterns caches the regular expression of every schema pattern, the mutex guards it against handlers run
+// concurrently by the peer
+var (
+	compiledPatterns     = map[string]*regexp.Regexp{}
+	compiledPatternsLock sync.Mutex
+)
+
+func Bound(value int64) *int64 {
+	return &value
+}
+
+func (a FunctionArgs) Has(name string) bool {
+	_, ok := a[name]
+	return ok
+}
+
+func (a FunctionArgs) Str(name string) string {
+	value, _ := a[name].(string)
+	return value
+}
+
+func (a FunctionArgs) Integer(name string) int {
+	value, _ := a[name].(int64)
+	return int(value)
+}
+
+func (a FunctionArgs) Boolean(name string) bool {
+	value, _ := a[name].(bool)
+	return value
+}
+
+func (a FunctionArgs) Bandwidth(name string) Bandwidth {
+	value, _ := a[name].(Bandwidth)
+	return value
+}
+
+// Format returns a value as a chaincode argument, empty when absent so optional arguments can be passed on positionally
+func (a FunctionArgs) Format(name string) string {
+	switch value := a[name].(type) {
+	case string:
+		return value
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case bool:
+		return strconv.FormatBool(value)
+	case Bandwidth:
+		return value.Argument()
+	}
+	return ""
+}
+
+// parseArguments validates args against schema, a single argument starting with '{' is read as a JSON object
+func parseArguments(functionName string, args []string, schema ArgumentSchema) (FunctionArgs, error) {
+	var raw map[string]interface{}
+	var fieldErrors []fieldError
+
+	if len(args) == 1 && strings.HasPrefix(strings.TrimSpace(args[0]), "{") {
+		decoder := json.NewDecoder(bytes.NewReader([]byte(args[0])))
+		decoder.UseNumber()
+		err := decoder.Decode(&raw)
+		if err != nil {
+			return nil, NewError(CodeInvalidArgument, "%s(): argument is not a valid JSON object: %s", functionName, err.Error())
+		}
+		for name := range raw {
+			if schema.field(name) == nil {
+				fieldErrors = append(fieldErrors, fieldError{name, "unknown field"})
+			}
+		}
+	} else {
+		if len(args) > len(schema) {
+			return nil, NewError(CodeInvalidArgument, "%s(): Incorrect number of arguments. Expecting at most %d but got %d", functionName, len(schema), len(args)).
+				WithDetail("expected", schema.names())
+		}
+		raw = map[string]interface{}{}
+		for i, arg := range args {
+			if arg != "" {
+				raw[schema[i].Name] = arg
+			}
+		}
+	}
+
+	parsed := FunctionArgs{}
+	for _, field := range schema {
+		value, present := raw[field.Name]
+		if !present || value == nil {
+			if field.Required {
+				fieldErrors = append(fieldErrors, fieldError{field.Name, "is required"})
+			}
+			continue
+		}
+		converted, reason := field.convert(value)
+		if reason != "" {
+			fieldErrors = append(fieldErrors, fieldError{field.Name, reason})
+			continue
+		}
+		parsed[field.Name] = converted
+	}
+
+	if len(fieldErrors) > 0 {
+		return nil, NewError(CodeInvalidArgument, "%s(): %d invalid argument(s)", functionName, len(fieldErrors)).
+			WithDetail("fields", fieldErrors)
+	}
+	return parsed, nil
+}
+
+func (s ArgumentSchema) field(name string) *ArgumentField {
+	for i := range s {
+		if s[i].Name == name {
+			return &s[i]
+		}
+	}
+	return nil
+}
+
+func (s ArgumentSchema) names() []string {
+	names := make([]string, len(s))
+	for i, field := range s {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// convert checks one raw value (a string for positional args, any JSON value otherwise), returning a reason on failure
+func (f ArgumentField) convert(value interface{}) (interface{}, string) {
+	switch f.Type {
+	case ArgString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		if f.Required && str == "" {
+			return nil, "must be a non-empty string"
+		}
+		if f.MaxLength > 0 && len(str) > f.MaxLength {
+			return nil, fmt.Sprintf("must be at most %d characters", f.MaxLength)
+		}
+		if f.Pattern != "" && !MatchesPattern(f.Pattern, str) {
+			return nil, "must match " + f.Pattern
+		}
+		if len(f.Enum) > 0 && !stringInSlice(str, f.Enum) {
+			return nil, "must be one of " + strings.Join(f.Enum, ", ")
+		}
+		return str, ""
+
+	case ArgInteger:
+		var number int64
+		var err error
+		switch v := value.(type) {
+		case json.Number:
+			number, err = v.Int64()
+		case string:
+			number, err = strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		default:
+			return nil, "must be an integer"
+		}
+		if err != nil {
+			return nil, "must be an integer"
+		}
+		if f.Minimum != nil && number < *f.Minimum {
+			return nil, fmt.Sprintf("must be >= %d", *f.Minimum)
+		}
+		if f.Maximum != nil && number > *f.Maximum {
+			return nil, fmt.Sprintf("must be <= %d", *f.Maximum)
+		}
+		return number, ""
+
+	case ArgBandwidth:
+		var bandwidth Bandwidth
+		switch v := value.(type) {
+		case json.Number:
+			number, err := v.Int64()
+			if err != nil || number < 0 {
+				return nil, "must be a whole, non-negative number of bits per second"
+			}
+			bandwidth = Bandwidth(number)
+		case string:
+			parsed, err := ParseBandwidth(v)
+			if err != nil {
+				return nil, err.Error()
+			}
+			bandwidth = parsed
+		default:
+			return nil, "must be a bandwidth"
+		}
+		if f.Minimum != nil && int64(bandwidth) < *f.Minimum {
+			return nil, fmt.Sprintf("must be at least %s", Bandwidth(*f.Minimum))
+		}
+		if f.Maximum != nil && int64(bandwidth) > *f.Maximum {
+			return nil, fmt.Sprintf("must be at most %s", Bandwidth(*f.Maximum))
+		}
+		return bandwidth, ""
+
+	case ArgBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, ""
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, "must be true or false"
+			}
+			return b, ""
+		}
+		return nil, "must be true or false"
+	}
+	return nil, "has unsupported type " + f.Type
+}
+
+func MatchesPattern(pattern string, value string) bool {
+	compiledPatternsLock.Lock()
+	re, ok := compiledPatterns[pattern]
+	if !ok {
+		re = regexp.MustCompile(pattern)
+		compiledPatterns[pattern] = re
+	}
+	compiledPatternsLock.Unlock()
+	return re.MatchString(value)
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
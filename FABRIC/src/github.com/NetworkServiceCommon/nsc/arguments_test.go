@@ -0,0 +1,49 @@
+package nsc
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestParseArgumentsConcurrentPatterns(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// a distinct pattern per goroutine so every call has to add to the cache
+			schema := ArgumentSchema{
+				{Name: "ID", Type: ArgString, Required: true, Pattern: IDPattern},
+				{Name: "Code", Type: ArgString, Pattern: fmt.Sprintf("^C%d$", i)},
+			}
+			_, err := parseArguments("test", []string{"A-1", fmt.Sprintf("C%d", i)}, schema)
+			if err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestParseArgumentsReportsEveryField(t *testing.T) {
+	schema := ArgumentSchema{
+		{Name: "ID", Type: ArgString, Required: true, Pattern: IDPattern},
+		{Name: "Count", Type: ArgInteger, Minimum: Bound(1)},
+		{Name: "Bandwidth", Type: ArgBandwidth},
+	}
+	_, err := parseArguments("test", []string{`{"ID": "-bad", "Count": 0, "Bandwidth": "fast", "Extra": 1}`}, schema)
+	ccErr, ok := err.(*ChaincodeError)
+	if !ok || ccErr.Code != CodeInvalidArgument {
+		t.Fatalf("expected %s, got %v", CodeInvalidArgument, err)
+	}
+	fields, _ := ccErr.Details["fields"].([]fieldError)
+	if len(fields) != 4 {
+		t.Errorf("expected 4 field errors, got %v", fields)
+	}
+}
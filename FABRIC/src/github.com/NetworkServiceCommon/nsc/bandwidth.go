@@ -0,0 +1,95 @@
+package nsc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================================================================
+// Bandwidth Values - every bandwidth is a Bandwidth in bits per second. Arguments accept a bare number of bits per second
+// or a number with a decimal unit such as "100M", "1.5G" or "10Gbps", values are printed the same way. Negative values,
+// fractions of a bit and values that overflow are rejected. Records keep the plain number of bits per second.
+// ============================================================================================================================
+
+type Bandwidth int64
+
+var bandwidthUnits = []struct {
+	prefix     string
+	multiplier int64
+}{
+	{"T", 1000000000000},
+	{"G", 1000000000},
+	{"M", 1000000},
+	{"K", 1000},
+}
+
+var bandwidthPattern = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]+))?\s*([KMGTkmgt]?)(?:(?i)bps|b/s)?$`)
+
+// ParseBandwidth reads a bandwidth such as "100000", "100M", "1.5G" or "10Gbps"
+func ParseBandwidth(value string) (Bandwidth, error) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "-") {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	match := bandwidthPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf("must be a bandwidth such as 100M, 1G or 10Gbps")
+	}
+
+	multiplier := int64(1)
+	for _, unit := range bandwidthUnits {
+		if strings.EqualFold(match[3], unit.prefix) {
+			multiplier = unit.multiplier
+		}
+	}
+
+	whole, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || whole > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("is too large")
+	}
+	bitsPerSecond := whole * multiplier
+
+	if fraction := match[2]; fraction != "" {
+		// the fraction must come to whole bits, so it has no more digits than the multiplier has zeros
+		scale := int64(1)
+		for range fraction {
+			scale = scale * 10
+			if scale > multiplier {
+				return 0, fmt.Errorf("must be a whole number of bits per second")
+			}
+		}
+		digits, _ := strconv.ParseInt(fraction, 10, 64)
+		part := digits * (multiplier / scale)
+		if bitsPerSecond > math.MaxInt64-part {
+			return 0, fmt.Errorf("is too large")
+		}
+		bitsPerSecond = bitsPerSecond + part
+	}
+	return Bandwidth(bitsPerSecond), nil
+}
+
+// String prints the bandwidth in the largest unit it reaches, with up to three decimals
+func (b Bandwidth) String() string {
+	for _, unit := range bandwidthUnits {
+		if int64(b) >= unit.multiplier {
+			value := strconv.FormatFloat(float64(b)/float64(unit.multiplier), 'f', 3, 64)
+			value = strings.TrimRight(strings.TrimRight(value, "0"), ".")
+			return value + unit.prefix + "bps"
+		}
+	}
+	return strconv.FormatInt(int64(b), 10) + "bps"
+}
+
+// Argument returns the bandwidth as a chaincode argument, the exact number of bits per second
+func (b Bandwidth) Argument() string {
+	return strconv.FormatInt(int64(b), 10)
+}
+
+// PercentOf is b times percent / 100, percentages are at most a few thousand so this does not overflow for any valid
+// bandwidth
+func (b Bandwidth) PercentOf(percent int) Bandwidth {
+	return b * Bandwidth(percent) / 100
+}
@@ -0,0 +1,76 @@
+package nsc
+
+import "testing"
+
+func TestParseBandwidth(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Bandwidth
+		valid    bool
+	}{
+		// bare bits per second and units
+		{"100000", 100000, true},
+		{"0", 0, true},
+		{"100K", 100000, true},
+		{"100M", 100000000, true},
+		{"10G", 10000000000, true},
+		{"2T", 2000000000000, true},
+		{"10Gbps", 10000000000, true},
+		{"10gbps", 10000000000, true},
+		{"100 Mbps", 100000000, true},
+		{"100Mb/s", 100000000, true},
+		{" 1G ", 1000000000, true},
+		// decimals must come to whole bits
+		{"1.5G", 1500000000, true},
+		{"0.001K", 1, true},
+		{"1.000000001G", 1000000001, true},
+		{"0.0001K", 0, false},
+		{"1.5", 0, false},
+		{"1.0000000001G", 0, false},
+		// overflow of int64 bits per second
+		{"9223372036854775807", 9223372036854775807, true},
+		{"9223372036854775808", 0, false},
+		{"9223372036854775807K", 0, false},
+		{"9223372.036854775807T", 9223372036854775807, true},
+		{"9223372.036854775808T", 0, false},
+		{"99999999999999999999999", 0, false},
+		// bad input
+		{"", 0, false},
+		{"-5M", 0, false},
+		{"abc", 0, false},
+		{"10X", 0, false},
+		{"1.M", 0, false},
+		{".5M", 0, false},
+		{"1e6", 0, false},
+		{"1 0M", 0, false},
+		{"10Gbpss", 0, false},
+	}
+	for _, test := range tests {
+		bandwidth, err := ParseBandwidth(test.value)
+		if test.valid && (err != nil || bandwidth != test.expected) {
+			t.Errorf("ParseBandwidth(%q): expected %d, got %d, %v", test.value, test.expected, bandwidth, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("ParseBandwidth(%q): expected an error, got %d", test.value, bandwidth)
+		}
+	}
+}
+
+func TestBandwidthString(t *testing.T) {
+	tests := map[Bandwidth]string{
+		999:           "999bps",
+		1000:          "1Kbps",
+		100000000:     "100Mbps",
+		1500000000:    "1.5Gbps",
+		1234567:       "1.235Mbps",
+		2000000000000: "2Tbps",
+	}
+	for bandwidth, expected := range tests {
+		if bandwidth.String() != expected {
+			t.Errorf("expected %d to print as %s, got %s", int64(bandwidth), expected, bandwidth.String())
+		}
+		if parsed, err := ParseBandwidth(bandwidth.Argument()); err != nil || parsed != bandwidth {
+			t.Errorf("expected the argument of %d to parse back, got %d, %v", int64(bandwidth), parsed, err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package nsc
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Chaincode Dependencies - admin managed registry of the chaincodes a chaincode is allowed to invoke or to be called
+// through, kept in the chaincode's own state under "ChaincodeDependency"[alias]. Every chaincode lists the aliases it may
+// register and puts RegisterChaincodeDependency and GetChaincodeDependencies in its function table. InvokeDependency
+// calls the chaincode registered under an alias, the CalledBy aliases of a function admit the chaincodes registered
+// under them, so a chaincode deployed under another name only needs its alias registered again.
+// ============================================================================================================================
+
+const dependencyObjectType = "ChaincodeDependency"
+
+type ChaincodeDependency struct {
+	Alias            string `json:"Alias"`
+	ChaincodeName    string `json:"ChaincodeName"`
+	ChaincodeVersion string `json:"ChaincodeVersion"`
+	ChannelID        string `json:"ChannelID"`
+	UpdatedBy        string `json:"UpdatedBy"`
+	UpdatedOn        string `json:"UpdatedOn"`
+}
+
+// DependencyArguments is the argument schema of RegisterChaincodeDependency for a chaincode with the given aliases
+func DependencyArguments(aliases []string) ArgumentSchema {
+	return ArgumentSchema{
+		{Name: "Alias", Type: ArgString, Required: true, Enum: aliases},
+		{Name: "ChaincodeName", Type: ArgString, Required: true, MaxLength: MaxNameLength, Pattern: ChaincodePattern},
+		{Name: "ChaincodeVersion", Type: ArgString, Required: true, MaxLength: MaxIDLength, Pattern: VersionPattern},
+		{Name: "ChannelID", Type: ArgString, Required: true, MaxLength: MaxNameLength, Pattern: ChannelPattern},
+	}
+}
+
+// RegisterChaincodeDependency stores (or replaces) the target of a dependency alias once the target chaincode reports
+// the registered version, a dependency is registered again after its chaincode is upgraded
+func RegisterChaincodeDependency(stub shim.ChaincodeStubInterface, arguments FunctionArgs) pb.Response {
+	fmt.Println("starting registerChaincodeDependency")
+
+	alias := arguments.Str("Alias")
+
+	err := AssertChaincodeVersion(stub, arguments.Str("ChaincodeName"), arguments.Str("ChaincodeVersion"), arguments.Str("ChannelID"))
+	if err != nil {
+		return ErrorResponse(err)
+	}
+
+	updatedBy, err := GetInvokerID(stub)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil {
+		return ErrorResponse(err)
+	}
+
+	dependency := ChaincodeDependency{
+		Alias:            alias,
+		ChaincodeName:    arguments.Str("ChaincodeName"),
+		ChaincodeVersion: arguments.Str("ChaincodeVersion"),
+		ChannelID:        arguments.Str("ChannelID"),
+		UpdatedBy:        updatedBy,
+		UpdatedOn:        time.Unix(txTime.Seconds, int64(txTime.Nanos)).UTC().Format("20060102150405"),
+	}
+
+	dependencyKey, err := stub.CreateCompositeKey(dependencyObjectType, []string{alias})
+	if err != nil {
+		return ErrorResponse(err)
+	}
+
+	buff, err := json.Marshal(dependency)
+	if err != nil {
+		return ErrorResponse(NewError(CodeInternal, "unable to convert ChaincodeDependency to json"))
+	}
+
+	err = stub.PutState(dependencyKey, buff)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+
+	fmt.Println("- end registerChaincodeDependency")
+	return shim.Success(buff)
+}
+
+// GetChaincodeDependencies returns every registered dependency as a JSON array
+func GetChaincodeDependencies(stub shim.ChaincodeStubInterface, arguments FunctionArgs) pb.Response {
+	resultsIterator, err := stub.GetStateByPartialCompositeKey(dependencyObjectType, []string{})
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	defer resultsIterator.Close()
+
+	dependencies := []ChaincodeDependency{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return ErrorResponse(err)
+		}
+		var dependency ChaincodeDependency
+		err = json.Unmarshal(queryResponse.Value, &dependency)
+		if err != nil {
+			return ErrorResponse(err)
+		}
+		dependencies = append(dependencies, dependency)
+	}
+
+	buff, err := json.Marshal(dependencies)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
+
+// GetChaincodeDependency reads the target registered under alias, UPSTREAM_FAILURE when none is
+func GetChaincodeDependency(stub shim.ChaincodeStubInterface, alias string) (ChaincodeDependency, error) {
+	var dependency ChaincodeDependency
+
+	dependencyKey, err := stub.CreateCompositeKey(dependencyObjectType, []string{alias})
+	if err != nil {
+		return dependency, err
+	}
+
+	dependencyAsBytes, err := stub.GetState(dependencyKey)
+	if err != nil {
+		return dependency, err
+	}
+	if dependencyAsBytes == nil {
+		return dependency, NewError(CodeUpstreamFailure, "chaincode dependency '%s' is not registered, an admin must call registerChaincodeDependency first", alias).WithDetail("alias", alias)
+	}
+
+	err = json.Unmarshal(dependencyAsBytes, &dependency)
+	return dependency, err
+}
+
+// InvokeDependency calls a function on the chaincode registered under alias, on the channel it was registered for
+func InvokeDependency(stub shim.ChaincodeStubInterface, alias string, functionName string, args ...string) pb.Response {
+	dependency, err := GetChaincodeDependency(stub, alias)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	return dependency.invoke(stub, dependency.ChannelID, functionName, args)
+}
+
+// InvokeDependencyOnChannel calls the chaincode registered under alias on a specific channel,
+// only reads survive when channelID is not the channel of the current transaction
+func InvokeDependencyOnChannel(stub shim.ChaincodeStubInterface, alias string, channelID string, functionName string, args ...string) pb.Response {
+	dependency, err := GetChaincodeDependency(stub, alias)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	return dependency.invoke(stub, channelID, functionName, args)
+}
+
+func (d ChaincodeDependency) invoke(stub shim.ChaincodeStubInterface, channelID string, functionName string, args []string) pb.Response {
+	fmt.Printf("- invoking %s (%s:%s on channel %s) %s\n", d.Alias, d.ChaincodeName, d.ChaincodeVersion, channelID, functionName)
+
+	invokeArgs := make([][]byte, 0, len(args)+1)
+	invokeArgs = append(invokeArgs, []byte(functionName))
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+	return stub.InvokeChaincode(d.ChaincodeName, invokeArgs, channelID)
+}
@@ -0,0 +1,98 @@
+// Package nsc holds what the four network service chaincodes share: the error model, argument schemas, the function
+// registry, the dependency registry, chaincode events, bandwidth values and client identity. The
+// NetworkConfigurationAgent uses its bandwidth values.
+package nsc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Error Model - every failed response carries {"error": {"code", "message", "details", "chaincode", "cause"}} as its message
+// so that API layers can map the stable codes below to HTTP statuses.
+// ============================================================================================================================
+
+const (
+	CodeInvalidArgument      = "INVALID_ARGUMENT"
+	CodeNotFound             = "NOT_FOUND"
+	CodeInsufficientCapacity = "INSUFFICIENT_CAPACITY"
+	CodeForbidden            = "FORBIDDEN"
+	CodeConflict             = "CONFLICT"
+	CodeDiversityViolation   = "DIVERSITY_VIOLATION"
+	CodeSLANotMet            = "SLA_NOT_MET"
+	CodeUpstreamFailure      = "UPSTREAM_FAILURE"
+	CodeInternal             = "INTERNAL"
+)
+
+// ChaincodeError is the error body. Chaincode and Cause are set when the error was raised by a called chaincode.
+type ChaincodeError struct {
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Chaincode string                 `json:"chaincode,omitempty"`
+	Cause     *ChaincodeError        `json:"cause,omitempty"`
+}
+
+type errorEnvelope struct {
+	Error *ChaincodeError `json:"error"`
+}
+
+func (e *ChaincodeError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// WithDetail adds a key to the details object and returns the error for chaining
+func (e *ChaincodeError) WithDetail(key string, value interface{}) *ChaincodeError {
+	if e.Details == nil {
+		e.Details = map[string]interface{}{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+func NewError(code string, format string, a ...interface{}) *ChaincodeError {
+	return &ChaincodeError{Code: code, Message: fmt.Sprintf(format, a...)}
+}
+
+// toChaincodeError keeps coded errors as they are and reports anything else as INTERNAL
+func toChaincodeError(err error) *ChaincodeError {
+	if ccErr, ok := err.(*ChaincodeError); ok {
+		return ccErr
+	}
+	return NewError(CodeInternal, "%s", err.Error())
+}
+
+// ErrorResponse renders err as the JSON error envelope of a failed response
+func ErrorResponse(err error) pb.Response {
+	envelope, marshalErr := json.Marshal(errorEnvelope{toChaincodeError(err)})
+	if marshalErr != nil {
+		return shim.Error(err.Error())
+	}
+	fmt.Println(string(envelope))
+	return shim.Error(string(envelope))
+}
+
+// UpstreamError wraps the failed response of a called chaincode, preserving the callee's code
+func UpstreamError(chaincode string, functionName string, response pb.Response) *ChaincodeError {
+	cause := ParseErrorMessage(response.Message)
+	return &ChaincodeError{
+		Code:      cause.Code,
+		Message:   fmt.Sprintf("%s %s failed: %s", chaincode, functionName, cause.Message),
+		Chaincode: chaincode,
+		Cause:     cause,
+	}
+}
+
+// ParseErrorMessage reads an error envelope, messages of chaincodes without the envelope become UPSTREAM_FAILURE
+func ParseErrorMessage(message string) *ChaincodeError {
+	var envelope errorEnvelope
+	err := json.Unmarshal([]byte(message), &envelope)
+	if err != nil || envelope.Error == nil || envelope.Error.Code == "" {
+		return NewError(CodeUpstreamFailure, "%s", message)
+	}
+	return envelope.Error
+}
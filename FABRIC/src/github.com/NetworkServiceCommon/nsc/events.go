@@ -0,0 +1,117 @@
+package nsc
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Chaincode Events - Fabric keeps one event per transaction, set by the chaincode the client invoked; events set by
+// chaincodes it calls are dropped. Every state changing function therefore returns its EventBatch as the success payload,
+// the caller merges the records into its own batch and the top level chaincode emits the combined batch.
+// ============================================================================================================================
+
+const eventName = "NetworkServiceEvents"
+
+const eventPayloadVersion = "1.0"
+
+// record types
+const (
+	EventCircuitAdded           = "CircuitAdded"
+	EventCircuitResized         = "CircuitResized"
+	EventCircuitStatusChanged   = "CircuitStatusChanged"
+	EventMaintenanceScheduled   = "MaintenanceScheduled"
+	EventMaintenanceCancelled   = "MaintenanceCancelled"
+	EventBandwidthBooked        = "BandwidthBooked"
+	EventBookingCancelled       = "BookingCancelled"
+	EventBandwidthAllocated     = "BandwidthAllocated"
+	EventBandwidthReleased      = "BandwidthReleased"
+	EventProtectionGroupCreated = "ProtectionGroupCreated"
+	EventAllocationMoved        = "AllocationMoved"
+	EventOrderPrepared          = "OrderPrepared"
+	EventCircuitSelected        = "CircuitSelected"
+	EventOrderCompleted         = "OrderCompleted"
+	EventOrderRejected          = "OrderRejected"
+	EventOrderWaitlisted        = "OrderWaitlisted"
+	EventWaitlistProcessed      = "WaitlistProcessed"
+	EventFailoverReported       = "FailoverReported"
+	EventConfigurationRequested = "ConfigurationRequested"
+	EventConfigurationApplied   = "ConfigurationApplied"
+	EventConfigurationFailed    = "ConfigurationFailed"
+	EventEquipmentStatusChanged = "EquipmentStatusChanged"
+	EventEquipmentSwapped       = "EquipmentSwapped"
+)
+
+type EventRecord struct {
+	Type          string          `json:"type"`
+	Chaincode     string          `json:"chaincode"`
+	CorrelationID string          `json:"correlationId"`
+	Data          json.RawMessage `json:"data"`
+}
+
+type EventBatch struct {
+	Version       string        `json:"version"`
+	TxID          string        `json:"txId"`
+	CorrelationID string        `json:"correlationId"`
+	Records       []EventRecord `json:"records"`
+	// chaincode is the name records added to this batch are attributed to
+	chaincode string
+}
+
+// NewEventBatch starts a batch of the named chaincode, the correlation ID defaults to the transaction ID
+func NewEventBatch(stub shim.ChaincodeStubInterface, chaincode string, correlationID string) *EventBatch {
+	if correlationID == "" {
+		correlationID = stub.GetTxID()
+	}
+	return &EventBatch{
+		Version:       eventPayloadVersion,
+		TxID:          stub.GetTxID(),
+		CorrelationID: correlationID,
+		Records:       []EventRecord{},
+		chaincode:     chaincode,
+	}
+}
+
+func (b *EventBatch) Add(eventType string, data interface{}) error {
+	buff, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	b.Records = append(b.Records, EventRecord{eventType, b.chaincode, b.CorrelationID, buff})
+	return nil
+}
+
+// Merge appends the records of a batch returned by another chaincode
+func (b *EventBatch) Merge(calleeName string, payload []byte) error {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	var callee EventBatch
+	err := json.Unmarshal(payload, &callee)
+	if err != nil {
+		return NewError(CodeUpstreamFailure, "%s did not return an event batch: %s", calleeName, err.Error())
+	}
+	if callee.Version != eventPayloadVersion {
+		return NewError(CodeUpstreamFailure, "%s returned event batch version %s, expected %s", calleeName, callee.Version, eventPayloadVersion)
+	}
+
+	b.Records = append(b.Records, callee.Records...)
+	return nil
+}
+
+// Emit sets the batch as the transaction event and returns it as the success payload
+func (b *EventBatch) Emit(stub shim.ChaincodeStubInterface) pb.Response {
+	buff, err := json.Marshal(b)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+
+	err = stub.SetEvent(eventName, buff)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
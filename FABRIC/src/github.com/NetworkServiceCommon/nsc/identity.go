@@ -0,0 +1,121 @@
+package nsc
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/ext/cid"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// ============================================================================================================================
+// Client Identity - roles are read from the "role" attribute of the enrollment certificate
+// (register the user at the Fabric CA with attrs: [{ name: "role", value: "admin", ecert: true }]), the operator a user
+// orders for is the enrollment ID the Fabric CA adds to every enrollment certificate as "hf.EnrollmentID".
+// A chaincode to chaincode call runs under the identity of the client, so a function that another chaincode calls on
+// the client's behalf is guarded by the chaincode the client proposed the transaction to, see AssertCalledBy.
+// ============================================================================================================================
+
+const roleAttribute = "role"
+
+const AdminRole = "admin"
+
+const enrollmentIDAttribute = "hf.EnrollmentID"
+
+// GetInvokerID returns "<mspid>:<subject id>" of the transaction creator
+func GetInvokerID(stub shim.ChaincodeStubInterface) (string, error) {
+	mspID, err := cid.GetMSPID(stub)
+	if err != nil {
+		return "", err
+	}
+	id, err := cid.GetID(stub)
+	if err != nil {
+		return "", err
+	}
+	return mspID + ":" + id, nil
+}
+
+// AssertRole fails unless the transaction creator carries the given role attribute
+func AssertRole(stub shim.ChaincodeStubInterface, role string) error {
+	value, found, err := cid.GetAttributeValue(stub, roleAttribute)
+	if err != nil {
+		return NewError(CodeForbidden, "unable to read the invoker identity: %s", err.Error())
+	}
+	if !found || value != role {
+		return NewError(CodeForbidden, "this function requires the '%s' role", role).WithDetail("requiredRole", role)
+	}
+	return nil
+}
+
+// AssertOperator fails unless operatorID is the enrollment ID of the transaction creator, an admin may act for any operator
+func AssertOperator(stub shim.ChaincodeStubInterface, operatorID string) error {
+	enrollmentID, found, err := cid.GetAttributeValue(stub, enrollmentIDAttribute)
+	if err != nil {
+		return NewError(CodeForbidden, "unable to read the invoker identity: %s", err.Error())
+	}
+	if found && enrollmentID == operatorID {
+		return nil
+	}
+	if AssertRole(stub, AdminRole) == nil {
+		return nil
+	}
+	return NewError(CodeForbidden, "the invoker is enrolled as '%s' and cannot act for operator '%s'", enrollmentID, operatorID).
+		WithDetail("OperatorID", operatorID).
+		WithDetail("enrollmentID", enrollmentID)
+}
+
+// AssertCalledBy fails unless the transaction was proposed to the chaincode registered under one of the dependency
+// aliases, or the creator is an admin. The names come from the registry, see dependencies.go, an alias that is not
+// registered admits no chaincode.
+func AssertCalledBy(stub shim.ChaincodeStubInterface, aliases []string) error {
+	chaincodes := []string{}
+	for _, alias := range aliases {
+		dependency, err := GetChaincodeDependency(stub, alias)
+		if err == nil {
+			chaincodes = append(chaincodes, dependency.ChaincodeName)
+		}
+	}
+
+	proposed, err := proposedChaincode(stub)
+	if err == nil && stringInSlice(proposed, chaincodes) {
+		return nil
+	}
+	if AssertRole(stub, AdminRole) == nil {
+		return nil
+	}
+	if err != nil {
+		return NewError(CodeForbidden, "unable to read the signed proposal: %s", err.Error())
+	}
+	return NewError(CodeForbidden, "this function can only be called through %s", strings.Join(aliases, " or ")).
+		WithDetail("calledBy", aliases).
+		WithDetail("registeredAs", chaincodes).
+		WithDetail("proposedTo", proposed)
+}
+
+// proposedChaincode returns the name of the chaincode the client proposed the transaction to, the same for every
+// chaincode the transaction calls
+func proposedChaincode(stub shim.ChaincodeStubInterface) (string, error) {
+	signedProposal, err := stub.GetSignedProposal()
+	if err != nil {
+		return "", err
+	}
+	if signedProposal == nil {
+		return "", NewError(CodeInternal, "the transaction carries no signed proposal")
+	}
+	proposal, err := utils.GetProposal(signedProposal.ProposalBytes)
+	if err != nil {
+		return "", err
+	}
+	payload, err := utils.GetChaincodeProposalPayload(proposal.GetPayload())
+	if err != nil {
+		return "", err
+	}
+	invocation := &pb.ChaincodeInvocationSpec{}
+	err = proto.Unmarshal(payload.GetInput(), invocation)
+	if err != nil {
+		return "", err
+	}
+	return invocation.GetChaincodeSpec().GetChaincodeId().GetName(), nil
+}
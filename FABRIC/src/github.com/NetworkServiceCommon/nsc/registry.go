@@ -0,0 +1,134 @@
+package nsc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Function Registry - Invoke routes through a table in which every function declares its name, argument schema,
+// read-only flag, required role and the chaincodes it must be called through. describeChaincode returns that table as JSON so clients can generate bindings.
+// The tables live next to each chaincode's Invoke.
+// ============================================================================================================================
+
+const DescribeFunctionName = "describeChaincode"
+
+type FunctionHandler func(stub shim.ChaincodeStubInterface, arguments FunctionArgs) pb.Response
+
+type ChaincodeFunction struct {
+	Name         string         `json:"name"`
+	Description  string         `json:"description"`
+	Arguments    ArgumentSchema `json:"arguments"`
+	ReadOnly     bool           `json:"readOnly"`
+	RequiredRole string         `json:"requiredRole,omitempty"`
+	// CalledBy names the dependency aliases of the chaincodes a transaction must be proposed to for a non admin to run
+	// the function, the chaincode names are looked up in the registry of dependencies.go
+	CalledBy []string        `json:"calledBy,omitempty"`
+	Handler  FunctionHandler `json:"-"`
+}
+
+type ChaincodeDescription struct {
+	Chaincode string              `json:"chaincode"`
+	Version   string              `json:"version"`
+	Functions []ChaincodeFunction `json:"functions"`
+}
+
+// InvokeFunction authorizes, validates and dispatches one invocation of the named chaincode
+func InvokeFunction(stub shim.ChaincodeStubInterface, chaincode string, version string, functions []ChaincodeFunction,
+	functionName string, args []string) pb.Response {
+	if functionName == DescribeFunctionName {
+		return describeChaincode(chaincode, version, functions)
+	}
+
+	for _, function := range functions {
+		if function.Name != functionName {
+			continue
+		}
+
+		if function.RequiredRole != "" {
+			err := AssertRole(stub, function.RequiredRole)
+			if err != nil {
+				return ErrorResponse(err)
+			}
+		}
+
+		if len(function.CalledBy) > 0 {
+			err := AssertCalledBy(stub, function.CalledBy)
+			if err != nil {
+				return ErrorResponse(err)
+			}
+		}
+
+		arguments, err := parseArguments(function.Name, args, function.Arguments)
+		if err != nil {
+			return ErrorResponse(err)
+		}
+
+		if function.ReadOnly {
+			return function.Handler(readOnlyStub{stub, function.Name}, arguments)
+		}
+		return function.Handler(newPendingStateStub(stub), arguments)
+	}
+
+	fmt.Println("Received unknown invoke function name - " + functionName)
+	return ErrorResponse(NewError(CodeInvalidArgument, "Received unknown invoke function name - '%s'", functionName).
+		WithDetail("hint", "call "+DescribeFunctionName+" to list the supported functions"))
+}
+
+// AssertChaincodeVersion fails unless the chaincode deployed under name on the channel describes itself as version
+func AssertChaincodeVersion(stub shim.ChaincodeStubInterface, name string, version string, channelID string) error {
+	response := stub.InvokeChaincode(name, [][]byte{[]byte(DescribeFunctionName)}, channelID)
+	if response.Status != shim.OK {
+		return UpstreamError(name, DescribeFunctionName, response)
+	}
+
+	var description ChaincodeDescription
+	err := json.Unmarshal(response.Payload, &description)
+	if err != nil {
+		return NewError(CodeUpstreamFailure, "%s returned an invalid %s: %s", name, DescribeFunctionName, err.Error())
+	}
+	if description.Version != version {
+		return NewError(CodeConflict, "%s on channel %s is version %s, not %s", name, channelID, description.Version, version).
+			WithDetail("ChaincodeName", name).
+			WithDetail("ChaincodeVersion", description.Version)
+	}
+	return nil
+}
+
+func describeChaincode(chaincode string, version string, functions []ChaincodeFunction) pb.Response {
+	describe := ChaincodeFunction{
+		Name:        DescribeFunctionName,
+		Description: "Lists every function of this chaincode with its argument schema, read-only flag, required role and callers",
+		Arguments:   ArgumentSchema{},
+		ReadOnly:    true,
+	}
+
+	description := ChaincodeDescription{
+		Chaincode: chaincode,
+		Version:   version,
+		Functions: append(append([]ChaincodeFunction{}, functions...), describe),
+	}
+
+	buff, err := json.Marshal(description)
+	if err != nil {
+		return ErrorResponse(err)
+	}
+	return shim.Success(buff)
+}
+
+// readOnlyStub rejects ledger writes made by functions declared read only
+type readOnlyStub struct {
+	shim.ChaincodeStubInterface
+	functionName string
+}
+
+func (s readOnlyStub) PutState(key string, value []byte) error {
+	return NewError(CodeForbidden, "%s() is declared read only and cannot write %s", s.functionName, key)
+}
+
+func (s readOnlyStub) DelState(key string) error {
+	return NewError(CodeForbidden, "%s() is declared read only and cannot delete %s", s.functionName, key)
+}
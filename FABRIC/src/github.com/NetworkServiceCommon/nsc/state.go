@@ -0,0 +1,128 @@
+package nsc
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+)
+
+// ============================================================================================================================
+// Transaction State - a peer serves GetState and range queries from the committed state only, a transaction does not
+// read its own writes. InvokeFunction hands every writing function a stub that remembers the writes of the invocation
+// and reads them back, so a function can write a record and read it again. The writes of one chaincode call are not
+// seen by the next call into the same chaincode within the transaction: a caller that changes the same record in
+// several calls loses all but the last write and must change it in one call instead.
+// ============================================================================================================================
+
+// pendingStateStub overlays the writes of one invocation on the committed state, a nil value is a deleted key
+type pendingStateStub struct {
+	shim.ChaincodeStubInterface
+	writes map[string][]byte
+}
+
+func newPendingStateStub(stub shim.ChaincodeStubInterface) pendingStateStub {
+	return pendingStateStub{stub, map[string][]byte{}}
+}
+
+func (s pendingStateStub) GetState(key string) ([]byte, error) {
+	if value, ok := s.writes[key]; ok {
+		return value, nil
+	}
+	return s.ChaincodeStubInterface.GetState(key)
+}
+
+func (s pendingStateStub) PutState(key string, value []byte) error {
+	err := s.ChaincodeStubInterface.PutState(key, value)
+	if err != nil {
+		return err
+	}
+	s.writes[key] = value
+	return nil
+}
+
+func (s pendingStateStub) DelState(key string) error {
+	err := s.ChaincodeStubInterface.DelState(key)
+	if err != nil {
+		return err
+	}
+	s.writes[key] = nil
+	return nil
+}
+
+func (s pendingStateStub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.overlay(iterator, func(key string) bool {
+		return (startKey == "" || key >= startKey) && (endKey == "" || key < endKey)
+	})
+}
+
+func (s pendingStateStub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	iterator, err := s.ChaincodeStubInterface.GetStateByPartialCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := s.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.overlay(iterator, func(key string) bool {
+		return len(key) >= len(prefix) && key[:len(prefix)] == prefix
+	})
+}
+
+// overlay merges the pending writes in range into the committed results of a range query, in key order
+func (s pendingStateStub) overlay(iterator shim.StateQueryIteratorInterface, inRange func(key string) bool) (shim.StateQueryIteratorInterface, error) {
+	defer iterator.Close()
+
+	values := map[string]*queryresult.KV{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		values[kv.Key] = kv
+	}
+	for key, value := range s.writes {
+		if !inRange(key) {
+			continue
+		}
+		if value == nil {
+			delete(values, key)
+		} else {
+			values[key] = &queryresult.KV{Key: key, Value: value}
+		}
+	}
+
+	merged := &stateIterator{}
+	for _, kv := range values {
+		merged.results = append(merged.results, kv)
+	}
+	sort.Slice(merged.results, func(i, j int) bool { return merged.results[i].Key < merged.results[j].Key })
+	return merged, nil
+}
+
+// stateIterator iterates over range query results already read
+type stateIterator struct {
+	results []*queryresult.KV
+	next    int
+}
+
+func (i *stateIterator) HasNext() bool {
+	return i.next < len(i.results)
+}
+
+func (i *stateIterator) Next() (*queryresult.KV, error) {
+	if !i.HasNext() {
+		return nil, NewError(CodeInternal, "the range query has no more results")
+	}
+	i.next++
+	return i.results[i.next-1], nil
+}
+
+func (i *stateIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,325 @@
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/AutomaticNetworkConfigurationService/ancs"
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+)
+
+// ============================================================================================================================
+// Scenario Helpers - seed inventory, place orders and read the resulting ledger state of each chaincode
+// ============================================================================================================================
+
+// bandwidth units for scenarios, as in 100 * Mbps
+const (
+	Kbps nsc.Bandwidth = 1000
+	Mbps nsc.Bandwidth = 1000 * Kbps
+	Gbps nsc.Bandwidth = 1000 * Mbps
+)
+
+// SeedCircuit adds a DataCircuit to NIMS as the admin of its provider
+func (s *Simulator) SeedCircuit(circuitID string, network string, providerID string, totalBandwidth nsc.Bandwidth) error {
+	result := s.Invoke(NIMS, ProviderAdminIdentity(providerID), "addNewDataCircuit", circuitID, network, providerID, formatBandwidth(totalBandwidth))
+	return result.Error()
+}
+
+// SubmitOrder places an order through OMS, the identity's name is used as OperatorID
+func (s *Simulator) SubmitOrder(as Identity, orderID string, circuitID string, bandwidth nsc.Bandwidth) Result {
+	return s.Invoke(OMS, as, "prepareOrder", orderID, as.Name, circuitID, formatBandwidth(bandwidth))
+}
+
+// SubmitProfiledOrder submits an order on a circuit with a bandwidth profile, bandwidth is its committed rate
+func (s *Simulator) SubmitProfiledOrder(as Identity, orderID string, circuitID string, bandwidth nsc.Bandwidth, excess nsc.Bandwidth, burstSize int, classOfService string) Result {
+	return s.Invoke(OMS, as, "prepareOrder", orderID, as.Name, circuitID, formatBandwidth(bandwidth), "", "", "",
+		optionalBandwidth(excess), optionalInt(burstSize), classOfService)
+}
+
+// optionalInt formats an optional integer argument, 0 leaves it out
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// optionalBandwidth formats an optional bandwidth argument, 0 leaves it out
+func optionalBandwidth(value nsc.Bandwidth) string {
+	if value == 0 {
+		return ""
+	}
+	return formatBandwidth(value)
+}
+
+// formatBandwidth formats a bandwidth argument as its exact number of bits per second
+func formatBandwidth(value nsc.Bandwidth) string {
+	return strconv.FormatInt(int64(value), 10)
+}
+
+// PlaceOrder places an order on a network and lets BPM choose the circuit, empty providerID and strategy are left out
+func (s *Simulator) PlaceOrder(as Identity, orderID string, network string, providerID string, bandwidth nsc.Bandwidth, strategy string) Result {
+	return s.Invoke(OMS, as, "placeOrder", orderID, as.Name, network, providerID, formatBandwidth(bandwidth), strategy)
+}
+
+// PlaceSplitOrder places an order on a network that BPM may split across several circuits
+func (s *Simulator) PlaceSplitOrder(as Identity, orderID string, network string, bandwidth nsc.Bandwidth) Result {
+	return s.Invoke(OMS, as, "placeOrder", orderID, as.Name, network, "", formatBandwidth(bandwidth), "", "", "", "", "true")
+}
+
+// PlaceProtectedOrder places an order on a primary and a backup circuit of the network, diverseOn may be empty
+func (s *Simulator) PlaceProtectedOrder(as Identity, orderID string, network string, bandwidth nsc.Bandwidth, diverseOn string) Result {
+	return s.Invoke(OMS, as, "placeOrder", orderID, as.Name, network, "", formatBandwidth(bandwidth), "", "", "", "", "", "true", diverseOn)
+}
+
+// SetCircuitAttribute sets a DataCircuit attribute that protected orders can be diverse on
+func (s *Simulator) SetCircuitAttribute(circuitID string, name string, value string) error {
+	return s.Invoke(NIMS, s.providerAdmin(circuitID), "setDataCircuitAttribute", circuitID, name, value).Error()
+}
+
+// SubmitWaitlistedOrder places an order that is queued on the circuit's waitlist when it does not fit
+func (s *Simulator) SubmitWaitlistedOrder(as Identity, orderID string, circuitID string, bandwidth nsc.Bandwidth, priority int) Result {
+	return s.Invoke(OMS, as, "prepareOrder", orderID, as.Name, circuitID, formatBandwidth(bandwidth), "", "true", strconv.Itoa(priority))
+}
+
+// ProcessWaitlist fulfils the waitlisted orders of a circuit that fit
+func (s *Simulator) ProcessWaitlist(circuitID string) Result {
+	return s.Invoke(BPM, AdminIdentity, "processWaitlist", circuitID)
+}
+
+// AddSite adds a Site to NIMS as admin
+func (s *Simulator) AddSite(siteID string, name string) error {
+	return s.Invoke(NIMS, AdminIdentity, "addSite", siteID, name).Error()
+}
+
+// LinkCircuit links a DataCircuit between an A-end and a Z-end site
+func (s *Simulator) LinkCircuit(circuitID string, aSiteID string, zSiteID string) error {
+	return s.Invoke(NIMS, s.providerAdmin(circuitID), "setCircuitEndpoints", circuitID, aSiteID, zSiteID).Error()
+}
+
+// PlacePathOrder places an order between two sites that BPM routes over circuits of the network by metric, hops
+// when empty
+func (s *Simulator) PlacePathOrder(as Identity, orderID string, network string, aSiteID string, zSiteID string, bandwidth nsc.Bandwidth, metric string) Result {
+	return s.Invoke(OMS, as, "placePathOrder", orderID, as.Name, network, aSiteID, zSiteID, formatBandwidth(bandwidth), metric)
+}
+
+// ComputePath returns the route BPM would take for bandwidth between two sites by metric, hops when empty
+func (s *Simulator) ComputePath(network string, aSiteID string, zSiteID string, bandwidth nsc.Bandwidth, metric string) Result {
+	return s.Query(BPM, AdminIdentity, "computePath", network, aSiteID, zSiteID, formatBandwidth(bandwidth), metric)
+}
+
+// ServiceLevel is the SLA of an order: latency and jitter in microseconds, loss and availability in parts per million,
+// a zero constraint is left out
+type ServiceLevel struct {
+	MaxLatency      int
+	MaxJitter       int
+	MaxLoss         int
+	MinAvailability int
+}
+
+// arguments formats the SLA as the trailing arguments of an order
+func (sla ServiceLevel) arguments() []string {
+	return []string{optionalInt(sla.MaxLatency), optionalInt(sla.MaxJitter), optionalInt(sla.MaxLoss), optionalInt(sla.MinAvailability)}
+}
+
+// SetCircuitPerformance records the performance of a DataCircuit in NIMS, a negative value is left unknown
+func (s *Simulator) SetCircuitPerformance(circuitID string, source string, latency int, jitter int, loss int, availability int) error {
+	return s.Invoke(NIMS, s.providerAdmin(circuitID), "setCircuitPerformance", circuitID, source, knownInt(latency), knownInt(jitter),
+		knownInt(loss), knownInt(availability)).Error()
+}
+
+// knownInt formats an optional integer argument that may be 0, a negative value leaves it out
+func knownInt(value int) string {
+	if value < 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// PlaceSLAOrder places an order on a network that BPM only places on circuits meeting the SLA
+func (s *Simulator) PlaceSLAOrder(as Identity, orderID string, network string, bandwidth nsc.Bandwidth, sla ServiceLevel) Result {
+	args := []string{orderID, as.Name, network, "", formatBandwidth(bandwidth), "", "", "", "", "", "", "", "", "", ""}
+	return s.Invoke(OMS, as, "placeOrder", append(args, sla.arguments()...)...)
+}
+
+// PlaceSLAPathOrder places an order between two sites whose route must meet the SLA end to end
+func (s *Simulator) PlaceSLAPathOrder(as Identity, orderID string, network string, aSiteID string, zSiteID string, bandwidth nsc.Bandwidth, metric string, sla ServiceLevel) Result {
+	args := []string{orderID, as.Name, network, aSiteID, zSiteID, formatBandwidth(bandwidth), metric, "", "", "", ""}
+	return s.Invoke(OMS, as, "placePathOrder", append(args, sla.arguments()...)...)
+}
+
+// AddDevice adds a Device at a site to NIMS as admin, siteID may be empty
+func (s *Simulator) AddDevice(deviceID string, name string, siteID string) error {
+	return s.Invoke(NIMS, AdminIdentity, "addDevice", deviceID, name, siteID).Error()
+}
+
+// AddCard adds a Card in a slot of a Device
+func (s *Simulator) AddCard(deviceID string, slot string) error {
+	return s.Invoke(NIMS, AdminIdentity, "addCard", deviceID, slot).Error()
+}
+
+// AddPort adds a free Port to a Card, a zero speed is left out
+func (s *Simulator) AddPort(deviceID string, slot string, portID string, speed nsc.Bandwidth) error {
+	return s.Invoke(NIMS, AdminIdentity, "addPort", deviceID, slot, portID, optionalBandwidth(speed)).Error()
+}
+
+// SetCircuitPort terminates the A-end or Z-end of a DataCircuit on a Port
+func (s *Simulator) SetCircuitPort(circuitID string, end string, deviceID string, slot string, portID string) Result {
+	return s.Invoke(NIMS, s.providerAdmin(circuitID), "setCircuitPort", circuitID, end, deviceID, slot, portID)
+}
+
+// AddEquipment adds in-stock Equipment to NIMS as admin, warrantyEndsOn may be empty
+func (s *Simulator) AddEquipment(serialNumber string, model string, warrantyEndsOn string) error {
+	return s.Invoke(NIMS, AdminIdentity, "addEquipment", serialNumber, model, "", warrantyEndsOn).Error()
+}
+
+// InstallEquipment installs Equipment in a slot of a Device, an empty slot installs it as the chassis
+func (s *Simulator) InstallEquipment(serialNumber string, deviceID string, slot string) Result {
+	return s.Invoke(NIMS, AdminIdentity, "installEquipment", serialNumber, deviceID, slot)
+}
+
+// SetEquipmentStatus marks Equipment faulty, installed or retired
+func (s *Simulator) SetEquipmentStatus(serialNumber string, status string) Result {
+	return s.Invoke(NIMS, AdminIdentity, "setEquipmentStatus", serialNumber, status)
+}
+
+// OpenRMA opens an RMA case for faulty Equipment
+func (s *Simulator) OpenRMA(rmaID string, serialNumber string, reason string) Result {
+	return s.Invoke(NIMS, AdminIdentity, "openRMA", rmaID, serialNumber, reason)
+}
+
+// SwapEquipment replaces the Equipment of an RMA case with in-stock Equipment
+func (s *Simulator) SwapEquipment(rmaID string, replacementSerialNumber string) Result {
+	return s.Invoke(NIMS, AdminIdentity, "swapEquipment", rmaID, replacementSerialNumber)
+}
+
+// CloseRMA closes an RMA case as returned or scrapped
+func (s *Simulator) CloseRMA(rmaID string, outcome string) Result {
+	return s.Invoke(NIMS, AdminIdentity, "closeRMA", rmaID, outcome)
+}
+
+// SetCircuitStatus marks a DataCircuit Up or Down in NIMS
+func (s *Simulator) SetCircuitStatus(circuitID string, status string) error {
+	return s.Invoke(NIMS, s.providerAdmin(circuitID), "setDataCircuitStatus", circuitID, status).Error()
+}
+
+// FailoverCircuit moves the orders of a Down circuit onto other circuits
+func (s *Simulator) FailoverCircuit(circuitID string) Result {
+	return s.Invoke(BPM, s.providerAdmin(circuitID), "failoverCircuit", circuitID)
+}
+
+// ScheduleMaintenance schedules an Outage or AtRisk maintenance window on a DataCircuit
+func (s *Simulator) ScheduleMaintenance(circuitID string, windowID string, startsOn string, endsOn string, windowType string) Result {
+	return s.Invoke(NIMS, s.providerAdmin(circuitID), "scheduleMaintenanceWindow", circuitID, windowID, startsOn, endsOn, windowType)
+}
+
+// BookBandwidth books bandwidth on a DataCircuit from startsOn up to endsOn for the identity as operator, through BPM
+func (s *Simulator) BookBandwidth(as Identity, circuitID string, bookingID string, bandwidth nsc.Bandwidth, startsOn string, endsOn string) Result {
+	return s.Invoke(BPM, as, "bookBandwidth", circuitID, bookingID, formatBandwidth(bandwidth), startsOn, endsOn, "", as.Name)
+}
+
+// CancelBooking cancels a booking of a DataCircuit through BPM
+func (s *Simulator) CancelBooking(as Identity, circuitID string, bookingID string) Result {
+	return s.Invoke(BPM, as, "cancelBooking", circuitID, bookingID)
+}
+
+// SetServiceClass adds a service class selling percent/100 times the physical bandwidth and sets it on a network
+func (s *Simulator) SetServiceClass(className string, percent int, network string) error {
+	result := s.Invoke(NIMS, AdminIdentity, "setServiceClass", className, strconv.Itoa(percent))
+	if result.Error() != nil {
+		return result.Error()
+	}
+	return s.Invoke(NIMS, AdminIdentity, "setNetworkServiceClass", network, className).Error()
+}
+
+// ReportConfiguration answers a configuration job the way the configuration agent does
+func (s *Simulator) ReportConfiguration(orderID string, status string, deviceID string, message string) Result {
+	return s.Invoke(ANCS, AgentIdentity, "reportConfigurationApplied", orderID, status, deviceID, message)
+}
+
+// ReportLegConfiguration answers the configuration job of one circuit leg of a split order
+func (s *Simulator) ReportLegConfiguration(orderID string, circuitID string, status string, deviceID string, message string) Result {
+	return s.Invoke(ANCS, AgentIdentity, "reportConfigurationApplied", orderID, status, deviceID, message, circuitID)
+}
+
+// GetState reads a key from a chaincode's ledger, nil when it does not exist
+func (s *Simulator) GetState(chaincode string, key string) []byte {
+	stub, ok := s.stubs[chaincode]
+	if !ok {
+		return nil
+	}
+	return stub.State[key]
+}
+
+// GetCompositeState reads a composite key from a chaincode's ledger
+func (s *Simulator) GetCompositeState(chaincode string, objectType string, attributes ...string) ([]byte, error) {
+	stub, ok := s.stubs[chaincode]
+	if !ok {
+		return nil, fmt.Errorf("unknown chaincode %s", chaincode)
+	}
+	key, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return stub.State[key], nil
+}
+
+// Circuit reads a DataCircuit from NIMS
+func (s *Simulator) Circuit(circuitID string) (nims.DataCircuit, error) {
+	var circuit nims.DataCircuit
+	err := s.readState(NIMS, circuitID, &circuit)
+	return circuit, err
+}
+
+// Order reads an order from ANCS, the order store
+func (s *Simulator) Order(orderID string) (ancs.Order, error) {
+	var order ancs.Order
+	err := s.readState(ANCS, orderID, &order)
+	return order, err
+}
+
+// ConfigurationJob reads the configuration job of an order from ANCS
+func (s *Simulator) ConfigurationJob(orderID string) (ancs.ConfigurationJob, error) {
+	var job ancs.ConfigurationJob
+	value, err := s.GetCompositeState(ANCS, "ConfigurationJob", orderID)
+	if err != nil {
+		return job, err
+	}
+	if value == nil {
+		return job, fmt.Errorf("no configuration job for order %s", orderID)
+	}
+	err = json.Unmarshal(value, &job)
+	return job, err
+}
+
+// ExpectBandwidth checks the allocated and unallocated bandwidth of a circuit
+func (s *Simulator) ExpectBandwidth(circuitID string, allocated nsc.Bandwidth, unallocated nsc.Bandwidth) error {
+	circuit, err := s.Circuit(circuitID)
+	if err != nil {
+		return err
+	}
+	if circuit.AllocatedBandwidth != allocated || circuit.UnallocatedBandwidth != unallocated {
+		return fmt.Errorf("circuit %s has %s allocated and %s unallocated, expected %s and %s",
+			circuitID, circuit.AllocatedBandwidth, circuit.UnallocatedBandwidth, allocated, unallocated)
+	}
+	return nil
+}
+
+// providerAdmin is the admin of the circuit's provider, AdminIdentity for a circuit that was never added
+func (s *Simulator) providerAdmin(circuitID string) Identity {
+	circuit, err := s.Circuit(circuitID)
+	if err != nil {
+		return AdminIdentity
+	}
+	return ProviderAdminIdentity(circuit.ProviderID)
+}
+
+func (s *Simulator) readState(chaincode string, key string, value interface{}) error {
+	data := s.GetState(chaincode, key)
+	if data == nil {
+		return fmt.Errorf("%s has no state for %s", chaincode, key)
+	}
+	return json.Unmarshal(data, value)
+}
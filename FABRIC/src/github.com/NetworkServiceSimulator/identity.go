@@ -0,0 +1,81 @@
+package simulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/msp"
+)
+
+// ============================================================================================================================
+// Identities - transactions are submitted as a serialized X.509 identity carrying the Fabric CA "hf.EnrollmentID" and
+// "role" attributes, so the chaincodes' cid based operator and role checks run exactly as they do on a peer.
+// ============================================================================================================================
+
+// attributesOID is the certificate extension the Fabric CA stores enrollment attributes in
+var attributesOID = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 1}
+
+type Identity struct {
+	MSPID string
+	Name  string
+	Role  string
+}
+
+var (
+	AdminIdentity = Identity{MSPID: "Org1MSP", Name: "admin", Role: "admin"}
+	AgentIdentity = Identity{MSPID: "Org1MSP", Name: "agent", Role: "agent"}
+)
+
+// ProviderAdminIdentity is the admin of the organization providing circuits under providerID, NIMS only lets the
+// provider change its circuits
+func ProviderAdminIdentity(providerID string) Identity {
+	return Identity{MSPID: providerID, Name: "admin", Role: "admin"}
+}
+
+// OperatorIdentity is an enrolled user without a role, the name doubles as the OperatorID of its orders
+func OperatorIdentity(name string) Identity {
+	return Identity{MSPID: "Org1MSP", Name: name}
+}
+
+// serialize returns the creator bytes a peer would hand to the chaincode
+func (i Identity) serialize() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: i.Name, Organization: []string{i.MSPID}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+
+	attributes := map[string]string{"hf.EnrollmentID": i.Name}
+	if i.Role != "" {
+		attributes["role"] = i.Role
+	}
+	attrs, err := json.Marshal(map[string]map[string]string{"attrs": attributes})
+	if err != nil {
+		return nil, err
+	}
+	template.ExtraExtensions = []pkix.Extension{{Id: attributesOID, Value: attrs}}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	return proto.Marshal(&msp.SerializedIdentity{Mspid: i.MSPID, IdBytes: certificatePEM})
+}
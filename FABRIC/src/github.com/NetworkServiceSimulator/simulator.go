@@ -0,0 +1,382 @@
+// Package simulator wires the four network service chaincodes together in process for integration testing:
+//
+//	s, err := simulator.New("mychannel")
+//	err = s.SeedCircuit("C1", "NET1", "P1", 100*simulator.Mbps)
+//	result := s.SubmitOrder(simulator.OperatorIdentity("alice"), "O1", "C1", 60*simulator.Mbps)
+//	err = s.ExpectBandwidth("C1", 60*simulator.Mbps, 40*simulator.Mbps)
+package simulator
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/AutomaticNetworkConfigurationService/ancs"
+	"github.com/BusinessProcessManagementService/bpm"
+	"github.com/NetworkInventoryManagementService/nims"
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/OrderManagementService/oms"
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Network Service Simulator - runs NIMS, BPM, OMS and ANCS in process on shim.MockStub instances peered with
+// MockPeerChaincode, so chaincode to chaincode calls go through the real Invoke of each chaincode. On top of the mock
+// stubs it adds what a peer would do: the creator identity and signed proposal of every call, reads of the committed
+// state only, with the writes of every chaincode the transaction calls buffered until it commits and dropped when it
+// fails, and only the event of the invoked chaincode reaching the client.
+// ============================================================================================================================
+
+// names the chaincodes are deployed under
+const (
+	NIMS = "NetworkInventoryManagementService"
+	BPM  = "BusinessProcessManagementService"
+	OMS  = "OrderManagementService"
+	ANCS = "AutomaticNetworkConfigurationService"
+)
+
+const chaincodeVersion = "1.0"
+
+type Simulator struct {
+	ChannelID string
+
+	stubs      map[string]*shim.MockStub
+	creator    []byte
+	proposal   *pb.SignedProposal
+	identities map[Identity][]byte
+	txCount    int
+	// writes buffers the writes of the running transaction per chaincode, a nil value deletes the key
+	writes map[string]map[string][]byte
+}
+
+// Result is what a client receives for one transaction
+type Result struct {
+	Response pb.Response
+	Event    *nsc.EventBatch
+	Err      *nsc.ChaincodeError
+}
+
+// New instantiates the four chaincodes on one channel and registers their dependencies as admin
+func New(channelID string) (*Simulator, error) {
+	s := &Simulator{
+		ChannelID:  channelID,
+		stubs:      map[string]*shim.MockStub{},
+		identities: map[Identity][]byte{},
+		writes:     map[string]map[string][]byte{},
+	}
+
+	chaincodes := map[string]shim.Chaincode{
+		NIMS: new(nims.NetworkInventoryManagementChaincode),
+		BPM:  new(bpm.BusinessProcessManagementChaincode),
+		OMS:  new(oms.OrderManagementChaincode),
+		ANCS: new(ancs.AutomaticNetworkConfigurationChaincode),
+	}
+	for name, chaincode := range chaincodes {
+		stub := shim.NewMockStub(name, identityChaincode{chaincode, name, s})
+		stub.ChannelID = channelID
+		s.stubs[name] = stub
+	}
+
+	// every stub can call every other one, with or without an explicit channel
+	for _, stub := range s.stubs {
+		for name, other := range s.stubs {
+			if other != stub {
+				stub.MockPeerChaincode(name, other)
+				stub.MockPeerChaincode(name+"/"+channelID, other)
+			}
+		}
+	}
+
+	err := s.setCreator(AdminIdentity)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range s.chaincodeNames() {
+		response := s.stubs[name].MockInit(s.nextTxID(), toChaincodeArgs("init", "init_for_chaincode", "0"))
+		if response.Status != shim.OK {
+			return nil, fmt.Errorf("Init of %s failed: %s", name, response.Message)
+		}
+		s.commit()
+	}
+
+	dependencies := []struct{ chaincode, alias, target string }{
+		{OMS, "BPM", BPM},
+		{OMS, "ANCS", ANCS},
+		{BPM, "NIMS", NIMS},
+		{BPM, "ANCS", ANCS},
+		{BPM, "OMS", OMS},
+		{ANCS, "OMS", OMS},
+		{ANCS, "BPM", BPM},
+		{NIMS, "OMS", OMS},
+		{NIMS, "BPM", BPM},
+	}
+	for _, dependency := range dependencies {
+		result := s.Invoke(dependency.chaincode, AdminIdentity, "registerChaincodeDependency", dependency.alias, dependency.target, chaincodeVersion, channelID)
+		if !result.OK() {
+			return nil, result.Error()
+		}
+	}
+	return s, nil
+}
+
+// NewForTest is New on "mychannel" for tests, failing the test when the chaincodes cannot be set up
+func NewForTest(t testing.TB) *Simulator {
+	t.Helper()
+	s, err := New("mychannel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// Invoke submits one transaction to a chaincode as the given identity and commits its writes when it succeeds
+func (s *Simulator) Invoke(chaincode string, as Identity, function string, args ...string) Result {
+	result := s.simulate(chaincode, as, function, args...)
+	if result.OK() {
+		s.commit()
+	}
+	s.writes = map[string]map[string][]byte{}
+	return result
+}
+
+// Query runs a transaction and discards its writes, as evaluating a proposal without ordering it would
+func (s *Simulator) Query(chaincode string, as Identity, function string, args ...string) Result {
+	result := s.simulate(chaincode, as, function, args...)
+	s.writes = map[string]map[string][]byte{}
+	return result
+}
+
+// simulate executes one transaction against the committed state, leaving its writes in s.writes
+func (s *Simulator) simulate(chaincode string, as Identity, function string, args ...string) Result {
+	stub, ok := s.stubs[chaincode]
+	if !ok {
+		return failedResult(fmt.Sprintf("unknown chaincode %s", chaincode))
+	}
+
+	err := s.setCreator(as)
+	if err != nil {
+		return failedResult(err.Error())
+	}
+	err = s.setProposal(chaincode)
+	if err != nil {
+		return failedResult(err.Error())
+	}
+
+	response := stub.MockInvoke(s.nextTxID(), toChaincodeArgs(append([]string{function}, args...)...))
+	events := s.drainEvents()
+
+	result := Result{Response: response}
+	if response.Status != shim.OK {
+		result.Err = nsc.ParseErrorMessage(response.Message)
+		return result
+	}
+
+	if event, ok := events[chaincode]; ok {
+		var batch nsc.EventBatch
+		err = json.Unmarshal(event.Payload, &batch)
+		if err != nil {
+			return failedResult(fmt.Sprintf("event of %s is not an event batch: %s", chaincode, err.Error()))
+		}
+		result.Event = &batch
+	}
+	return result
+}
+
+func (r Result) OK() bool {
+	return r.Response.Status == shim.OK
+}
+
+// Error returns the decoded error envelope of a failed result, nil on success
+func (r Result) Error() error {
+	if r.OK() {
+		return nil
+	}
+	if r.Err == nil {
+		return fmt.Errorf("%s", r.Response.Message)
+	}
+	return fmt.Errorf("%s: %s", r.Err.Code, r.Err.Message)
+}
+
+// Records returns the event records of the given type
+func (r Result) Records(eventType string) []nsc.EventRecord {
+	var records []nsc.EventRecord
+	if r.Event == nil {
+		return records
+	}
+	for _, record := range r.Event.Records {
+		if record.Type == eventType {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Stub exposes the mock stub of a chaincode for direct ledger access
+func (s *Simulator) Stub(chaincode string) *shim.MockStub {
+	return s.stubs[chaincode]
+}
+
+// ========================================== peer behaviour =================================================================
+
+// identityChaincode hands the current creator and signed proposal to the chaincode, the 1.4 MockStub returns neither,
+// and buffers the chaincode's writes in the running transaction
+type identityChaincode struct {
+	chaincode shim.Chaincode
+	name      string
+	simulator *Simulator
+}
+
+func (c identityChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	return c.chaincode.Init(c.transactionStub(stub))
+}
+
+func (c identityChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	return c.chaincode.Invoke(c.transactionStub(stub))
+}
+
+func (c identityChaincode) transactionStub(stub shim.ChaincodeStubInterface) creatorStub {
+	writes, ok := c.simulator.writes[c.name]
+	if !ok {
+		writes = map[string][]byte{}
+		c.simulator.writes[c.name] = writes
+	}
+	return creatorStub{stub, c.simulator.creator, c.simulator.proposal, writes}
+}
+
+// creatorStub reads through to the mock stub, which only ever holds committed state, as a peer does not let a
+// transaction read its own writes
+type creatorStub struct {
+	shim.ChaincodeStubInterface
+	creator  []byte
+	proposal *pb.SignedProposal
+	writes   map[string][]byte
+}
+
+func (s creatorStub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+// GetSignedProposal returns the proposal the client sent, every chaincode the transaction calls sees the same one
+func (s creatorStub) GetSignedProposal() (*pb.SignedProposal, error) {
+	return s.proposal, nil
+}
+
+func (s creatorStub) PutState(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	s.writes[key] = value
+	return nil
+}
+
+func (s creatorStub) DelState(key string) error {
+	s.writes[key] = nil
+	return nil
+}
+
+func (s *Simulator) setCreator(as Identity) error {
+	creator, ok := s.identities[as]
+	if !ok {
+		var err error
+		creator, err = as.serialize()
+		if err != nil {
+			return err
+		}
+		s.identities[as] = creator
+	}
+	s.creator = creator
+	return nil
+}
+
+// setProposal makes the signed proposal of the next transaction one sent to the chaincode
+func (s *Simulator) setProposal(chaincode string) error {
+	invocation, err := proto.Marshal(&pb.ChaincodeInvocationSpec{ChaincodeSpec: &pb.ChaincodeSpec{ChaincodeId: &pb.ChaincodeID{Name: chaincode}}})
+	if err != nil {
+		return err
+	}
+	payload, err := proto.Marshal(&pb.ChaincodeProposalPayload{Input: invocation})
+	if err != nil {
+		return err
+	}
+	proposal, err := proto.Marshal(&pb.Proposal{Payload: payload})
+	if err != nil {
+		return err
+	}
+	s.proposal = &pb.SignedProposal{ProposalBytes: proposal}
+	return nil
+}
+
+// commit applies the buffered writes of the transaction to the state of each chaincode
+func (s *Simulator) commit() {
+	for name, writes := range s.writes {
+		stub := s.stubs[name]
+		for key, value := range writes {
+			if value == nil {
+				delete(stub.State, key)
+			} else {
+				stub.State[key] = value
+			}
+		}
+
+		keys := make([]string, 0, len(stub.State))
+		for key := range stub.State {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		stub.Keys = list.New()
+		for _, key := range keys {
+			stub.Keys.PushBack(key)
+		}
+	}
+	s.writes = map[string]map[string][]byte{}
+}
+
+// drainEvents empties every event channel, keeping the last event each chaincode set
+func (s *Simulator) drainEvents() map[string]*pb.ChaincodeEvent {
+	events := map[string]*pb.ChaincodeEvent{}
+	for name, stub := range s.stubs {
+		for {
+			select {
+			case event := <-stub.ChaincodeEventsChannel:
+				events[name] = event
+				continue
+			default:
+			}
+			break
+		}
+	}
+	return events
+}
+
+func (s *Simulator) nextTxID() string {
+	s.txCount++
+	return fmt.Sprintf("simulated-tx-%d", s.txCount)
+}
+
+func (s *Simulator) chaincodeNames() []string {
+	names := make([]string, 0, len(s.stubs))
+	for name := range s.stubs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func failedResult(message string) Result {
+	return Result{Response: shim.Error(message), Err: nsc.NewError(nsc.CodeInternal, "%s", message)}
+}
+
+func toChaincodeArgs(args ...string) [][]byte {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	return bargs
+}
@@ -0,0 +1,115 @@
+package simulator_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+var alice = simulator.OperatorIdentity("alice")
+
+// newSimulator is a simulator with the 100M circuit C1 of NET1
+func newSimulator(t *testing.T) *simulator.Simulator {
+	s := simulator.NewForTest(t)
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func expectCode(t *testing.T, result simulator.Result, code string) {
+	t.Helper()
+	if result.OK() {
+		t.Fatalf("expected %s, the call succeeded", code)
+	}
+	if result.Err == nil || result.Err.Code != code {
+		t.Fatalf("expected %s, got %v", code, result.Error())
+	}
+}
+
+// expectNoOrder checks a failed order left neither an order in ANCS nor an allocation in NIMS
+func expectNoOrder(t *testing.T, s *simulator.Simulator, orderID string) {
+	t.Helper()
+	if _, err := s.Order(orderID); err == nil {
+		t.Errorf("expected no order %s", orderID)
+	}
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOrderIsCompletedThroughEveryChaincode(t *testing.T) {
+	s := newSimulator(t)
+
+	// prepareOrder in OMS, checkOnNIMSAndRespond in BPM, allocation in NIMS and completeOrder in ANCS
+	result := s.SubmitOrder(alice, "O1", "C1", 60*simulator.Mbps)
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if len(result.Records(nsc.EventOrderPrepared)) != 1 {
+		t.Errorf("expected the OMS event to carry one %s record, got %+v", nsc.EventOrderPrepared, result.Event)
+	}
+
+	order, err := s.Order("O1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != "Completed" || order.OperatorID != "alice" || order.DataCircuitID != "C1" {
+		t.Errorf("expected O1 of alice Completed on C1, got %+v", order)
+	}
+	if err = s.ExpectBandwidth("C1", 60*simulator.Mbps, 40*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFailingChaincodeCallReturnsAnErrorEnvelope(t *testing.T) {
+	s := newSimulator(t)
+
+	// NIMS fails the lookup of an unknown circuit, OMS passes its code on
+	result := s.SubmitOrder(alice, "O1", "C9", 60*simulator.Mbps)
+	expectCode(t, result, nsc.CodeNotFound)
+	expectNoOrder(t, s, "O1")
+
+	// point the BPM dependency of OMS at a chaincode that is not deployed, InvokeChaincode itself fails
+	key, err := s.Stub(simulator.OMS).CreateCompositeKey("ChaincodeDependency", []string{"BPM"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dependency map[string]interface{}
+	if err = json.Unmarshal(s.GetState(simulator.OMS, key), &dependency); err != nil {
+		t.Fatal(err)
+	}
+	dependency["ChaincodeName"] = "MissingService"
+	s.Stub(simulator.OMS).State[key], err = json.Marshal(dependency)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result = s.SubmitOrder(alice, "O1", "C1", 60*simulator.Mbps)
+	expectCode(t, result, nsc.CodeUpstreamFailure)
+	if result.Err.Cause == nil {
+		t.Errorf("expected the peer's message as the cause, got %+v", result.Err)
+	}
+	expectNoOrder(t, s, "O1")
+}
+
+func TestOrderUnderTheWrongIdentity(t *testing.T) {
+	s := newSimulator(t)
+
+	// an operator cannot skip OMS and have BPM allocate for it, nor re-point the chaincodes OMS calls
+	expectCode(t, s.Invoke(simulator.BPM, alice, "checkOnNIMSAndRespond", "C1", "60M", "O1", "alice"), nsc.CodeForbidden)
+	expectCode(t, s.Invoke(simulator.OMS, alice, "registerChaincodeDependency", "BPM", simulator.BPM, "1.0", "mychannel"), nsc.CodeForbidden)
+	expectNoOrder(t, s, "O1")
+
+	// nor change the inventory of a provider it does not belong to
+	expectCode(t, s.Invoke(simulator.NIMS, simulator.ProviderAdminIdentity("Org2MSP"), "setDataCircuitStatus", "C1", "Down"), nsc.CodeForbidden)
+	circuit, err := s.Circuit("C1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if circuit.Status == "Down" {
+		t.Error("expected C1 to keep its status")
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/OrderManagementService/oms"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+// ============================================================================================================================
+// Main - the chaincode itself lives in package oms so it can also be loaded in process by the NetworkServiceSimulator
+// ============================================================================================================================
+func main() {
+	err := shim.Start(new(oms.OrderManagementChaincode))
+	if err != nil {
+		fmt.Printf("Error starting Simple chaincode - %s", err)
+	}
+}
@@ -0,0 +1,518 @@
+package oms
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// OrderManagementChaincode example simple Chaincode implementation
+type OrderManagementChaincode struct {
+}
+
+const chaincodeName = "OrderManagementService"
+
+const chaincodeVersion = "1.0"
+
+// ============================================================================================================================
+// Asset Definitions - The ledger will store answers with hash id and cid
+// ============================================================================================================================
+type Order struct {
+	OrderID        string        `json:"QuestionHashID"`
+	DataCircuitID  string        `json:"QuestionerID"`
+	OrderBandwidth nsc.Bandwidth `json:"OrderBandwidth"`
+	OperatorID     string        `json:"OperatorID"`
+	OrderSatus     bool          `json:"OrderSatus"`
+	CreatedOn      string        `json:"CreatedOn"`
+	// Status is Completed or Rejected, RejectionReason carries the reason code of a rejected order
+	Status          string `json:"Status"`
+	RejectionReason string `json:"RejectionReason,omitempty"`
+	// Placement is the strategy BPM chose DataCircuitID with, empty when the operator named the circuit
+	Placement string `json:"Placement,omitempty"`
+	// Legs lists every circuit of an order split across circuits or routed over a path, DataCircuitID is then the circuit
+	// of the first leg
+	Legs []OrderLeg `json:"Legs,omitempty"`
+	// BackupCircuitID is set for a protected order, DataCircuitID is then its primary circuit
+	BackupCircuitID string `json:"BackupCircuitID,omitempty"`
+}
+
+// OrderLeg is the part of an order allocated on one circuit. The legs of a path order are its hops in order from the
+// A-end, each carries the whole OrderBandwidth and names the sites it is crossed between.
+type OrderLeg struct {
+	DataCircuitID string        `json:"DataCircuitID"`
+	Bandwidth     nsc.Bandwidth `json:"Bandwidth"`
+	FromSiteID    string        `json:"FromSiteID,omitempty"`
+	ToSiteID      string        `json:"ToSiteID,omitempty"`
+}
+
+// PreparedOrder is the data of an OrderPrepared event
+// an order placed by network has no DataCircuitID yet, BPM's CircuitSelected record names the circuit it chose
+type PreparedOrder struct {
+	OrderID        string        `json:"OrderID"`
+	OperatorID     string        `json:"OperatorID"`
+	DataCircuitID  string        `json:"DataCircuitID,omitempty"`
+	OrderBandwidth nsc.Bandwidth `json:"OrderBandwidth"`
+	HomeChannel    string        `json:"HomeChannel"`
+	CircuitNetwork string        `json:"CircuitNetwork,omitempty"`
+	ProviderID     string        `json:"ProviderID,omitempty"`
+	Strategy       string        `json:"Strategy,omitempty"`
+	Protected      bool          `json:"Protected,omitempty"`
+	// the sites and the route metric of an order routed over a path
+	ASiteID string `json:"ASiteID,omitempty"`
+	ZSiteID string `json:"ZSiteID,omitempty"`
+	Metric  string `json:"Metric,omitempty"`
+	// the bandwidth profile, OrderBandwidth is then its committed rate
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	BurstSize       int           `json:"BurstSize,omitempty"`
+	ClassOfService  string        `json:"ClassOfService,omitempty"`
+	// the SLA constraints
+	MaxLatency      int `json:"MaxLatency,omitempty"`
+	MaxJitter       int `json:"MaxJitter,omitempty"`
+	MaxLoss         int `json:"MaxLoss,omitempty"`
+	MinAvailability int `json:"MinAvailability,omitempty"`
+}
+
+// Internal data maps
+type DataCircuit struct {
+	CircuitID            string        `json:"CircuitID"`
+	CircuitNetwork       string        `json:"CircuitNetwork"`
+	ProviderID           string        `json:"ProviderID"`
+	IsConfigured         bool          `json:"IsConfigured"`
+	TotalBandwidth       nsc.Bandwidth `json:"TotalBandwidth"`
+	AllocatedBandwidth   nsc.Bandwidth `json:"AllowedBandwidth"`
+	UnallocatedBandwidth nsc.Bandwidth `json:"unallowedBandwidth"`
+	CreatedOn            string        `json:"CreatedOn"`
+	// Attributes such as a site or path are compared when choosing diverse primary and backup circuits
+	Attributes map[string]string `json:"Attributes,omitempty"`
+	// Status is Up or Down, circuits added before it was tracked are Up
+	Status string `json:"Status,omitempty"`
+	// ServiceClass is the class set on the circuit itself, SellableBandwidth is TotalBandwidth times the oversubscription
+	// ratio of its class and left out when it is sold 1:1
+	ServiceClass      string        `json:"ServiceClass,omitempty"`
+	SellableBandwidth nsc.Bandwidth `json:"SellableBandwidth,omitempty"`
+	// ExcessBandwidth is the excess rate allocated on top of the committed rates, ExcessLimit caps it and is left out
+	// when it is the TotalBandwidth
+	ExcessBandwidth nsc.Bandwidth `json:"ExcessBandwidth,omitempty"`
+	ExcessLimit     nsc.Bandwidth `json:"ExcessLimit,omitempty"`
+	// Performance is the latency, jitter, loss and availability NIMS recorded for the circuit
+	Performance *CircuitPerformance `json:"Performance,omitempty"`
+	// FaultyEquipment names the faulty or RMA equipment above the ports the circuit is terminated on
+	FaultyEquipment []string `json:"FaultyEquipment,omitempty"`
+}
+
+// CircuitPerformance is the NIMS record of a circuit's performance, latency and jitter are in microseconds, loss and
+// availability in parts per million, a nil value is unknown
+type CircuitPerformance struct {
+	Latency      *int   `json:"Latency,omitempty"`
+	Jitter       *int   `json:"Jitter,omitempty"`
+	Loss         *int   `json:"Loss,omitempty"`
+	Availability *int   `json:"Availability,omitempty"`
+	Source       string `json:"Source"`
+	UpdatedBy    string `json:"UpdatedBy"`
+	UpdatedOn    string `json:"UpdatedOn"`
+}
+
+// ============================================================================================================================
+// Init - initialize the chaincode
+// ============================================================================================================================
+func (t *OrderManagementChaincode) Init(stub shim.ChaincodeStubInterface) pb.Response {
+	fmt.Println("Answer Store Channel Is Starting Up")
+	funcName, args := stub.GetFunctionAndParameters()
+	var err error
+	txId := stub.GetTxID()
+
+	fmt.Println("  Init() is running")
+	fmt.Println("  Transaction ID: ", txId)
+	fmt.Println("  GetFunctionAndParameters() function: ", funcName)
+	fmt.Println("  GetFunctionAndParameters() args count: ", len(args))
+	fmt.Println("  GetFunctionAndParameters() args found: ", args)
+
+	// expecting 2 args for instantiate or upgrade
+	if len(args) != 2 {
+		return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Init(): Incorrect number of arguments. Expecting 2 but got %d", len(args)))
+	}
+
+	err = stub.PutState(args[0], []byte(args[1]))
+	if err != nil {
+		return nsc.ErrorResponse(err) //self-test fail
+	}
+
+	fmt.Println("Ready for action") //self-test pass
+	return shim.Success(nil)
+}
+
+// ============================================================================================================================
+// Invoke - Our entry point for Invocations
+// ============================================================================================================================
+func (t *OrderManagementChaincode) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
+	function, args := stub.GetFunctionAndParameters()
+	fmt.Println(" ")
+	fmt.Println("starting invoke, for - " + function)
+
+	return nsc.InvokeFunction(stub, chaincodeName, chaincodeVersion, chaincodeFunctions, function, args)
+}
+
+// ============================================================================================================================
+// Query - legacy function
+// ============================================================================================================================
+func (t *OrderManagementChaincode) Query(stub shim.ChaincodeStubInterface) pb.Response {
+	return nsc.ErrorResponse(nsc.NewError(nsc.CodeInvalidArgument, "Unknown supported call - Query()"))
+}
+
+// ============================================================================================================================
+// Function Registry - see github.com/NetworkServiceCommon/nsc/registry.go
+// ============================================================================================================================
+var chaincodeFunctions = []nsc.ChaincodeFunction{
+	{
+		Name:        "prepareOrder",
+		Description: "Places an order for bandwidth on a DataCircuit and hands it to BPM, optionally waitlisting it when it does not fit",
+		Arguments:   prepareOrderArguments,
+		Handler:     prepareOrder,
+	},
+	{
+		Name:        "placeOrder",
+		Description: "Places an order for bandwidth on a network, optionally of one provider, and lets BPM choose the DataCircuit",
+		Arguments:   placeOrderArguments,
+		Handler:     placeOrder,
+	},
+	{
+		Name:        "placePathOrder",
+		Description: "Places an order for bandwidth between two sites and lets BPM route it over DataCircuits of a network",
+		Arguments:   placePathOrderArguments,
+		Handler:     placePathOrder,
+	},
+	{
+		Name:        "getOrder",
+		Description: "Returns an order from ANCS by its ID",
+		Arguments:   getOrderArguments,
+		ReadOnly:    true,
+		Handler:     getOrder,
+	},
+	{
+		Name:         "registerChaincodeDependency",
+		Description:  "Registers the chaincode name, version and channel behind a dependency alias",
+		Arguments:    nsc.DependencyArguments(knownDependencies),
+		RequiredRole: nsc.AdminRole,
+		Handler:      nsc.RegisterChaincodeDependency,
+	},
+	{
+		Name:        "getChaincodeDependencies",
+		Description: "Lists the registered chaincode dependencies",
+		Arguments:   nsc.ArgumentSchema{},
+		ReadOnly:    true,
+		Handler:     nsc.GetChaincodeDependencies,
+	},
+	{
+		Name:        "checkCircuitCapacity",
+		Description: "Checks whether bandwidth fits on a DataCircuit through BPM",
+		Arguments:   checkCircuitCapacityArguments,
+		ReadOnly:    true,
+		Handler:     checkCircuitCapacity,
+	},
+}
+
+// ============================================================================================================================
+// Argument Schemas - see github.com/NetworkServiceCommon/nsc/arguments.go
+// ============================================================================================================================
+var getOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+}
+
+// bandwidth profiles are checked again by BPM, burst sizes are in kilobytes
+const maxBurstSize = 1000000
+
+var classesOfService = []string{"best-effort", "assured", "expedited"}
+
+// SLA constraints are checked by BPM, latency and jitter are in microseconds, loss and availability in parts per million
+const (
+	maxDelay        = 1000000000
+	partsPerMillion = 1000000
+)
+
+var prepareOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "DataCircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "Waitlist", Type: nsc.ArgBoolean},
+	{Name: "Priority", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(nsc.MaxPriority)},
+	// the bandwidth profile: an excess rate on top of the committed OrderBandwidth, a burst size in kilobytes and a
+	// class of service, best-effort unless named. The SLA constraints leave out circuits that do not meet them.
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "BurstSize", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxBurstSize)},
+	{Name: "ClassOfService", Type: nsc.ArgString, Enum: classesOfService},
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+// Strategy defaults to the placement strategy set in BPM
+var placeOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ProviderID", Type: nsc.ArgString, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "Strategy", Type: nsc.ArgString, Enum: []string{"best-fit", "first-fit", "worst-fit", "least-utilized"}},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "Waitlist", Type: nsc.ArgBoolean},
+	{Name: "Priority", Type: nsc.ArgInteger, Minimum: nsc.Bound(0), Maximum: nsc.Bound(nsc.MaxPriority)},
+	// lets BPM split an order no single circuit can hold across circuits of the network, all legs or none
+	{Name: "AllowSplit", Type: nsc.ArgBoolean},
+	// asks BPM for a primary and a backup circuit of different providers, diverse on the comma separated DiverseOn
+	// DataCircuit attributes
+	{Name: "Protected", Type: nsc.ArgBoolean},
+	{Name: "DiverseOn", Type: nsc.ArgString, MaxLength: nsc.MaxNameLength, Pattern: `^[A-Za-z0-9_]+(,[A-Za-z0-9_]+)*$`},
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "BurstSize", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxBurstSize)},
+	{Name: "ClassOfService", Type: nsc.ArgString, Enum: classesOfService},
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+// Metric is what BPM minimises along the route, hops unless named
+var placePathOrderArguments = nsc.ArgumentSchema{
+	{Name: "OrderID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OperatorID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "CircuitNetwork", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ASiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "ZSiteID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "OrderBandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "Metric", Type: nsc.ArgString, Enum: []string{"hops", "latency", "cost"}},
+	{Name: "ExpiresOn", Type: nsc.ArgString, Pattern: nsc.TimestampPattern},
+	{Name: "ExcessBandwidth", Type: nsc.ArgBandwidth, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+	{Name: "BurstSize", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxBurstSize)},
+	{Name: "ClassOfService", Type: nsc.ArgString, Enum: classesOfService},
+	{Name: "MaxLatency", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxJitter", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(maxDelay)},
+	{Name: "MaxLoss", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+	{Name: "MinAvailability", Type: nsc.ArgInteger, Minimum: nsc.Bound(1), Maximum: nsc.Bound(partsPerMillion)},
+}
+
+// ============================================================================================================================
+// Get Answer - get a answer asset from ledger
+// ============================================================================================================================
+func getOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting getOrder")
+
+	orderID := arguments.Str("OrderID")
+
+	fmt.Println("========================= recieved args ==========================")
+	fmt.Println(arguments)
+
+	// ==================================== fetch the order from ANCS ===========================================
+	response := nsc.InvokeDependency(stub, ancsDependency, "getOrder", orderID)
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(ancsDependency, "getOrder", response))
+	}
+	orderBytes := response.Payload
+
+	str := fmt.Sprintf("%s", orderBytes)
+	fmt.Println("string is " + str)
+
+	return shim.Success(orderBytes)
+}
+
+func prepareOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting prepareOrder")
+
+	orderID := arguments.Str("OrderID")
+	operatorID := arguments.Str("OperatorID")
+	dataCircuitID := arguments.Str("DataCircuitID")
+	orderBandwidth := arguments.Bandwidth("OrderBandwidth").Argument()
+
+	fmt.Println("========================= recieved args ==========================")
+	fmt.Println(arguments)
+
+	err := nsc.AssertOperator(stub, operatorID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	// ==================================== refuse orders for circuits homed on another channel ==================
+	homeChannel, err := resolveCircuitHomeChannel(stub, dataCircuitID)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+	err = assertWritableChannel(stub, dataCircuitID, homeChannel)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	err = events.Add(nsc.EventOrderPrepared, PreparedOrder{
+		OrderID:         orderID,
+		OperatorID:      operatorID,
+		DataCircuitID:   dataCircuitID,
+		OrderBandwidth:  arguments.Bandwidth("OrderBandwidth"),
+		HomeChannel:     homeChannel,
+		ExcessBandwidth: arguments.Bandwidth("ExcessBandwidth"),
+		BurstSize:       arguments.Integer("BurstSize"),
+		ClassOfService:  arguments.Str("ClassOfService"),
+		MaxLatency:      arguments.Integer("MaxLatency"),
+		MaxJitter:       arguments.Integer("MaxJitter"),
+		MaxLoss:         arguments.Integer("MaxLoss"),
+		MinAvailability: arguments.Integer("MinAvailability"),
+	})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	// ==================================== hand the order over to BPM ===========================================
+	response := nsc.InvokeDependency(stub, bpmDependency, "checkOnNIMSAndRespond", dataCircuitID, orderBandwidth, orderID, operatorID,
+		arguments.Format("ExpiresOn"), arguments.Format("Waitlist"), arguments.Format("Priority"),
+		arguments.Format("ExcessBandwidth"), arguments.Format("BurstSize"), arguments.Format("ClassOfService"),
+		arguments.Format("MaxLatency"), arguments.Format("MaxJitter"), arguments.Format("MaxLoss"), arguments.Format("MinAvailability"))
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(bpmDependency, "checkOnNIMSAndRespond", response))
+	}
+
+	err = events.Merge(bpmDependency, response.Payload)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	// now send for testing and cabling
+
+	fmt.Println("- end prepareOrder")
+	return events.Emit(stub)
+}
+
+// placeOrder hands an order that names a network instead of a circuit to BPM, which chooses the circuit
+func placeOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting placeOrder")
+
+	orderID := arguments.Str("OrderID")
+	fmt.Println(arguments)
+
+	err := nsc.AssertOperator(stub, arguments.Str("OperatorID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	err = events.Add(nsc.EventOrderPrepared, PreparedOrder{
+		OrderID:         orderID,
+		OperatorID:      arguments.Str("OperatorID"),
+		OrderBandwidth:  arguments.Bandwidth("OrderBandwidth"),
+		HomeChannel:     stub.GetChannelID(),
+		CircuitNetwork:  arguments.Str("CircuitNetwork"),
+		ProviderID:      arguments.Str("ProviderID"),
+		Strategy:        arguments.Str("Strategy"),
+		Protected:       arguments.Boolean("Protected"),
+		ExcessBandwidth: arguments.Bandwidth("ExcessBandwidth"),
+		BurstSize:       arguments.Integer("BurstSize"),
+		ClassOfService:  arguments.Str("ClassOfService"),
+		MaxLatency:      arguments.Integer("MaxLatency"),
+		MaxJitter:       arguments.Integer("MaxJitter"),
+		MaxLoss:         arguments.Integer("MaxLoss"),
+		MinAvailability: arguments.Integer("MinAvailability"),
+	})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	response := nsc.InvokeDependency(stub, bpmDependency, "placeOrder", arguments.Str("CircuitNetwork"), arguments.Format("ProviderID"),
+		arguments.Format("OrderBandwidth"), orderID, arguments.Str("OperatorID"), arguments.Format("Strategy"),
+		arguments.Format("ExpiresOn"), arguments.Format("Waitlist"), arguments.Format("Priority"), arguments.Format("AllowSplit"),
+		arguments.Format("Protected"), arguments.Str("DiverseOn"), arguments.Format("ExcessBandwidth"), arguments.Format("BurstSize"),
+		arguments.Format("ClassOfService"), arguments.Format("MaxLatency"), arguments.Format("MaxJitter"), arguments.Format("MaxLoss"),
+		arguments.Format("MinAvailability"))
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(bpmDependency, "placeOrder", response))
+	}
+
+	err = events.Merge(bpmDependency, response.Payload)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end placeOrder")
+	return events.Emit(stub)
+}
+
+// placePathOrder hands an order between two sites to BPM, which routes it over circuits of the network
+func placePathOrder(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	fmt.Println("starting placePathOrder")
+
+	orderID := arguments.Str("OrderID")
+	fmt.Println(arguments)
+
+	err := nsc.AssertOperator(stub, arguments.Str("OperatorID"))
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	events := nsc.NewEventBatch(stub, chaincodeName, orderID)
+	err = events.Add(nsc.EventOrderPrepared, PreparedOrder{
+		OrderID:         orderID,
+		OperatorID:      arguments.Str("OperatorID"),
+		OrderBandwidth:  arguments.Bandwidth("OrderBandwidth"),
+		HomeChannel:     stub.GetChannelID(),
+		CircuitNetwork:  arguments.Str("CircuitNetwork"),
+		Metric:          arguments.Str("Metric"),
+		ASiteID:         arguments.Str("ASiteID"),
+		ZSiteID:         arguments.Str("ZSiteID"),
+		ExcessBandwidth: arguments.Bandwidth("ExcessBandwidth"),
+		BurstSize:       arguments.Integer("BurstSize"),
+		ClassOfService:  arguments.Str("ClassOfService"),
+		MaxLatency:      arguments.Integer("MaxLatency"),
+		MaxJitter:       arguments.Integer("MaxJitter"),
+		MaxLoss:         arguments.Integer("MaxLoss"),
+		MinAvailability: arguments.Integer("MinAvailability"),
+	})
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	response := nsc.InvokeDependency(stub, bpmDependency, "placePathOrder", arguments.Str("CircuitNetwork"), arguments.Str("ASiteID"),
+		arguments.Str("ZSiteID"), arguments.Format("OrderBandwidth"), orderID, arguments.Str("OperatorID"), arguments.Format("Metric"),
+		arguments.Format("ExpiresOn"), arguments.Format("ExcessBandwidth"), arguments.Format("BurstSize"), arguments.Format("ClassOfService"),
+		arguments.Format("MaxLatency"), arguments.Format("MaxJitter"), arguments.Format("MaxLoss"), arguments.Format("MinAvailability"))
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(bpmDependency, "placePathOrder", response))
+	}
+
+	err = events.Merge(bpmDependency, response.Payload)
+	if err != nil {
+		return nsc.ErrorResponse(err)
+	}
+
+	fmt.Println("- end placePathOrder")
+	return events.Emit(stub)
+}
+
+// for thumbsup first validate the registered evaluator by evaluator secret from the evaluator chaincode
+// then allow the evaluator to do a thumsup against an answer hash id
+// iff the evaluator has a tech reputation more than 1000
+
+// ====================================================== Private Library ====================================================
+
+func JSONtoOrder(data []byte) (Order, error) {
+
+	order := Order{}
+	err := json.Unmarshal([]byte(data), &order)
+	if err != nil {
+		fmt.Println("Unmarshal failed : ", err)
+		return order, err
+	}
+
+	return order, nil
+}
+
+// getTxTimestamp formats the proposal timestamp so every endorser records the same value
+func getTxTimestamp(stub shim.ChaincodeStubInterface) (string, error) {
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil {
+		return "", err
+	}
+	return time.Unix(txTime.Seconds, int64(txTime.Nanos)).UTC().Format("20060102150405"), nil
+}
@@ -0,0 +1,58 @@
+package oms
+
+import (
+	"encoding/json"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// ============================================================================================================================
+// Circuit Home Channels - the circuit to channel mapping is configured on BPM, OMS only resolves it
+// ============================================================================================================================
+
+type CircuitHomeChannel struct {
+	CircuitID string `json:"CircuitID"`
+	ChannelID string `json:"ChannelID"`
+}
+
+var checkCircuitCapacityArguments = nsc.ArgumentSchema{
+	{Name: "CircuitID", Type: nsc.ArgString, Required: true, MaxLength: nsc.MaxIDLength, Pattern: nsc.IDPattern},
+	{Name: "Bandwidth", Type: nsc.ArgBandwidth, Required: true, Minimum: nsc.Bound(1), Maximum: nsc.Bound(nsc.MaxBandwidth)},
+}
+
+// checkCircuitCapacity runs BPM's read only capacity check, which follows the circuit to its home channel
+func checkCircuitCapacity(stub shim.ChaincodeStubInterface, arguments nsc.FunctionArgs) pb.Response {
+	response := nsc.InvokeDependency(stub, bpmDependency, "checkCircuitCapacity", arguments.Str("CircuitID"), arguments.Bandwidth("Bandwidth").Argument())
+	if response.Status != shim.OK {
+		return nsc.ErrorResponse(nsc.UpstreamError(bpmDependency, "checkCircuitCapacity", response))
+	}
+	return shim.Success(response.Payload)
+}
+
+func resolveCircuitHomeChannel(stub shim.ChaincodeStubInterface, circuitID string) (string, error) {
+	response := nsc.InvokeDependency(stub, bpmDependency, "getCircuitHomeChannel", circuitID)
+	if response.Status != shim.OK {
+		return "", nsc.UpstreamError(bpmDependency, "getCircuitHomeChannel", response)
+	}
+
+	var homeChannel CircuitHomeChannel
+	err := json.Unmarshal(response.Payload, &homeChannel)
+	if err != nil {
+		return "", err
+	}
+	return homeChannel.ChannelID, nil
+}
+
+// assertWritableChannel refuses orders whose allocation would have to commit on another channel
+func assertWritableChannel(stub shim.ChaincodeStubInterface, circuitID string, homeChannel string) error {
+	if homeChannel == "" || homeChannel == stub.GetChannelID() {
+		return nil
+	}
+	return nsc.NewError(nsc.CodeForbidden, "DataCircuit %s lives on channel %s, submit the order on that channel; "+
+		"orders placed on %s cannot allocate its bandwidth because cross-channel writes are never committed",
+		circuitID, homeChannel, stub.GetChannelID()).
+		WithDetail("CircuitID", circuitID).
+		WithDetail("HomeChannel", homeChannel)
+}
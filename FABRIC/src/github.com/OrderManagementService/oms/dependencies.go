@@ -0,0 +1,13 @@
+package oms
+
+// ============================================================================================================================
+// Chaincode Dependencies - see github.com/NetworkServiceCommon/nsc/dependencies.go
+// ============================================================================================================================
+
+// aliases of the chaincodes OMS calls into
+const (
+	bpmDependency  = "BPM"
+	ancsDependency = "ANCS"
+)
+
+var knownDependencies = []string{bpmDependency, ancsDependency}
@@ -0,0 +1,26 @@
+package oms_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestRegisterDependencyChecksVersion(t *testing.T) {
+	s := simulator.NewForTest(t)
+
+	result := s.Invoke(simulator.OMS, simulator.AdminIdentity, "registerChaincodeDependency", "BPM", simulator.BPM, "2.0", "mychannel")
+	if result.OK() || result.Err == nil || result.Err.Code != nsc.CodeConflict {
+		t.Fatalf("expected %s registering a version BPM does not report, got %v", nsc.CodeConflict, result.Error())
+	}
+	result = s.Invoke(simulator.OMS, simulator.AdminIdentity, "registerChaincodeDependency", "BPM", "MissingService", "1.0", "mychannel")
+	if result.OK() {
+		t.Fatal("expected registering a chaincode that is not deployed to fail")
+	}
+
+	result = s.Invoke(simulator.OMS, simulator.AdminIdentity, "registerChaincodeDependency", "BPM", simulator.BPM, "1.0", "mychannel")
+	if !result.OK() {
+		t.Fatal(result.Error())
+	}
+}
@@ -0,0 +1,39 @@
+package oms_test
+
+import (
+	"testing"
+
+	"github.com/NetworkServiceCommon/nsc"
+	"github.com/NetworkServiceSimulator"
+)
+
+func TestOrdersArePlacedForTheInvoker(t *testing.T) {
+	s := simulator.NewForTest(t)
+	if err := s.SeedCircuit("C1", "NET1", "Org1MSP", 100*simulator.Mbps); err != nil {
+		t.Fatal(err)
+	}
+	alice := simulator.OperatorIdentity("alice")
+
+	for _, result := range []simulator.Result{
+		s.Invoke(simulator.OMS, alice, "prepareOrder", "O1", "bob", "C1", "10M"),
+		s.Invoke(simulator.OMS, alice, "placeOrder", "O1", "bob", "NET1", "", "10M"),
+		s.Invoke(simulator.OMS, alice, "placePathOrder", "O1", "bob", "NET1", "S1", "S2", "10M"),
+	} {
+		if result.OK() || result.Err == nil || result.Err.Code != nsc.CodeForbidden {
+			t.Fatalf("expected %s ordering for another operator, got %v", nsc.CodeForbidden, result.Error())
+		}
+	}
+	if err := s.ExpectBandwidth("C1", 0, 100*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+
+	if result := s.Invoke(simulator.OMS, alice, "prepareOrder", "O1", "alice", "C1", "10M"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if result := s.Invoke(simulator.OMS, simulator.AdminIdentity, "prepareOrder", "O2", "bob", "C1", "10M"); !result.OK() {
+		t.Fatal(result.Error())
+	}
+	if err := s.ExpectBandwidth("C1", 20*simulator.Mbps, 80*simulator.Mbps); err != nil {
+		t.Error(err)
+	}
+}
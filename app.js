@@ -22,6 +22,7 @@ var usersRouter = require("./routes/users");
 var channelsRouter = require("./routes/channels");
 var chaincodesRouter = require("./routes/chaincodes");
 var queriesRouter = require("./routes/queries");
+var usersService = require("./utils/users");
 
 global.appDir = path.resolve(__dirname).toString();
 global.configs = require("./configs/envs/config.json");
@@ -103,7 +104,8 @@ app.use(
 );
 
 app.use("/", indexRouter);
-app.use("/usersAPI", usersRouter);
+// only a CA registrar registers users, and gives them the admin or agent role
+app.use("/usersAPI", usersService.requireRegistrar, usersRouter);
 app.use("/channelsAPI", channelsRouter);
 app.use("/chaincodesAPI", chaincodesRouter);
 app.use("/queriesAPI", queriesRouter);
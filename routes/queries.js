@@ -2,6 +2,7 @@
 var express = require('express');
 var router = express.Router();
 var query = require("../utils/query.js");
+var chaincodeEvents = require("../utils/chaincode-events.js");
 var helper = require("../utils/helper");
 
 
@@ -55,6 +56,47 @@ router.get("/username/:userName/orgName/:orgName/channels/:channelName/chaincode
       res.send(message);
     });
 });
+//  Stream chaincode events as newline delimited JSON until the client disconnects
+router.get("/username/:userName/orgName/:orgName/channels/:channelName/chaincodes/:chaincodeName/events", function(req, res) {
+  console.log("==================== STREAM CHAINCODE EVENTS ==================");
+  let userName = req.params.userName;
+  let orgName = req.params.orgName;
+  var channelName = req.params.channelName;
+  var chaincodeName = req.params.chaincodeName;
+  let eventName = req.query.eventName || "NetworkServiceEvents";
+  let startBlock = req.query.startBlock;
+  let peer = req.query.peer;
+
+  console.log("channelName : " + channelName);
+  console.log("chaincodeName : " + chaincodeName);
+  console.log("eventName : " + eventName);
+  console.log("startBlock : " + startBlock);
+
+  res.setHeader("Content-Type", "application/x-ndjson");
+
+  chaincodeEvents
+    .listenChaincodeEvents(
+      peer,
+      channelName,
+      chaincodeName,
+      eventName,
+      startBlock,
+      userName,
+      orgName,
+      function(event) {
+        res.write(JSON.stringify(event) + "\n");
+      },
+      function(err) {
+        res.end();
+      }
+    )
+    .then(function(stop) {
+      req.on("close", stop);
+    })
+    .catch(function(err) {
+      res.status(500).json({ success: false, message: err.toString() });
+    });
+});
 //  Query Get Block by BlockNumber
 router.get("/channels/:channelName/blocks/:blockId", function(req, res) {
   console.log("==================== GET BLOCK BY NUMBER ==================");
@@ -4,10 +4,11 @@ var router = express.Router();
 var helper = require("../utils/helper");
 var usersService = require("../utils/users");
 
-// Register and enroll user
+// Register and enroll user, the request is authenticated as a CA registrar by usersService.requireRegistrar
 router.post("/users", function(req, res) {
   var username = req.body.username;
   var orgName = req.body.orgName;
+  var role = req.body.role;
 
   console.log(req.body);
   
@@ -20,8 +21,12 @@ router.post("/users", function(req, res) {
     res.json(helper.getErrorMessage("'orgName'"));
     return;
   }
+  if (role && usersService.registrableRoles.indexOf(role) < 0) {
+    res.json(helper.getErrorMessage("'role'"));
+    return;
+  }
 
-  usersService.registerUserService(username, orgName, true).then(response => {
+  usersService.registerUserService(username, orgName, true, role, req.registrar).then(response => {
     // helper.getRegisteredUsers(username, orgName, true).then(function(response) {
     if (response.data && typeof !response.err) {
       res.json(response);
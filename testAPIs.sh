@@ -13,10 +13,14 @@ if [ $? -ne 0 ]; then
 fi
 starttime=$(date +%s)
 
+# users are registered by a registrar of the org's CA, the bootstrap identity of the sample CAs by default
+REGISTRAR=${REGISTRAR:-admin:adminpw}
+
 echo "POST request Enroll on Org1  ..."
 echo
 curl -X POST \
   http://localhost:3000/usersAPI/users \
+  -u "$REGISTRAR" \
   -H "content-type: application/x-www-form-urlencoded" \
   -d 'username=UserA&orgName=org1'
 
@@ -25,6 +29,7 @@ echo "POST request Enroll on Org2 ..."
 echo
 curl -s -X POST \
   http://localhost:3000/usersAPI/users \
+  -u "$REGISTRAR" \
   -H "content-type: application/x-www-form-urlencoded" \
   -d 'username=UserB&orgName=org2'
 
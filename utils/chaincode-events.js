@@ -0,0 +1,75 @@
+"use strict";
+
+var util = require("util");
+var helper = require("./helper.js");
+var logger = helper.getLogger("ChaincodeEvents");
+
+// listenChaincodeEvents calls onEvent for every chaincode event of chaincodeName
+// until the returned stop function is called
+var listenChaincodeEvents = async function(
+  peer,
+  channelName,
+  chaincodeName,
+  eventName,
+  startBlock,
+  username,
+  org_name,
+  onEvent,
+  onError
+) {
+  // first setup the client for this org
+  let client = await helper.getClientForOrg(org_name, username);
+  let channel = client.getChannel(channelName);
+  if (!channel) {
+    let message = util.format(
+      "Channel %s was not defined in the connection profile",
+      channelName
+    );
+    logger.error(message);
+    throw new Error(message);
+  }
+
+  let event_hub = peer
+    ? channel.getChannelEventHub(peer)
+    : channel.getChannelEventHubsForOrg()[0];
+
+  let options = {};
+  if (startBlock !== undefined && startBlock !== null && startBlock !== "") {
+    options.startBlock = parseInt(startBlock);
+  }
+
+  let handle = event_hub.registerChaincodeEvent(
+    chaincodeName,
+    eventName,
+    (event, block_num, tx_id, tx_status) => {
+      logger.debug("chaincode event %s in block %s", event.event_name, block_num);
+      // skip events of transactions that did not commit
+      if (tx_status && tx_status !== "VALID") {
+        return;
+      }
+      onEvent({
+        blockNumber: parseInt(block_num),
+        txId: tx_id,
+        chaincodeId: event.chaincode_id,
+        eventName: event.event_name,
+        payload: event.payload.toString("utf8")
+      });
+    },
+    err => {
+      logger.error(err);
+      onError(err);
+    },
+    options
+  );
+
+  // full blocks are needed, filtered blocks do not carry the event payload
+  event_hub.connect(true);
+
+  return function() {
+    event_hub.unregisterChaincodeEvent(handle);
+    event_hub.disconnect();
+    channel.close();
+  };
+};
+
+exports.listenChaincodeEvents = listenChaincodeEvents;
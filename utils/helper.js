@@ -305,7 +305,7 @@ module.exports = {
   getLogger,
   setupChaincodeDeploy,
   getRegisteredUser,
-
+  getErrorMessage,
   generateRSAKeyPair,
   hashingData,
   checkAndPersistUser,
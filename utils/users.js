@@ -1,11 +1,78 @@
 "use strict";
 
-var hfc = require("fabric-client");
 var helper = require("./helper");
 var log4js = require("log4js");
 var logger = log4js.getLogger("Helper");
 
-var registerUserService = async function(username, userOrg, isJson) {
+// the roles the chaincodes authorize on, a registrar may give one to a new user
+var registrableRoles = ["admin", "agent"];
+
+// authenticateRegistrar enrolls the given identity at the CA of the org, so only a valid secret gets a registrar back.
+// Whether the identity may register users, and with which attributes, is left to the CA when it registers them.
+var authenticateRegistrar = async function(registrarName, registrarSecret, userOrg) {
+  var client = await helper.getClientForOrg(userOrg);
+  let caClient = client.getCertificateAuthority();
+  let enrollment = await caClient.enroll({
+    enrollmentID: registrarName,
+    enrollmentSecret: registrarSecret
+  });
+  return client.createUser({
+    username: registrarName,
+    mspid: client.getMspid(),
+    cryptoContent: {
+      privateKeyPEM: enrollment.key.toBytes(),
+      signedCertPEM: enrollment.certificate
+    },
+    skipPersistence: true
+  });
+};
+
+// requireRegistrar authenticates the HTTP basic credentials of the request as a registrar of the CA of req.body.orgName
+// and keeps the registrar on req.registrar
+var requireRegistrar = function(req, res, next) {
+  var authorization = req.headers.authorization || "";
+  var credentials = Buffer.from(
+    authorization.replace(/^Basic /, ""),
+    "base64"
+  ).toString();
+  var separator = credentials.indexOf(":");
+  if (!authorization.startsWith("Basic ") || separator < 1) {
+    res.status(401).json({
+      success: false,
+      message: "registrar credentials are required as HTTP basic authentication"
+    });
+    return;
+  }
+  if (!req.body.orgName) {
+    res.json(helper.getErrorMessage("'orgName'"));
+    return;
+  }
+
+  authenticateRegistrar(
+    credentials.substring(0, separator),
+    credentials.substring(separator + 1),
+    req.body.orgName
+  )
+    .then(registrar => {
+      req.registrar = registrar;
+      next();
+    })
+    .catch(error => {
+      logger.error("Failed to authenticate registrar: %s", error.toString());
+      res.status(401).json({
+        success: false,
+        message: "registrar authentication failed"
+      });
+    });
+};
+
+var registerUserService = async function(
+  username,
+  userOrg,
+  isJson,
+  role,
+  registrar
+) {
   var secret;
   try {
     var client = await helper.getClientForOrg(userOrg);
@@ -16,24 +83,22 @@ var registerUserService = async function(username, userOrg, isJson) {
     if (user && user.isEnrolled()) {
       logger.info("Successfully loaded member from persistence");
     } else {
-      // user was not enrolled, so we will need an admin user object to register
+      // user was not enrolled, so the authenticated registrar registers it
       logger.info(
-        "User %s was not enrolled, so we will need an admin user object to register",
-        username
+        "User %s was not enrolled, registering it as %s",
+        username,
+        registrar.getName()
       );
-      var admins = hfc.getConfigSetting("admins");
-      let adminUserObj = await client.setUserContext({
-        username: admins[0].username,
-        password: admins[0].secret
-      });
       let caClient = client.getCertificateAuthority();
-      secret = await caClient.register(
-        {
-          enrollmentID: username,
-          affiliation: userOrg.toLowerCase() + ".department1"
-        },
-        adminUserObj
-      );
+      var registerRequest = {
+        enrollmentID: username,
+        affiliation: userOrg.toLowerCase() + ".department1"
+      };
+      if (role) {
+        // the chaincodes authorize on the "role" attribute of the enrollment certificate
+        registerRequest.attrs = [{ name: "role", value: role, ecert: true }];
+      }
+      secret = await caClient.register(registerRequest, registrar);
       logger.debug("Successfully got the secret for user %s", username);
       user = await client.setUserContext({
         username: username,
@@ -127,5 +192,7 @@ var generateAndPersistUserCredData = async function(
 };
 
 module.exports = {
+  registrableRoles,
+  requireRegistrar,
   registerUserService
 };